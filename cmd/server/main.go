@@ -4,12 +4,14 @@ import (
 	"context"
 	"log"
 	"os"
+	"strings"
 
 	"golang-service/internal/cache"
 	"golang-service/internal/config"
 	"golang-service/internal/database"
 	"golang-service/internal/handlers"
 	"golang-service/internal/middleware"
+	"golang-service/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -26,13 +28,21 @@ import (
 // @in header
 // @name Authorization
 func main() {
+	// Subcommands run without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using system environment variables")
 	}
 
-	// Load configuration
+	// Load configuration; dev auth must never run with a production-looking configuration
 	cfg := config.Load()
+	if err := cfg.ValidateAuthMode(); err != nil {
+		log.Fatal("Refusing to start: ", err)
+	}
 
 	// Initialize database
 	db, err := database.Initialize(cfg.DatabaseURL)
@@ -40,21 +50,41 @@ func main() {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
-	// Initialize Redis cache
-	redisCache := cache.NewRedisCache("localhost:6379", "", 0)
-	
-	// Test Redis connection
-	ctx := context.Background()
-	if err := redisCache.Ping(ctx); err != nil {
-		log.Printf("Warning: Redis connection failed: %v", err)
-		log.Println("Continuing without Redis cache...")
-		redisCache = nil
-	} else {
-		log.Println("Redis cache initialized successfully")
+	// A mistyped cache mode must not silently select another store
+	if err := cache.ValidateMode(cfg.CacheMode); err != nil {
+		log.Fatal("Invalid cache configuration: ", err)
 	}
 
-	// Initialize environment service
-	envService := config.NewEnvironmentService("config/environments.yaml")
+	// Initialize Redis cache unless running with the in-process cache only
+	var redisCache, sharedCache *cache.RedisCache
+	if cfg.CacheMode != cache.ModeMemory {
+		redisCache, err = cache.ConnectRedis(context.Background(), cfg)
+		if err != nil {
+			log.Printf("Warning: Redis connection failed: %v", err)
+			log.Println("Continuing with in-memory cache only...")
+		} else {
+			sharedCache = redisCache
+			log.Println("Redis cache initialized successfully")
+		}
+	}
+
+	vmCache, err := cache.NewStore(cfg.CacheMode, sharedCache, cfg.CacheMaxEntries, cfg.CacheLocalTTL)
+	if err != nil {
+		log.Fatal("Invalid cache configuration: ", err)
+	}
+	// Invalidations made by other replicas also clear this replica's local tier
+	if tiered, ok := vmCache.(*cache.TieredStore); ok {
+		tiered.Start(context.Background())
+	}
+
+	// Initialize environment service backed by the database, seeded from the YAML file when empty;
+	// every reload invalidates cached results that depend on resolution
+	envService := config.NewDatabaseEnvironmentService(db, cfg.EnvironmentConfigPath)
+	envService.OnReload(func() {
+		if _, err := vmCache.InvalidateTags(context.Background(), cache.TagEnvironments); err != nil {
+			log.Printf("Warning: Failed to invalidate VM cache after environment reload: %v", err)
+		}
+	})
 	if err := envService.LoadConfig(); err != nil {
 		log.Printf("Warning: Failed to load environment configuration: %v", err)
 		log.Println("Continuing without environment configuration...")
@@ -63,6 +93,84 @@ func main() {
 		log.Println("Environment configuration loaded successfully")
 	}
 
+	// Every replica keeps its own snapshot; pick up changes made through the others
+	if envService != nil && cfg.EnvironmentSyncInterval > 0 {
+		envService.WatchDatabase(context.Background(), cfg.EnvironmentSyncInterval)
+	}
+
+	// Apply changes to the YAML file (e.g. a mounted ConfigMap) without a manual reload
+	if envService != nil && cfg.EnvironmentWatch {
+		if err := envService.WatchConfig(context.Background(), cfg.EnvironmentWatchDebounce); err != nil {
+			log.Printf("Warning: Failed to watch environment configuration: %v", err)
+		} else {
+			log.Printf("Watching %s for environment configuration changes", cfg.EnvironmentConfigPath)
+		}
+	}
+
+	// Every API route is authorized against the role policy; refuse to start without one
+	policy, err := config.LoadPolicy(cfg.AuthzPolicyPath)
+	if err != nil {
+		log.Fatal("Failed to load authorization policy:", err)
+	}
+
+	// Bearer tokens are accepted from the configured issuers (Entra ID v1 and v2 by default)
+	issuers, err := config.LoadIssuers(cfg)
+	if err != nil {
+		log.Fatal("Failed to load token issuers:", err)
+	}
+	if len(issuers) == 0 && cfg.AuthMode != config.AuthModeDev {
+		log.Println("Warning: No token issuers configured; every bearer token will be rejected")
+	}
+	verifier := middleware.NewTokenVerifier(issuers, middleware.KeySetOptions{
+		RefreshInterval:    cfg.JWKSRefreshInterval,
+		MinRefreshInterval: cfg.JWKSMinRefreshInterval,
+		NegativeCacheTTL:   cfg.JWKSNegativeCacheTTL,
+	})
+	verifier.Start(context.Background())
+
+	// In dev auth mode tokens signed with a local key are trusted as well
+	var devSigner *middleware.DevSigner
+	if cfg.AuthMode == config.AuthModeDev {
+		devSigner, err = middleware.NewDevSigner(cfg.DevAuthKeyPath)
+		if err != nil {
+			log.Fatal("Failed to set up dev auth:", err)
+		}
+		verifier.TrustDevSigner(devSigner)
+		log.Println("Warning: Dev auth mode is enabled; tokens minted by POST /dev/token are accepted")
+	}
+
+	// Automation authenticates with API keys stored in the database
+	apiKeys := services.NewAPIKeyService(db, cfg.APIKeyMaxTTL)
+	auditLog := services.NewAuditService(db)
+	users := services.NewUserService(db)
+	// Users provisioned before identities carried their issuer came from the Entra ID tenant
+	if cfg.AzureTenantID != "" {
+		if adopted, err := users.AdoptLegacyIdentities(config.EntraNamespace(cfg.AzureTenantID)); err != nil {
+			log.Printf("Warning: Failed to assign issuers to existing users: %v", err)
+		} else if adopted > 0 {
+			log.Printf("Assigned the Entra ID issuer to %d existing users", adopted)
+		}
+	}
+	// Users are only matched by object ID, so users without one cannot sign in
+	if unlinked, err := users.UnlinkedUsers(); err != nil {
+		log.Printf("Warning: Failed to check for users without an object ID: %v", err)
+	} else if len(unlinked) > 0 {
+		emails := make([]string, 0, len(unlinked))
+		for _, user := range unlinked {
+			emails = append(emails, user.Email)
+		}
+		log.Printf("Warning: %d users have no object ID and are refused on sign-in until an admin sets their azure_id and issuer: %s", len(unlinked), strings.Join(emails, ", "))
+	}
+	views := services.NewViewService(db)
+	// Views saved before owners carried their issuer belong to Entra ID users too
+	if cfg.AzureTenantID != "" {
+		if adopted, err := views.AdoptLegacyOwners(config.EntraNamespace(cfg.AzureTenantID)); err != nil {
+			log.Printf("Warning: Failed to assign issuers to existing view owners: %v", err)
+		} else if adopted > 0 {
+			log.Printf("Assigned the Entra ID issuer to the owners of %d existing views", adopted)
+		}
+	}
+
 	// Setup Gin router
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -73,29 +181,97 @@ func main() {
 	// Add CORS middleware
 	router.Use(middleware.CORS())
 
-	// Health check endpoint (no auth required, but with DB context)
-	router.GET("/health", handlers.DatabaseHealthMiddleware(db), handlers.HealthCheck)
+	// Health check endpoint (no auth required, but with DB and cache context)
+	router.GET("/health", handlers.DatabaseHealthMiddleware(db), handlers.RedisHealthMiddleware(redisCache), handlers.JWKSHealthMiddleware(verifier), handlers.HealthCheck)
+
+	// Token minting for local development
+	if devSigner != nil {
+		router.POST("/dev/token", handlers.NewDevAuthHandler(devSigner, cfg.DevRoles).IssueToken)
+	}
 
 	// API routes with authentication
 	api := router.Group("/api/v1")
-	api.Use(middleware.AzureEntraAuth(cfg))
+	api.Use(middleware.Authenticate(verifier, apiKeys))
+	// Audit runs before authorization so that refused requests are recorded too
+	if cfg.AuditEnabled {
+		api.Use(middleware.Audit(auditLog))
+	}
+	if cfg.UserProvisioning {
+		api.Use(middleware.ProvisionUser(users))
+	}
+	api.Use(middleware.RequirePermission(policy), middleware.DataScope(policy))
 	{
 		// Initialize handlers
-		usersHandler := handlers.NewUsersHandler(db)
-		vmsHandler := handlers.NewVMsHandler(db, redisCache, envService, cfg)
-		envHandler := handlers.NewEnvironmentHandler(envService)
+		usersHandler := handlers.NewUsersHandler(users)
+		vmsHandler := handlers.NewVMsHandler(db, vmCache, envService, cfg, views)
+		if cfg.CacheSyncPoll > 0 {
+			go vmsHandler.WatchProviderSyncs(context.Background(), cfg.CacheSyncPoll)
+		}
+		envHandler := handlers.NewEnvironmentHandler(envService, vmsHandler, policy)
+		cacheHandler := handlers.NewCacheHandler(vmCache)
+		apiKeysHandler := handlers.NewAPIKeysHandler(apiKeys, policy, envService)
+		auditHandler := handlers.NewAuditHandler(auditLog)
+		meHandler := handlers.NewMeHandler(users, envService)
+		viewsHandler := handlers.NewViewsHandler(views)
+
+		// Caller profile endpoints
+		api.GET("/me", meHandler.GetMe)
+		api.PATCH("/me/preferences", meHandler.UpdatePreferences)
 
 		// User management endpoints
 		api.GET("/users", usersHandler.GetUsers)
+		api.POST("/users", usersHandler.CreateUser)
+		api.GET("/users/:id", usersHandler.GetUser)
+		api.PUT("/users/:id", usersHandler.UpdateUser)
+		api.PATCH("/users/:id", usersHandler.UpdateUser)
+		api.DELETE("/users/:id", usersHandler.DeleteUser)
+		api.POST("/users/:id/restore", usersHandler.RestoreUser)
 
 		// VM management endpoints
 		api.GET("/vms", vmsHandler.GetVMs)
 
+		// Saved view endpoints
+		api.GET("/views", viewsHandler.ListViews)
+		api.POST("/views", viewsHandler.CreateView)
+		api.GET("/views/:id", viewsHandler.GetView)
+		api.PUT("/views/:id", viewsHandler.UpdateView)
+		api.DELETE("/views/:id", viewsHandler.DeleteView)
+
 		// Environment management endpoints
 		api.GET("/environments", envHandler.ListEnvironments)
+		api.POST("/environments", envHandler.CreateEnvironment)
+		api.GET("/environments/export", envHandler.ExportEnvironments)
+		api.GET("/environments/ambiguities", envHandler.GetAmbiguities)
+		api.GET("/environments/coverage", envHandler.GetCoverage)
+		api.GET("/environments/tree", envHandler.GetEnvironmentTree)
+		api.POST("/environments/resolve", envHandler.ResolveEnvironment)
+		api.POST("/environments/import", envHandler.ImportEnvironments)
+		api.POST("/environments/validate", envHandler.ValidateEnvironments)
+		api.GET("/environments/versions", envHandler.ListVersions)
+		api.GET("/environments/versions/diff", envHandler.DiffVersions)
+		api.GET("/environments/versions/:version", envHandler.GetVersion)
+		api.POST("/environments/rollback/:version", envHandler.RollbackToVersion)
 		api.GET("/environments/:id", envHandler.GetEnvironment)
+		api.GET("/environments/:id/children", envHandler.GetEnvironmentChildren)
+		api.PUT("/environments/:id", envHandler.UpdateEnvironment)
+		api.PATCH("/environments/:id", envHandler.PatchEnvironment)
+		api.DELETE("/environments/:id", envHandler.DeleteEnvironment)
 		api.POST("/environments/reload", envHandler.ReloadConfig)
 		api.GET("/environments/config/info", envHandler.GetConfigInfo)
+
+		// Cache administration endpoints
+		api.GET("/admin/cache/stats", cacheHandler.GetStats)
+		api.POST("/admin/cache/invalidate", cacheHandler.InvalidateTags)
+
+		// API key administration endpoints
+		api.GET("/admin/api-keys", apiKeysHandler.ListAPIKeys)
+		api.POST("/admin/api-keys", apiKeysHandler.CreateAPIKey)
+		api.GET("/admin/api-keys/:id", apiKeysHandler.GetAPIKey)
+		api.DELETE("/admin/api-keys/:id", apiKeysHandler.RevokeAPIKey)
+
+		// Audit log endpoints
+		api.GET("/audit", auditHandler.ListAuditEvents)
+		api.GET("/audit/export", auditHandler.ExportAuditEvents)
 	}
 
 	// Swagger documentation
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"golang-service/internal/config"
+)
+
+// runValidate implements "server validate [-strict] [-json] [file ...]". It validates
+// environments files without connecting to anything, for use in CI, and returns the exit
+// code: 0 when every file is valid, 1 when any has errors (or warnings with -strict), 2 on
+// usage errors. Without files it validates ENVIRONMENT_CONFIG_PATH.
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	strict := flags.Bool("strict", false, "treat warnings as errors")
+	asJSON := flags.Bool("json", false, "print the reports as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: server validate [-strict] [-json] [file ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{config.Load().EnvironmentConfigPath}
+	}
+
+	reports := make(map[string]config.ValidationReport, len(files))
+	failed := false
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			failed = true
+			continue
+		}
+
+		report := config.ValidateEnvironmentConfig(data)
+		reports[file] = report
+		if !report.Valid || (*strict && len(report.Warnings) > 0) {
+			failed = true
+		}
+		if !*asJSON {
+			printValidationReport(os.Stdout, file, report)
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to encode reports: %v\n", err)
+			return 1
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// printValidationReport prints one line per issue followed by a summary
+func printValidationReport(w io.Writer, file string, report config.ValidationReport) {
+	for _, issue := range append(report.Errors, report.Warnings...) {
+		location := file
+		if issue.Environment != "" {
+			location += " [" + issue.Environment + "]"
+		}
+		if issue.Field != "" {
+			location += " " + issue.Field
+		}
+		fmt.Fprintf(w, "%s: %s: %s\n", location, issue.Severity, issue.Message)
+	}
+
+	status := "valid"
+	if !report.Valid {
+		status = "invalid"
+	}
+	fmt.Fprintf(w, "%s: %s (%d environments, %d errors, %d warnings)\n",
+		file, status, report.Environments, len(report.Errors), len(report.Warnings))
+}
@@ -100,6 +100,24 @@ CREATE TABLE IF NOT EXISTS azure_compute_virtual_machines (
     PRIMARY KEY (id)
 );
 
+-- Create Azure network interfaces table (used to resolve the virtual network of Azure VMs)
+CREATE TABLE IF NOT EXISTS azure_network_interfaces (
+    _cq_sync_time timestamp without time zone,
+    _cq_source_name text,
+    _cq_id uuid NOT NULL,
+    _cq_parent_id uuid,
+    subscription_id text,
+    etag text,
+    extended_location jsonb,
+    location text,
+    properties jsonb,
+    tags jsonb,
+    id text NOT NULL,
+    name text,
+    type text,
+    PRIMARY KEY (id)
+);
+
 -- Create GCP Compute instances table
 CREATE TABLE IF NOT EXISTS gcp_compute_instances (
     _cq_sync_time timestamp without time zone,
@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang-service/internal/config"
+	"golang-service/internal/models"
+)
+
+// Cache modes selectable through configuration
+const (
+	ModeMemory = "memory"
+	ModeRedis  = "redis"
+	ModeTiered = "tiered"
+)
+
+// Cache keys and tags
+const (
+	// vmsAllKey is the cache key holding the full, unfiltered VM inventory
+	vmsAllKey = "vms:all"
+	// queryKeyPrefix prefixes cached query results
+	queryKeyPrefix = "vms:query:"
+	// TagEnvironments is carried by every entry whose content depends on environment resolution
+	TagEnvironments = "env"
+	// unassignedEnvironment tags entries containing VMs without a resolved environment
+	unassignedEnvironment = "unassigned"
+)
+
+// CloudTypes lists the providers whose VMs are cached
+var CloudTypes = []string{"aws", "azure", "gcp"}
+
+// Store is the cache abstraction the handlers depend on.
+// A cache miss is reported as a nil result with a nil error.
+type Store interface {
+	// GetVMs retrieves the cached VM inventory
+	GetVMs(ctx context.Context) ([]models.VM, error)
+	// SetVMs stores the VM inventory, tagged by provider and environment
+	SetVMs(ctx context.Context, vms []models.VM) error
+	// InvalidateVMs removes the cached VM inventory
+	InvalidateVMs(ctx context.Context) error
+	// GetQuery retrieves a cached query result
+	GetQuery(ctx context.Context, key string) (*QueryResult, error)
+	// SetQuery stores a query result under the tags it carries
+	SetQuery(ctx context.Context, key string, result *QueryResult) error
+	// InvalidateTags removes every entry carrying any of the tags and returns how many were removed
+	InvalidateTags(ctx context.Context, tags ...string) (int, error)
+	// Generation returns a counter advanced by every invalidation. Writers read it before
+	// loading the data they cache and skip the write if it has moved on since.
+	Generation() uint64
+	// Stats returns hit and miss counters since startup
+	Stats() Stats
+	// Ping checks that the backing store is reachable
+	Ping(ctx context.Context) error
+	// Close releases any resources held by the store
+	Close() error
+}
+
+// QueryResult is one cached page of a filtered, sorted VM query
+type QueryResult struct {
+	VMs        []models.VM `json:"vms"`
+	TotalItems int         `json:"totalItems"`
+	Tags       []string    `json:"tags"`
+	SyncTime   time.Time   `json:"syncTime"` // latest sync time of the inventory the page was built from
+}
+
+// Stats reports cache effectiveness
+type Stats struct {
+	Backend string  `json:"backend"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hitRate"`
+	Entries int     `json:"entries,omitempty"`
+	Tiers   []Stats `json:"tiers,omitempty"`
+}
+
+// counters tracks hits and misses for a store
+type counters struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// record counts a lookup as a hit or a miss
+func (c *counters) record(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+// stats builds the Stats snapshot for a backend
+func (c *counters) stats(backend string) Stats {
+	stats := Stats{Backend: backend, Hits: c.hits.Load(), Misses: c.misses.Load()}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// ProviderTag returns the tag for entries that depend on a cloud provider's data
+func ProviderTag(cloudType string) string {
+	return "provider:" + cloudType
+}
+
+// EnvironmentTag returns the tag for entries containing VMs of an environment
+func EnvironmentTag(envID string) string {
+	return "env:" + envID
+}
+
+// QueryKey builds a cache key from the normalized filter, sort and page tuple
+func QueryKey(filters []config.FilterParam, sortBy, sortOrder string, page, pageSize int) string {
+	parts := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		parts = append(parts, filter.Field+"|"+string(filter.Operator)+"|"+filter.Value)
+	}
+	sort.Strings(parts)
+
+	normalized := strings.Join(parts, "&") +
+		"#sort=" + sortBy + "," + sortOrder +
+		"#page=" + strconv.Itoa(page) + "," + strconv.Itoa(pageSize)
+
+	sum := sha256.Sum256([]byte(normalized))
+	return queryKeyPrefix + hex.EncodeToString(sum[:16])
+}
+
+// QueryTags returns the tags for a query result: the providers the query can
+// draw from, plus the environments of the VMs it matched
+func QueryTags(providers []string, matched []models.VM) []string {
+	tags := []string{TagEnvironments}
+	for _, provider := range providers {
+		tags = append(tags, ProviderTag(provider))
+	}
+	return append(tags, environmentTags(matched)...)
+}
+
+// inventoryTags returns the tags for the full VM inventory
+func inventoryTags(vms []models.VM) []string {
+	return QueryTags(CloudTypes, vms)
+}
+
+// environmentTags returns one tag per distinct environment among the VMs
+func environmentTags(vms []models.VM) []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, vm := range vms {
+		envID := vm.Env
+		if envID == "" {
+			envID = unassignedEnvironment
+		}
+		if !seen[envID] {
+			seen[envID] = true
+			tags = append(tags, EnvironmentTag(envID))
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// ValidateMode rejects cache modes other than memory, redis and tiered
+func ValidateMode(mode string) error {
+	switch mode {
+	case ModeMemory, ModeRedis, ModeTiered:
+		return nil
+	}
+	return fmt.Errorf("unknown CACHE_MODE %q: expected %s, %s or %s", mode, ModeMemory, ModeRedis, ModeTiered)
+}
+
+// NewStore builds the cache store for the given mode. The Redis cache may be nil,
+// in which case every mode falls back to the in-process memory store.
+func NewStore(mode string, redisCache *RedisCache, maxEntries int, ttl time.Duration) (Store, error) {
+	if err := ValidateMode(mode); err != nil {
+		return nil, err
+	}
+
+	if redisCache == nil || mode == ModeMemory {
+		return NewMemoryStore(maxEntries, ttl), nil
+	}
+
+	if mode == ModeRedis {
+		return redisCache, nil
+	}
+
+	return NewTieredStore(NewMemoryStore(maxEntries, ttl), redisCache, redisCache), nil
+}
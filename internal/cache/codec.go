@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encoded cache values start with a short header:
+//
+//	magic (3 bytes) | schema version (1 byte) | flags (1 byte) | payload
+//
+// The payload is a zstd-compressed gob stream, or for chunked values a
+// manifest naming the keys holding the pieces of the encoded value.
+const (
+	codecMagic = "ATL"
+	// schemaVersion must be bumped whenever a cached type (models.VM, QueryResult)
+	// changes shape, so entries written by an older deploy are ignored
+	schemaVersion byte = 6
+	headerSize         = len(codecMagic) + 2
+
+	flagChunked byte = 1 << 0
+)
+
+// errSchemaMismatch reports an entry written with another encoding or schema version
+var errSchemaMismatch = errors.New("cache entry schema mismatch")
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// chunkManifest is stored in place of a value that was split across several keys
+type chunkManifest struct {
+	ID     string
+	Chunks int
+	Size   int
+}
+
+// encodeValue gob-encodes and compresses a value behind the codec header
+func encodeValue(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return zstdEncoder.EncodeAll(buf.Bytes(), header(0)), nil
+}
+
+// decodeValue reverses encodeValue
+func decodeValue(data []byte, value interface{}) error {
+	flags, payload, err := parseHeader(data)
+	if err != nil {
+		return err
+	}
+	if flags&flagChunked != 0 {
+		return fmt.Errorf("unexpected chunk manifest")
+	}
+
+	raw, err := zstdDecoder.DecodeAll(payload, nil)
+	if err != nil {
+		return fmt.Errorf("failed to decompress cache entry: %w", err)
+	}
+	return gob.NewDecoder(bytes.NewReader(raw)).Decode(value)
+}
+
+// encodeManifest encodes a chunk manifest behind the codec header
+func encodeManifest(manifest chunkManifest) ([]byte, error) {
+	buf := bytes.NewBuffer(header(flagChunked))
+	if err := gob.NewEncoder(buf).Encode(manifest); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeManifest returns the chunk manifest held in data, or nil if data is a plain value
+func decodeManifest(data []byte) (*chunkManifest, error) {
+	flags, payload, err := parseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if flags&flagChunked == 0 {
+		return nil, nil
+	}
+
+	var manifest chunkManifest
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode chunk manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// splitChunks splits data into pieces of at most size bytes
+func splitChunks(data []byte, size int) [][]byte {
+	chunks := make([][]byte, 0, (len(data)+size-1)/size)
+	for len(data) > size {
+		chunks = append(chunks, data[:size])
+		data = data[size:]
+	}
+	return append(chunks, data)
+}
+
+// header returns a fresh codec header with the given flags
+func header(flags byte) []byte {
+	return append([]byte(codecMagic), schemaVersion, flags)
+}
+
+// parseHeader validates the codec header and returns the flags and payload
+func parseHeader(data []byte) (byte, []byte, error) {
+	if len(data) < headerSize || string(data[:len(codecMagic)]) != codecMagic {
+		return 0, nil, errSchemaMismatch
+	}
+	if data[len(codecMagic)] != schemaVersion {
+		return 0, nil, errSchemaMismatch
+	}
+	return data[headerSize-1], data[headerSize:], nil
+}
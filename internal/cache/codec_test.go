@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec(t *testing.T) {
+	vms := []models.VM{
+		{
+			ID:                   "vm-1",
+			Name:                 "web-01",
+			CloudType:            "aws",
+			CloudSpecificDetails: json.RawMessage(`{"vpcId":"vpc-1"}`),
+			Environment:          &models.EnvironmentInfo{ID: "prod", Name: "Production", Tags: []string{"critical"}},
+			Env:                  "prod",
+		},
+		{ID: "vm-2", Name: "db-01", CloudType: "gcp", CloudSpecificDetails: json.RawMessage(`{}`)},
+	}
+
+	t.Run("Round trip", func(t *testing.T) {
+		data, err := encodeValue(vms)
+		require.NoError(t, err)
+
+		var decoded []models.VM
+		require.NoError(t, decodeValue(data, &decoded))
+		assert.Equal(t, vms, decoded)
+	})
+
+	t.Run("Query results round trip", func(t *testing.T) {
+		result := &QueryResult{VMs: vms, TotalItems: 42, Tags: []string{"env", "provider:aws"}}
+		data, err := encodeValue(result)
+		require.NoError(t, err)
+
+		var decoded QueryResult
+		require.NoError(t, decodeValue(data, &decoded))
+		assert.Equal(t, *result, decoded)
+	})
+
+	t.Run("Other schema versions are rejected as mismatches", func(t *testing.T) {
+		data, err := encodeValue(vms)
+		require.NoError(t, err)
+		data[len(codecMagic)] = schemaVersion + 1
+
+		var decoded []models.VM
+		assert.ErrorIs(t, decodeValue(data, &decoded), errSchemaMismatch)
+	})
+
+	t.Run("Legacy JSON entries are rejected as mismatches", func(t *testing.T) {
+		data, err := json.Marshal(vms)
+		require.NoError(t, err)
+
+		var decoded []models.VM
+		assert.ErrorIs(t, decodeValue(data, &decoded), errSchemaMismatch)
+		_, err = decodeManifest(data)
+		assert.ErrorIs(t, err, errSchemaMismatch)
+	})
+
+	t.Run("Manifests are distinguished from values", func(t *testing.T) {
+		manifest := chunkManifest{ID: "abc", Chunks: 3, Size: 100}
+		data, err := encodeManifest(manifest)
+		require.NoError(t, err)
+
+		decoded, err := decodeManifest(data)
+		require.NoError(t, err)
+		assert.Equal(t, &manifest, decoded)
+
+		value, err := encodeValue(vms)
+		require.NoError(t, err)
+		decoded, err = decodeManifest(value)
+		assert.NoError(t, err)
+		assert.Nil(t, decoded)
+	})
+
+	t.Run("Chunks reassemble to the encoded value", func(t *testing.T) {
+		data, err := encodeValue(vms)
+		require.NoError(t, err)
+
+		chunks := splitChunks(data, 16)
+		assert.Len(t, chunks, (len(data)+15)/16)
+		for _, chunk := range chunks {
+			assert.LessOrEqual(t, len(chunk), 16)
+		}
+		assert.Equal(t, data, bytes.Join(chunks, nil))
+
+		assert.Len(t, splitChunks(data, len(data)), 1)
+	})
+
+	t.Run("Chunk keys share the manifest's hash slot", func(t *testing.T) {
+		key := chunkKey(vmsAllKey, "abc", 2)
+		assert.Equal(t, "{vms:all}:chunk:abc:2", key)
+		assert.True(t, isChunkKey(key))
+		assert.False(t, isChunkKey(vmsAllKey))
+	})
+}
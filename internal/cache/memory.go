@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang-service/internal/models"
+)
+
+// MemoryStore is an in-process cache bounded by entry count and TTL.
+// When full, the least recently used entry is evicted.
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	order      *list.List
+	entries    map[string]*list.Element
+	tags       map[string]map[string]struct{} // tag -> keys
+	counters   counters
+	generation atomic.Uint64
+}
+
+// memoryEntry is a single cached value with its expiry and tags
+type memoryEntry struct {
+	key       string
+	value     interface{}
+	tags      []string
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates a new in-memory cache. A maxEntries or ttl of zero disables that bound.
+func NewMemoryStore(maxEntries int, ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+		tags:       make(map[string]map[string]struct{}),
+	}
+}
+
+// GetVMs retrieves VMs from the memory cache.
+// The returned slice is shared with the cache and must not be modified.
+func (ms *MemoryStore) GetVMs(ctx context.Context) ([]models.VM, error) {
+	value, ok := ms.get(vmsAllKey)
+	ms.counters.record(ok)
+	if !ok {
+		return nil, nil // Cache miss
+	}
+	return value.([]models.VM), nil
+}
+
+// SetVMs stores VMs in the memory cache
+func (ms *MemoryStore) SetVMs(ctx context.Context, vms []models.VM) error {
+	ms.set(vmsAllKey, vms, inventoryTags(vms))
+	return nil
+}
+
+// InvalidateVMs removes VMs from the memory cache
+func (ms *MemoryStore) InvalidateVMs(ctx context.Context) error {
+	ms.generation.Add(1)
+	ms.delete(vmsAllKey)
+	return nil
+}
+
+// GetQuery retrieves a query result from the memory cache
+func (ms *MemoryStore) GetQuery(ctx context.Context, key string) (*QueryResult, error) {
+	value, ok := ms.get(key)
+	ms.counters.record(ok)
+	if !ok {
+		return nil, nil // Cache miss
+	}
+	return value.(*QueryResult), nil
+}
+
+// SetQuery stores a query result in the memory cache
+func (ms *MemoryStore) SetQuery(ctx context.Context, key string, result *QueryResult) error {
+	ms.set(key, result, result.Tags)
+	return nil
+}
+
+// InvalidateTags removes every entry carrying any of the tags
+func (ms *MemoryStore) InvalidateTags(ctx context.Context, tags ...string) (int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.generation.Add(1)
+
+	removed := 0
+	for _, tag := range tags {
+		for key := range ms.tags[tag] {
+			if elem, ok := ms.entries[key]; ok {
+				ms.removeElement(elem)
+				removed++
+			}
+		}
+	}
+	return removed, nil
+}
+
+// Generation returns the number of invalidations so far
+func (ms *MemoryStore) Generation() uint64 {
+	return ms.generation.Load()
+}
+
+// Stats returns hit and miss counters along with the current entry count
+func (ms *MemoryStore) Stats() Stats {
+	stats := ms.counters.stats(ModeMemory)
+	stats.Entries = ms.Len()
+	return stats
+}
+
+// Ping always succeeds for the memory cache
+func (ms *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close drops all cached entries
+func (ms *MemoryStore) Close() error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.order.Init()
+	ms.entries = make(map[string]*list.Element)
+	ms.tags = make(map[string]map[string]struct{})
+	return nil
+}
+
+// Len returns the number of entries currently held, including expired ones not yet evicted
+func (ms *MemoryStore) Len() int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.order.Len()
+}
+
+// get returns the value for a key and marks it as recently used
+func (ms *MemoryStore) get(key string) (interface{}, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	elem, ok := ms.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		ms.removeElement(elem)
+		return nil, false
+	}
+
+	ms.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// set stores a value under the given tags, evicting the least recently used entry when the store is full
+func (ms *MemoryStore) set(key string, value interface{}, tags []string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if elem, ok := ms.entries[key]; ok {
+		ms.removeElement(elem)
+	}
+
+	var expiresAt time.Time
+	if ms.ttl > 0 {
+		expiresAt = time.Now().Add(ms.ttl)
+	}
+
+	ms.entries[key] = ms.order.PushFront(&memoryEntry{key: key, value: value, tags: tags, expiresAt: expiresAt})
+	for _, tag := range tags {
+		if ms.tags[tag] == nil {
+			ms.tags[tag] = make(map[string]struct{})
+		}
+		ms.tags[tag][key] = struct{}{}
+	}
+
+	if ms.maxEntries > 0 {
+		for ms.order.Len() > ms.maxEntries {
+			ms.removeElement(ms.order.Back())
+		}
+	}
+}
+
+// delete removes a key from the store
+func (ms *MemoryStore) delete(key string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if elem, ok := ms.entries[key]; ok {
+		ms.removeElement(elem)
+	}
+}
+
+// removeElement unlinks an entry and its tag references; the caller must hold the lock
+func (ms *MemoryStore) removeElement(elem *list.Element) {
+	entry := elem.Value.(*memoryEntry)
+	ms.order.Remove(elem)
+	delete(ms.entries, entry.key)
+
+	for _, tag := range entry.tags {
+		delete(ms.tags[tag], entry.key)
+		if len(ms.tags[tag]) == 0 {
+			delete(ms.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang-service/internal/config"
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	vms := []models.VM{{ID: "vm-1", Name: "web-01", CloudType: "aws"}}
+
+	t.Run("Miss before set", func(t *testing.T) {
+		store := NewMemoryStore(10, time.Minute)
+
+		cached, err := store.GetVMs(ctx)
+		assert.NoError(t, err)
+		assert.Nil(t, cached)
+	})
+
+	t.Run("Set, get and invalidate", func(t *testing.T) {
+		store := NewMemoryStore(10, time.Minute)
+
+		assert.NoError(t, store.SetVMs(ctx, vms))
+		cached, err := store.GetVMs(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, vms, cached)
+
+		assert.NoError(t, store.InvalidateVMs(ctx))
+		cached, err = store.GetVMs(ctx)
+		assert.NoError(t, err)
+		assert.Nil(t, cached)
+	})
+
+	t.Run("Entries expire after TTL", func(t *testing.T) {
+		store := NewMemoryStore(10, 10*time.Millisecond)
+
+		assert.NoError(t, store.SetVMs(ctx, vms))
+		time.Sleep(20 * time.Millisecond)
+
+		cached, err := store.GetVMs(ctx)
+		assert.NoError(t, err)
+		assert.Nil(t, cached)
+		assert.Equal(t, 0, store.Len())
+	})
+
+	t.Run("Least recently used entry is evicted", func(t *testing.T) {
+		store := NewMemoryStore(2, 0)
+
+		store.set("a", 1, nil)
+		store.set("b", 2, nil)
+		store.get("a")
+		store.set("c", 3, nil)
+
+		_, ok := store.get("b")
+		assert.False(t, ok)
+		_, ok = store.get("a")
+		assert.True(t, ok)
+		_, ok = store.get("c")
+		assert.True(t, ok)
+		assert.Equal(t, 2, store.Len())
+	})
+}
+
+func TestMemoryStoreTags(t *testing.T) {
+	ctx := context.Background()
+	vms := []models.VM{
+		{ID: "vm-1", CloudType: "aws", Env: "prod0"},
+		{ID: "vm-2", CloudType: "gcp"},
+	}
+
+	t.Run("Invalidating a provider drops inventory and matching queries", func(t *testing.T) {
+		store := NewMemoryStore(10, time.Minute)
+
+		assert.NoError(t, store.SetVMs(ctx, vms))
+		assert.NoError(t, store.SetQuery(ctx, "aws", &QueryResult{VMs: vms[:1], TotalItems: 1, Tags: QueryTags([]string{"aws"}, vms[:1])}))
+		assert.NoError(t, store.SetQuery(ctx, "gcp", &QueryResult{VMs: vms[1:], TotalItems: 1, Tags: QueryTags([]string{"gcp"}, vms[1:])}))
+
+		removed, err := store.InvalidateTags(ctx, ProviderTag("aws"))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, removed)
+
+		result, _ := store.GetQuery(ctx, "gcp")
+		assert.NotNil(t, result)
+		result, _ = store.GetQuery(ctx, "aws")
+		assert.Nil(t, result)
+		cached, _ := store.GetVMs(ctx)
+		assert.Nil(t, cached)
+	})
+
+	t.Run("Environment tags follow resolved environments", func(t *testing.T) {
+		store := NewMemoryStore(10, time.Minute)
+
+		assert.NoError(t, store.SetQuery(ctx, "prod", &QueryResult{Tags: QueryTags(CloudTypes, vms[:1])}))
+		assert.NoError(t, store.SetQuery(ctx, "other", &QueryResult{Tags: QueryTags(CloudTypes, vms[1:])}))
+
+		removed, err := store.InvalidateTags(ctx, EnvironmentTag("prod0"))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, removed)
+
+		removed, err = store.InvalidateTags(ctx, TagEnvironments)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, removed)
+		assert.Equal(t, 0, store.Len())
+	})
+
+	t.Run("Stats count hits and misses", func(t *testing.T) {
+		store := NewMemoryStore(10, time.Minute)
+
+		store.GetQuery(ctx, "missing")
+		store.SetQuery(ctx, "present", &QueryResult{})
+		store.GetQuery(ctx, "present")
+		store.GetQuery(ctx, "present")
+
+		stats := store.Stats()
+		assert.Equal(t, int64(2), stats.Hits)
+		assert.Equal(t, int64(1), stats.Misses)
+		assert.InDelta(t, 2.0/3.0, stats.HitRate, 0.001)
+		assert.Equal(t, 1, stats.Entries)
+	})
+}
+
+func TestQueryKey(t *testing.T) {
+	a := []config.FilterParam{
+		{Field: "status", Operator: config.OperatorEquals, Value: "running"},
+		{Field: "cloudType", Operator: config.OperatorIn, Value: "aws,gcp"},
+	}
+	b := []config.FilterParam{a[1], a[0]}
+
+	assert.Equal(t, QueryKey(a, "name", "asc", 1, 10), QueryKey(b, "name", "asc", 1, 10))
+	assert.NotEqual(t, QueryKey(a, "name", "asc", 1, 10), QueryKey(a, "name", "asc", 2, 10))
+	assert.NotEqual(t, QueryKey(a, "name", "asc", 1, 10), QueryKey(a, "name", "desc", 1, 10))
+}
+
+func TestTieredStore(t *testing.T) {
+	ctx := context.Background()
+	vms := []models.VM{{ID: "vm-1", Name: "web-01", CloudType: "aws"}}
+
+	t.Run("L2 hit populates L1", func(t *testing.T) {
+		local := NewMemoryStore(10, time.Minute)
+		remote := NewMemoryStore(10, time.Minute)
+		store := NewTieredStore(local, remote, nil)
+
+		assert.NoError(t, remote.SetVMs(ctx, vms))
+
+		cached, err := store.GetVMs(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, vms, cached)
+
+		cached, err = local.GetVMs(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, vms, cached)
+	})
+
+	t.Run("Writes and invalidations reach both tiers", func(t *testing.T) {
+		local := NewMemoryStore(10, time.Minute)
+		remote := NewMemoryStore(10, time.Minute)
+		store := NewTieredStore(local, remote, nil)
+
+		assert.NoError(t, store.SetVMs(ctx, vms))
+		assert.Equal(t, 1, local.Len())
+		assert.Equal(t, 1, remote.Len())
+
+		assert.NoError(t, store.InvalidateVMs(ctx))
+		assert.Equal(t, 0, local.Len())
+		assert.Equal(t, 0, remote.Len())
+	})
+
+	t.Run("Invalidations advance the generation", func(t *testing.T) {
+		store := NewTieredStore(NewMemoryStore(10, time.Minute), NewMemoryStore(10, time.Minute), nil)
+
+		before := store.Generation()
+		_, err := store.InvalidateTags(ctx, ProviderTag("aws"))
+		assert.NoError(t, err)
+		assert.Greater(t, store.Generation(), before)
+	})
+
+	t.Run("Invalidations reach the L1 of other replicas", func(t *testing.T) {
+		remote := NewMemoryStore(10, time.Minute)
+		bus := &fakeBus{}
+		first := NewTieredStore(NewMemoryStore(10, time.Minute), remote, bus)
+		secondLocal := NewMemoryStore(10, time.Minute)
+		second := NewTieredStore(secondLocal, remote, bus)
+		first.Start(ctx)
+		second.Start(ctx)
+
+		assert.NoError(t, second.SetVMs(ctx, vms))
+		before := second.Generation()
+		_, err := first.InvalidateTags(ctx, ProviderTag("aws"))
+		assert.NoError(t, err)
+
+		assert.Equal(t, 0, secondLocal.Len())
+		assert.Greater(t, second.Generation(), before)
+	})
+}
+
+// fakeBus delivers published invalidations to every subscriber synchronously
+type fakeBus struct {
+	subscribers []func(Invalidation)
+}
+
+func (b *fakeBus) PublishInvalidation(ctx context.Context, invalidation Invalidation) error {
+	for _, fn := range b.subscribers {
+		fn(invalidation)
+	}
+	return nil
+}
+
+func (b *fakeBus) SubscribeInvalidations(ctx context.Context, fn func(Invalidation)) {
+	b.subscribers = append(b.subscribers, fn)
+}
+
+func TestNewStore(t *testing.T) {
+	t.Run("Falls back to memory without Redis", func(t *testing.T) {
+		for _, mode := range []string{ModeMemory, ModeRedis, ModeTiered} {
+			store, err := NewStore(mode, nil, 10, time.Minute)
+			assert.NoError(t, err)
+			_, ok := store.(*MemoryStore)
+			assert.True(t, ok, mode)
+		}
+	})
+
+	t.Run("Rejects unknown modes", func(t *testing.T) {
+		_, err := NewStore("teired", nil, 10, time.Minute)
+		assert.ErrorContains(t, err, `unknown CACHE_MODE "teired"`)
+	})
+
+	t.Run("Selects store by mode", func(t *testing.T) {
+		redisCache, err := NewRedisCache(&config.Config{RedisURL: "redis://localhost:6379/0"})
+		assert.NoError(t, err)
+		defer redisCache.Close()
+
+		for mode, want := range map[string]Store{
+			ModeRedis:  &RedisCache{},
+			ModeTiered: &TieredStore{},
+			ModeMemory: &MemoryStore{},
+		} {
+			store, err := NewStore(mode, redisCache, 10, time.Minute)
+			assert.NoError(t, err)
+			assert.IsType(t, want, store, mode)
+		}
+	})
+}
@@ -2,74 +2,177 @@ package cache
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"golang-service/internal/config"
 	"golang-service/internal/models"
 	"github.com/redis/go-redis/v9"
 )
 
-// RedisCache handles Redis operations for VM data caching
+// RedisCache handles Redis operations for VM data caching.
+// Values are stored in the versioned binary encoding from codec.go; values
+// larger than the chunk size are split across several keys.
 type RedisCache struct {
-	client *redis.Client
+	client     redis.UniversalClient
+	ttl        time.Duration
+	queryTTL   time.Duration
+	chunkSize  int
+	counters   counters
+	generation atomic.Uint64
 }
 
-// NewRedisCache creates a new Redis cache instance
-func NewRedisCache(addr string, password string, db int) *RedisCache {
-	client := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: password,
-		DB:       db,
-	})
+// tagKeyPrefix prefixes the Redis sets indexing cache keys by tag
+const tagKeyPrefix = "vms:tag:"
 
-	return &RedisCache{client: client}
+// invalidationChannel is the pub/sub channel relaying invalidations to every replica
+const invalidationChannel = "vms:invalidations"
+
+// errChunkMissing reports a chunked value whose pieces have expired or been evicted
+var errChunkMissing = errors.New("cache entry chunk missing")
+
+var _ Store = (*RedisCache)(nil)
+
+// NewRedisCache creates a new Redis cache instance from configuration.
+// Depending on the configuration it connects to a single node, a sentinel-managed master or a cluster.
+func NewRedisCache(cfg *config.Config) (*RedisCache, error) {
+	client, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisCache{
+		client:    client,
+		ttl:       cfg.CacheTTL,
+		queryTTL:  cfg.CacheQueryTTL,
+		chunkSize: cfg.CacheChunkSize,
+	}, nil
 }
 
-// GetVMs retrieves VMs from cache
-func (rc *RedisCache) GetVMs(ctx context.Context) ([]models.VM, error) {
-	key := "vms:all"
-	data, err := rc.client.Get(ctx, key).Result()
+// ConnectRedis creates the Redis cache and waits until it answers a ping.
+// Failed attempts are retried with exponential backoff; the cache is returned
+// alongside the last error so callers can still report its state.
+func ConnectRedis(ctx context.Context, cfg *config.Config) (*RedisCache, error) {
+	rc, err := NewRedisCache(cfg)
 	if err != nil {
-		if err == redis.Nil {
-			return nil, nil // Cache miss
+		return nil, err
+	}
+
+	backoff := cfg.RedisConnectBackoff
+	for attempt := 0; ; attempt++ {
+		if err = rc.Ping(ctx); err == nil {
+			return rc, nil
+		}
+		if attempt >= cfg.RedisConnectRetries {
+			return rc, fmt.Errorf("redis not reachable after %d attempts: %w", attempt+1, err)
+		}
+
+		log.Printf("Redis connection attempt %d failed: %v (retrying in %v)", attempt+1, err, backoff)
+		select {
+		case <-ctx.Done():
+			return rc, ctx.Err()
+		case <-time.After(backoff):
 		}
-		return nil, fmt.Errorf("failed to get VMs from cache: %w", err)
+		backoff *= 2
+	}
+}
+
+// newRedisClient builds the Redis client matching the configured topology
+func newRedisClient(cfg *config.Config) (redis.UniversalClient, error) {
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+
+	addrs := []string{opts.Addr}
+	if len(cfg.RedisAddrs) > 0 {
+		addrs = cfg.RedisAddrs
+	}
+	if cfg.RedisPassword != "" {
+		opts.Password = cfg.RedisPassword
+	}
+	if cfg.RedisDB >= 0 {
+		opts.DB = cfg.RedisDB
+	}
+	if cfg.RedisTLS && opts.TLSConfig == nil {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if opts.TLSConfig != nil {
+		opts.TLSConfig.InsecureSkipVerify = cfg.RedisTLSInsecureSkipVerify
 	}
 
+	switch {
+	case cfg.RedisCluster:
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        addrs,
+			Username:     opts.Username,
+			Password:     opts.Password,
+			TLSConfig:    opts.TLSConfig,
+			PoolSize:     cfg.RedisPoolSize,
+			DialTimeout:  cfg.RedisDialTimeout,
+			ReadTimeout:  cfg.RedisReadTimeout,
+			WriteTimeout: cfg.RedisWriteTimeout,
+		}), nil
+	case cfg.RedisSentinelMaster != "":
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    cfg.RedisSentinelMaster,
+			SentinelAddrs: addrs,
+			Username:      opts.Username,
+			Password:      opts.Password,
+			DB:            opts.DB,
+			TLSConfig:     opts.TLSConfig,
+			PoolSize:      cfg.RedisPoolSize,
+			DialTimeout:   cfg.RedisDialTimeout,
+			ReadTimeout:   cfg.RedisReadTimeout,
+			WriteTimeout:  cfg.RedisWriteTimeout,
+		}), nil
+	default:
+		opts.Addr = addrs[0]
+		opts.PoolSize = cfg.RedisPoolSize
+		opts.DialTimeout = cfg.RedisDialTimeout
+		opts.ReadTimeout = cfg.RedisReadTimeout
+		opts.WriteTimeout = cfg.RedisWriteTimeout
+		return redis.NewClient(opts), nil
+	}
+}
+
+// GetVMs retrieves VMs from cache
+func (rc *RedisCache) GetVMs(ctx context.Context) ([]models.VM, error) {
 	var vms []models.VM
-	if err := json.Unmarshal([]byte(data), &vms); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal VMs from cache: %w", err)
+	found, err := rc.getEncoded(ctx, vmsAllKey, &vms)
+	if err != nil || !found {
+		return nil, err
 	}
 
 	log.Printf("Retrieved %d VMs from cache", len(vms))
 	return vms, nil
 }
 
-// SetVMs stores VMs in cache with 24-hour expiry
+// SetVMs stores VMs in cache with the configured expiry
 func (rc *RedisCache) SetVMs(ctx context.Context, vms []models.VM) error {
-	key := "vms:all"
-	data, err := json.Marshal(vms)
-	if err != nil {
-		return fmt.Errorf("failed to marshal VMs for cache: %w", err)
-	}
-
-	// Set with 24-hour expiry
-	expiry := 24 * time.Hour
-	err = rc.client.Set(ctx, key, data, expiry).Err()
-	if err != nil {
+	if err := rc.setEncoded(ctx, vmsAllKey, vms, inventoryTags(vms), rc.ttl); err != nil {
 		return fmt.Errorf("failed to set VMs in cache: %w", err)
 	}
 
-	log.Printf("Stored %d VMs in cache with %v expiry", len(vms), expiry)
+	log.Printf("Stored %d VMs in cache with %v expiry", len(vms), rc.ttl)
 	return nil
 }
 
 // InvalidateVMs removes VMs from cache
 func (rc *RedisCache) InvalidateVMs(ctx context.Context) error {
-	key := "vms:all"
-	err := rc.client.Del(ctx, key).Err()
+	rc.generation.Add(1)
+	// Chunks share the key's hash slot, so a single DEL also works on a cluster
+	keys := append([]string{vmsAllKey}, rc.chunkKeys(ctx, vmsAllKey)...)
+	err := rc.client.Del(ctx, keys...).Err()
 	if err != nil {
 		return fmt.Errorf("failed to invalidate VMs cache: %w", err)
 	}
@@ -78,6 +181,240 @@ func (rc *RedisCache) InvalidateVMs(ctx context.Context) error {
 	return nil
 }
 
+// GetQuery retrieves a query result from cache
+func (rc *RedisCache) GetQuery(ctx context.Context, key string) (*QueryResult, error) {
+	var result QueryResult
+	found, err := rc.getEncoded(ctx, key, &result)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SetQuery stores a query result in cache with the query expiry
+func (rc *RedisCache) SetQuery(ctx context.Context, key string, result *QueryResult) error {
+	if err := rc.setEncoded(ctx, key, result, result.Tags, rc.queryTTL); err != nil {
+		return fmt.Errorf("failed to set query result in cache: %w", err)
+	}
+	return nil
+}
+
+// InvalidateTags removes every key indexed under any of the tags
+func (rc *RedisCache) InvalidateTags(ctx context.Context, tags ...string) (int, error) {
+	rc.generation.Add(1)
+	removed := 0
+	for _, tag := range tags {
+		tagKey := tagKeyPrefix + tag
+		keys, err := rc.client.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return removed, fmt.Errorf("failed to read cache tag %s: %w", tag, err)
+		}
+
+		// Delete keys one by one so the pipeline also works across cluster slots
+		pipe := rc.client.Pipeline()
+		dels := make([]*redis.IntCmd, 0, len(keys))
+		for _, key := range keys {
+			dels = append(dels, pipe.Del(ctx, key))
+		}
+		pipe.Del(ctx, tagKey)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return removed, fmt.Errorf("failed to invalidate cache tag %s: %w", tag, err)
+		}
+		for i, del := range dels {
+			if !isChunkKey(keys[i]) {
+				removed += int(del.Val())
+			}
+		}
+	}
+
+	log.Printf("Invalidated %d cache entries for tags %v", removed, tags)
+	return removed, nil
+}
+
+// Generation returns the number of invalidations made by this process
+func (rc *RedisCache) Generation() uint64 {
+	return rc.generation.Load()
+}
+
+// Stats returns hit and miss counters for this process
+func (rc *RedisCache) Stats() Stats {
+	return rc.counters.stats(ModeRedis)
+}
+
+// getEncoded reads and decodes a cached value, recording a hit or miss.
+// Entries written with another schema version or missing chunks count as misses.
+func (rc *RedisCache) getEncoded(ctx context.Context, key string, value interface{}) (bool, error) {
+	data, err := rc.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			rc.counters.record(false)
+			return false, nil // Cache miss
+		}
+		return false, fmt.Errorf("failed to get %s from cache: %w", key, err)
+	}
+
+	manifest, err := decodeManifest(data)
+	if err == nil && manifest != nil {
+		data, err = rc.readChunks(ctx, key, manifest)
+	}
+	if err == nil {
+		err = decodeValue(data, value)
+	}
+	if err != nil {
+		if errors.Is(err, errSchemaMismatch) || errors.Is(err, errChunkMissing) {
+			log.Printf("Ignoring cache entry %s: %v", key, err)
+			rc.counters.record(false)
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to decode %s from cache: %w", key, err)
+	}
+
+	rc.counters.record(true)
+	return true, nil
+}
+
+// setEncoded encodes and stores a value, indexing its key under each tag.
+// Values above the chunk size are written as chunks followed by a manifest under
+// the key itself, so readers never see a manifest before its chunks exist.
+func (rc *RedisCache) setEncoded(ctx context.Context, key string, value interface{}, tags []string, ttl time.Duration) error {
+	data, err := encodeValue(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s for cache: %w", key, err)
+	}
+
+	stale := rc.chunkKeys(ctx, key)
+	members := []interface{}{key}
+
+	pipe := rc.client.Pipeline()
+	if rc.chunkSize > 0 && len(data) > rc.chunkSize {
+		chunks := splitChunks(data, rc.chunkSize)
+		manifest := chunkManifest{ID: newChunkID(), Chunks: len(chunks), Size: len(data)}
+		for i, chunk := range chunks {
+			chunkKey := chunkKey(key, manifest.ID, i)
+			pipe.Set(ctx, chunkKey, chunk, ttl)
+			members = append(members, chunkKey)
+		}
+		if data, err = encodeManifest(manifest); err != nil {
+			return fmt.Errorf("failed to encode chunk manifest for %s: %w", key, err)
+		}
+	}
+	pipe.Set(ctx, key, data, ttl)
+
+	for _, tag := range tags {
+		tagKey := tagKeyPrefix + tag
+		pipe.SAdd(ctx, tagKey, members...)
+		// Tag sets outlive their longest member; stale members are harmless on invalidation
+		if rc.ttl > 0 {
+			pipe.Expire(ctx, tagKey, rc.ttl)
+		}
+	}
+	if len(stale) > 0 {
+		pipe.Del(ctx, stale...)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// readChunks fetches and reassembles the pieces listed in a manifest
+func (rc *RedisCache) readChunks(ctx context.Context, key string, manifest *chunkManifest) ([]byte, error) {
+	pipe := rc.client.Pipeline()
+	gets := make([]*redis.StringCmd, manifest.Chunks)
+	for i := range gets {
+		gets[i] = pipe.Get(ctx, chunkKey(key, manifest.ID, i))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get chunks of %s from cache: %w", key, err)
+	}
+
+	data := make([]byte, 0, manifest.Size)
+	for _, get := range gets {
+		chunk, err := get.Bytes()
+		if err != nil {
+			return nil, errChunkMissing
+		}
+		data = append(data, chunk...)
+	}
+	if len(data) != manifest.Size {
+		return nil, errChunkMissing
+	}
+	return data, nil
+}
+
+// chunkKeys returns the chunk keys currently referenced by the value at key, if it is chunked
+func (rc *RedisCache) chunkKeys(ctx context.Context, key string) []string {
+	data, err := rc.client.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil
+	}
+	manifest, err := decodeManifest(data)
+	if err != nil || manifest == nil {
+		return nil
+	}
+
+	keys := make([]string, manifest.Chunks)
+	for i := range keys {
+		keys[i] = chunkKey(key, manifest.ID, i)
+	}
+	return keys
+}
+
+// chunkKey names one piece of a chunked value. The {key} hash tag keeps every
+// chunk in the same cluster slot as the manifest.
+func chunkKey(key, id string, index int) string {
+	return "{" + key + "}:chunk:" + id + ":" + strconv.Itoa(index)
+}
+
+// isChunkKey reports whether a key holds a piece of a chunked value
+func isChunkKey(key string) bool {
+	return strings.HasPrefix(key, "{")
+}
+
+// newChunkID returns a random identifier for a set of chunks
+func newChunkID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// PublishInvalidation announces an invalidation to every subscribed replica
+func (rc *RedisCache) PublishInvalidation(ctx context.Context, invalidation Invalidation) error {
+	payload, err := json.Marshal(invalidation)
+	if err != nil {
+		return err
+	}
+	if err := rc.client.Publish(ctx, invalidationChannel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish cache invalidation: %w", err)
+	}
+	return nil
+}
+
+// SubscribeInvalidations calls fn with every invalidation published until ctx is done.
+// The subscription reconnects by itself; invalidations published while it is
+// disconnected are lost, which the local cache TTL bounds.
+func (rc *RedisCache) SubscribeInvalidations(ctx context.Context, fn func(Invalidation)) {
+	pubsub := rc.client.Subscribe(ctx, invalidationChannel)
+	go func() {
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				var invalidation Invalidation
+				if err := json.Unmarshal([]byte(message.Payload), &invalidation); err != nil {
+					log.Printf("Ignoring malformed cache invalidation: %v", err)
+					continue
+				}
+				fn(invalidation)
+			}
+		}
+	}()
+}
+
 // Close closes the Redis connection
 func (rc *RedisCache) Close() error {
 	return rc.client.Close()
@@ -86,4 +423,4 @@ func (rc *RedisCache) Close() error {
 // Ping tests the Redis connection
 func (rc *RedisCache) Ping(ctx context.Context) error {
 	return rc.client.Ping(ctx).Err()
-} 
\ No newline at end of file
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang-service/internal/config"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedisClient(t *testing.T) {
+	t.Run("Single node from URL with overrides", func(t *testing.T) {
+		client, err := newRedisClient(&config.Config{
+			RedisURL:      "rediss://:url-secret@cache.internal:6380/2",
+			RedisPassword: "override",
+			RedisDB:       -1,
+		})
+		assert.NoError(t, err)
+		defer client.Close()
+
+		single, ok := client.(*redis.Client)
+		assert.True(t, ok)
+		assert.Equal(t, "cache.internal:6380", single.Options().Addr)
+		assert.Equal(t, "override", single.Options().Password)
+		assert.Equal(t, 2, single.Options().DB)
+		assert.NotNil(t, single.Options().TLSConfig)
+	})
+
+	t.Run("Cluster mode uses configured addresses", func(t *testing.T) {
+		client, err := newRedisClient(&config.Config{
+			RedisURL:     "redis://localhost:6379/0",
+			RedisAddrs:   []string{"node-1:6379", "node-2:6379"},
+			RedisCluster: true,
+			RedisTLS:     true,
+		})
+		assert.NoError(t, err)
+		defer client.Close()
+
+		cluster, ok := client.(*redis.ClusterClient)
+		assert.True(t, ok)
+		assert.Equal(t, []string{"node-1:6379", "node-2:6379"}, cluster.Options().Addrs)
+		assert.NotNil(t, cluster.Options().TLSConfig)
+	})
+
+	t.Run("Invalid URL", func(t *testing.T) {
+		_, err := newRedisClient(&config.Config{RedisURL: "http://localhost"})
+		assert.Error(t, err)
+	})
+}
+
+func TestConnectRedis(t *testing.T) {
+	t.Run("Gives up after configured retries", func(t *testing.T) {
+		cfg := &config.Config{
+			RedisURL:            "redis://127.0.0.1:1/0",
+			RedisDB:             -1,
+			RedisDialTimeout:    100 * time.Millisecond,
+			RedisConnectRetries: 1,
+			RedisConnectBackoff: 10 * time.Millisecond,
+		}
+
+		rc, err := ConnectRedis(context.Background(), cfg)
+		assert.Error(t, err)
+		assert.NotNil(t, rc)
+		rc.Close()
+	})
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync/atomic"
+
+	"golang-service/internal/models"
+)
+
+// Invalidation tells the replicas sharing a remote tier which entries to drop from
+// their local tier
+type Invalidation struct {
+	Origin string   `json:"origin"` // the store that made the invalidation
+	Tags   []string `json:"tags,omitempty"`
+	VMs    bool     `json:"vms,omitempty"` // the full VM inventory
+}
+
+// InvalidationBus relays invalidations between replicas
+type InvalidationBus interface {
+	PublishInvalidation(ctx context.Context, invalidation Invalidation) error
+	SubscribeInvalidations(ctx context.Context, fn func(Invalidation))
+}
+
+// TieredStore serves reads from a local L1 cache in front of a shared L2 cache.
+// Writes and invalidations go to both tiers, and invalidations are published on the
+// bus so that other replicas drop their L1 entries too. Should a replica miss a
+// message, the L1 TTL still bounds how long it serves stale entries.
+type TieredStore struct {
+	local      Store
+	remote     Store
+	bus        InvalidationBus
+	id         string
+	counters   counters
+	generation atomic.Uint64
+}
+
+// NewTieredStore creates a two-tier cache. The bus may be nil for a single replica.
+func NewTieredStore(local, remote Store, bus InvalidationBus) *TieredStore {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return &TieredStore{local: local, remote: remote, bus: bus, id: hex.EncodeToString(id)}
+}
+
+// Start applies invalidations published by other replicas to the local tier until ctx
+// is done
+func (ts *TieredStore) Start(ctx context.Context) {
+	if ts.bus == nil {
+		return
+	}
+	ts.bus.SubscribeInvalidations(ctx, func(invalidation Invalidation) {
+		if invalidation.Origin == ts.id {
+			return
+		}
+		ts.generation.Add(1)
+		if invalidation.VMs {
+			if err := ts.local.InvalidateVMs(ctx); err != nil {
+				log.Printf("Failed to invalidate local cache: %v", err)
+			}
+		}
+		if len(invalidation.Tags) > 0 {
+			if _, err := ts.local.InvalidateTags(ctx, invalidation.Tags...); err != nil {
+				log.Printf("Failed to invalidate local cache tags: %v", err)
+			}
+		}
+	})
+}
+
+// publish announces an invalidation made through this store to the other replicas
+func (ts *TieredStore) publish(ctx context.Context, invalidation Invalidation) {
+	if ts.bus == nil {
+		return
+	}
+	invalidation.Origin = ts.id
+	if err := ts.bus.PublishInvalidation(ctx, invalidation); err != nil {
+		log.Printf("Failed to publish cache invalidation: %v", err)
+	}
+}
+
+// GetVMs retrieves VMs from L1, falling back to L2 and populating L1 on an L2 hit
+func (ts *TieredStore) GetVMs(ctx context.Context) ([]models.VM, error) {
+	vms, err := ts.local.GetVMs(ctx)
+	if err == nil && vms != nil {
+		ts.counters.record(true)
+		return vms, nil
+	}
+
+	vms, err = ts.remote.GetVMs(ctx)
+	ts.counters.record(err == nil && vms != nil)
+	if err != nil || vms == nil {
+		return nil, err
+	}
+
+	if err := ts.local.SetVMs(ctx, vms); err != nil {
+		log.Printf("Failed to populate local cache: %v", err)
+	}
+
+	return vms, nil
+}
+
+// SetVMs stores VMs in both tiers
+func (ts *TieredStore) SetVMs(ctx context.Context, vms []models.VM) error {
+	if err := ts.local.SetVMs(ctx, vms); err != nil {
+		log.Printf("Failed to store VMs in local cache: %v", err)
+	}
+	return ts.remote.SetVMs(ctx, vms)
+}
+
+// InvalidateVMs removes VMs from both tiers
+func (ts *TieredStore) InvalidateVMs(ctx context.Context) error {
+	ts.generation.Add(1)
+	if err := ts.local.InvalidateVMs(ctx); err != nil {
+		log.Printf("Failed to invalidate local cache: %v", err)
+	}
+	err := ts.remote.InvalidateVMs(ctx)
+	ts.publish(ctx, Invalidation{VMs: true})
+	return err
+}
+
+// GetQuery retrieves a query result from L1, falling back to L2 and populating L1 on an L2 hit
+func (ts *TieredStore) GetQuery(ctx context.Context, key string) (*QueryResult, error) {
+	result, err := ts.local.GetQuery(ctx, key)
+	if err == nil && result != nil {
+		ts.counters.record(true)
+		return result, nil
+	}
+
+	result, err = ts.remote.GetQuery(ctx, key)
+	ts.counters.record(err == nil && result != nil)
+	if err != nil || result == nil {
+		return nil, err
+	}
+
+	if err := ts.local.SetQuery(ctx, key, result); err != nil {
+		log.Printf("Failed to populate local cache: %v", err)
+	}
+
+	return result, nil
+}
+
+// SetQuery stores a query result in both tiers
+func (ts *TieredStore) SetQuery(ctx context.Context, key string, result *QueryResult) error {
+	if err := ts.local.SetQuery(ctx, key, result); err != nil {
+		log.Printf("Failed to store query result in local cache: %v", err)
+	}
+	return ts.remote.SetQuery(ctx, key, result)
+}
+
+// InvalidateTags removes tagged entries from both tiers and returns the count removed from L2
+func (ts *TieredStore) InvalidateTags(ctx context.Context, tags ...string) (int, error) {
+	ts.generation.Add(1)
+	if _, err := ts.local.InvalidateTags(ctx, tags...); err != nil {
+		log.Printf("Failed to invalidate local cache tags: %v", err)
+	}
+	removed, err := ts.remote.InvalidateTags(ctx, tags...)
+	ts.publish(ctx, Invalidation{Tags: tags})
+	return removed, err
+}
+
+// Generation returns the number of invalidations made through this store
+func (ts *TieredStore) Generation() uint64 {
+	return ts.generation.Load()
+}
+
+// Stats returns the combined counters along with those of each tier
+func (ts *TieredStore) Stats() Stats {
+	stats := ts.counters.stats(ModeTiered)
+	stats.Tiers = []Stats{ts.local.Stats(), ts.remote.Stats()}
+	return stats
+}
+
+// Ping tests the shared tier
+func (ts *TieredStore) Ping(ctx context.Context) error {
+	return ts.remote.Ping(ctx)
+}
+
+// Close closes both tiers
+func (ts *TieredStore) Close() error {
+	ts.local.Close()
+	return ts.remote.Close()
+}
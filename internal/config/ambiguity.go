@@ -0,0 +1,103 @@
+package config
+
+import (
+	"sort"
+	"strings"
+
+	"golang-service/internal/models"
+)
+
+// maxSampleVMs bounds the VM IDs listed per group in reports
+const maxSampleVMs = 5
+
+// Ambiguity groups VMs whose best matching environments tie on priority and score,
+// so only the ID order decides which of them the VMs resolve to
+type Ambiguity struct {
+	// Environments lists the tied environment IDs; VMs resolve to the first
+	Environments []string `json:"environments"`
+	Priority     int      `json:"priority"`
+	Score        int      `json:"score"`
+	VMCount      int      `json:"vmCount"`
+	SampleVMs    []string `json:"sampleVms"`
+	// Reason suggests why the environments could not be told apart
+	Reason string `json:"reason"`
+}
+
+// TiedCandidates returns the leading matched candidates sharing the winner's priority, score
+// and depth. More than one means the resolution was decided by environment ID alone.
+func TiedCandidates(candidates []Candidate) []Candidate {
+	if len(candidates) == 0 || !candidates[0].Matched {
+		return nil
+	}
+	winner := candidates[0]
+	n := 1
+	for n < len(candidates) && candidates[n].Matched &&
+		candidates[n].Priority == winner.Priority && candidates[n].Score == winner.Score &&
+		len(candidates[n].Environment.Ancestors) == len(winner.Environment.Ancestors) {
+		n++
+	}
+	return candidates[:n]
+}
+
+// DetectAmbiguities evaluates every VM and reports the groups of environments that tie for
+// at least one of them, most affected VMs first
+func DetectAmbiguities(environments []models.Environment, vms []models.VM) []Ambiguity {
+	environments = InheritEnvironments(environments)
+	groups := make(map[string]*Ambiguity)
+	unknownVPC := make(map[string]int)
+
+	for _, vm := range vms {
+		tied := TiedCandidates(EvaluateEnvironments(environments, vm))
+		if len(tied) < 2 {
+			continue
+		}
+
+		ids := make([]string, len(tied))
+		vpcCriteria := true
+		for i, candidate := range tied {
+			ids[i] = candidate.Environment.ID
+			vpcCriteria = vpcCriteria && candidate.Environment.Criteria.VPC != ""
+		}
+		key := strings.Join(ids, "\x00")
+
+		group, ok := groups[key]
+		if !ok {
+			group = &Ambiguity{Environments: ids, Priority: tied[0].Priority, Score: tied[0].Score}
+			groups[key] = group
+		}
+		group.VMCount++
+		if len(group.SampleVMs) < maxSampleVMs {
+			group.SampleVMs = append(group.SampleVMs, vm.ID)
+		}
+		if vpcCriteria && vm.VpcID == "" {
+			unknownVPC[key]++
+		}
+	}
+
+	ambiguities := make([]Ambiguity, 0, len(groups))
+	for key, group := range groups {
+		if unknownVPC[key] == group.VMCount {
+			group.Reason = "the environments differ by vpc, but these VMs have no network information"
+		} else {
+			group.Reason = "no criterion distinguishes these environments; set a priority or add a discriminating criterion or rule"
+		}
+		ambiguities = append(ambiguities, *group)
+	}
+
+	sort.Slice(ambiguities, func(i, j int) bool {
+		if ambiguities[i].VMCount != ambiguities[j].VMCount {
+			return ambiguities[i].VMCount > ambiguities[j].VMCount
+		}
+		return strings.Join(ambiguities[i].Environments, ",") < strings.Join(ambiguities[j].Environments, ",")
+	})
+	return ambiguities
+}
+
+// DetectAmbiguities reports the environments of the current configuration that tie for the given VMs
+func (s *EnvironmentService) DetectAmbiguities(vms []models.VM) ([]Ambiguity, error) {
+	environments, err := s.GetEnvironments()
+	if err != nil {
+		return nil, err
+	}
+	return DetectAmbiguities(environments, vms), nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectAmbiguities(t *testing.T) {
+	environments := []models.Environment{
+		{ID: "prod0", Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "123456789012", Region: "us-east-1", VPC: "vpc-1"}},
+		{ID: "prod1", Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "123456789012", Region: "us-east-1", VPC: "vpc-2"}},
+		{ID: "gcp-a", Criteria: models.EnvironmentCriteria{CloudType: "gcp", Project: "p", Zone: "us-central1"}},
+		{ID: "gcp-b", Criteria: models.EnvironmentCriteria{CloudType: "gcp", Project: "p", Zone: "us-central1"}},
+		{ID: "gcp-pinned", Priority: 10, Criteria: models.EnvironmentCriteria{CloudType: "gcp", Project: "pinned", Zone: "us-central1"}},
+	}
+	vms := []models.VM{
+		// Resolved by VPC
+		{ID: "aws-1", CloudType: "aws", CloudAccountID: "123456789012", Location: "us-east-1", VpcID: "vpc-1"},
+		// No network information: prod0 and prod1 tie
+		{ID: "aws-2", CloudType: "aws", CloudAccountID: "123456789012", Location: "us-east-1"},
+		// Identical criteria tie for every VM
+		{ID: "gcp-1", CloudType: "gcp", CloudAccountID: "p", Location: "us-central1-a"},
+		{ID: "gcp-2", CloudType: "gcp", CloudAccountID: "p", Location: "us-central1-b"},
+		{ID: "gcp-3", CloudType: "gcp", CloudAccountID: "pinned", Location: "us-central1-b"},
+	}
+
+	ambiguities := DetectAmbiguities(environments, vms)
+	require.Len(t, ambiguities, 2)
+
+	assert.Equal(t, []string{"gcp-a", "gcp-b"}, ambiguities[0].Environments)
+	assert.Equal(t, 2, ambiguities[0].VMCount)
+	assert.Equal(t, []string{"gcp-1", "gcp-2"}, ambiguities[0].SampleVMs)
+	assert.Contains(t, ambiguities[0].Reason, "no criterion distinguishes")
+
+	assert.Equal(t, []string{"prod0", "prod1"}, ambiguities[1].Environments)
+	assert.Equal(t, 3, ambiguities[1].Score)
+	assert.Equal(t, []string{"aws-2"}, ambiguities[1].SampleVMs)
+	assert.Contains(t, ambiguities[1].Reason, "no network information")
+}
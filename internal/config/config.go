@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all configuration for the application
@@ -14,12 +18,83 @@ type Config struct {
 	AzureClientID      string
 	AzureClientSecret  string
 	JWTSecret          string
-	BypassAuth         bool
+	AuthMode           string // oidc, or dev to trust tokens minted by POST /dev/token
+	DevAuthKeyPath     string // PEM private key dev tokens are signed with; generated per start when empty
 	AzureAuthScope     string
 	AzureTokenEndpoint string
+	DevRoles           []string // app roles dev tokens carry unless others are requested
+	AuthzPolicyPath    string   // YAML file mapping app roles to route permissions
+	AuthIssuersPath    string   // YAML file listing trusted token issuers; Entra ID is derived from the tenant without it
+	// JWKS refresh configuration
+	JWKSRefreshInterval    time.Duration // background refresh period
+	JWKSMinRefreshInterval time.Duration // minimum gap between refreshes forced by unknown key IDs
+	JWKSNegativeCacheTTL   time.Duration // how long unknown key IDs are rejected without refetching
+	APIKeyMaxTTL           time.Duration // longest lifetime an API key may be created with
+	AuditEnabled           bool          // record authenticated requests and admin changes in the audit log
+	UserProvisioning       bool          // create users from their token on their first request, and reject deactivated ones
 	// Environment resolution configuration
 	EnableEnvironmentResolution bool
 	EnvironmentResolutionConfig map[string]bool // API endpoint -> enable/disable
+	EnvironmentConfigPath       string          // YAML file seeding (or, without a database, holding) environments
+	EnvironmentWatch            bool            // apply changes to the YAML file automatically; only without a database
+	EnvironmentWatchDebounce    time.Duration
+	EnvironmentSyncInterval     time.Duration // how often changes made through other replicas are picked up, 0 disables
+	// Cache configuration
+	CacheMode       string // memory, redis or tiered
+	CacheMaxEntries int
+	CacheLocalTTL   time.Duration // bounds staleness for replicas that miss an invalidation message
+	CacheTTL        time.Duration
+	CacheQueryTTL   time.Duration
+	CacheChunkSize  int           // bytes per Redis value, 0 disables chunking
+	CacheSyncPoll   time.Duration // how often provider tables are checked for new syncs, 0 disables
+	// Redis configuration
+	RedisURL                   string   // redis:// or rediss:// URL
+	RedisAddrs                 []string // sentinel or cluster node addresses, overrides the URL host
+	RedisPassword              string
+	RedisDB                    int // -1 keeps the database from RedisURL
+	RedisTLS                   bool
+	RedisTLSInsecureSkipVerify bool
+	RedisSentinelMaster        string
+	RedisCluster               bool
+	RedisPoolSize              int
+	RedisDialTimeout           time.Duration
+	RedisReadTimeout           time.Duration
+	RedisWriteTimeout          time.Duration
+	RedisConnectRetries        int
+	RedisConnectBackoff        time.Duration
+}
+
+// Authentication modes
+const (
+	// AuthModeOIDC accepts tokens from the configured issuers and API keys
+	AuthModeOIDC = "oidc"
+	// AuthModeDev additionally trusts tokens signed with a local key and minted by
+	// POST /dev/token, for local development only
+	AuthModeDev = "dev"
+)
+
+// devEnvironments are the ENVIRONMENT values dev auth may run in
+var devEnvironments = map[string]bool{"development": true, "local": true, "test": true}
+
+// ValidateAuthMode rejects unknown auth modes, and dev auth combined with a
+// production-looking configuration: an ENVIRONMENT other than development, local or
+// test, or running inside a Kubernetes cluster
+func (c *Config) ValidateAuthMode() error {
+	switch c.AuthMode {
+	case AuthModeOIDC:
+		return nil
+	case AuthModeDev:
+	default:
+		return fmt.Errorf("unknown AUTH_MODE %q: expected %s or %s", c.AuthMode, AuthModeOIDC, AuthModeDev)
+	}
+
+	if !devEnvironments[c.Environment] {
+		return fmt.Errorf("AUTH_MODE=dev is not allowed with ENVIRONMENT=%s", c.Environment)
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return fmt.Errorf("AUTH_MODE=dev is not allowed inside a Kubernetes cluster")
+	}
+	return nil
 }
 
 // Load loads configuration from environment variables
@@ -34,15 +109,50 @@ func Load() *Config {
 		AzureClientID:               getEnv("AZURE_CLIENT_ID", ""),
 		AzureClientSecret:           getEnv("AZURE_CLIENT_SECRET", ""),
 		JWTSecret:                   getEnv("JWT_SECRET", "your-secret-key"),
-		BypassAuth:                  env == "development" || env == "local",
+		AuthMode:                    getEnv("AUTH_MODE", AuthModeOIDC),
+		DevAuthKeyPath:              getEnv("DEV_AUTH_KEY_PATH", ""),
 		AzureAuthScope:              getEnv("AZURE_AUTH_SCOPE", "https://graph.microsoft.com/.default"),
 		AzureTokenEndpoint:          getEnv("AZURE_TOKEN_ENDPOINT", ""),
+		DevRoles:                    getEnvListDefault("DEV_ROLES", []string{"Atlas.Admin"}),
+		AuthzPolicyPath:             getEnv("AUTHZ_POLICY_PATH", "config/policy.yaml"),
+		AuthIssuersPath:             getEnv("AUTH_ISSUERS_PATH", "config/issuers.yaml"),
+		JWKSRefreshInterval:         getEnvDuration("JWKS_REFRESH_INTERVAL", time.Hour),
+		JWKSMinRefreshInterval:      getEnvDuration("JWKS_MIN_REFRESH_INTERVAL", time.Minute),
+		JWKSNegativeCacheTTL:        getEnvDuration("JWKS_NEGATIVE_CACHE_TTL", 5*time.Minute),
+		APIKeyMaxTTL:                getEnvDuration("API_KEY_MAX_TTL", 90*24*time.Hour),
+		AuditEnabled:                getEnvBool("AUDIT_ENABLED", true),
+		UserProvisioning:            getEnvBool("USER_PROVISIONING", true),
 		EnableEnvironmentResolution: getEnvBool("ENABLE_ENVIRONMENT_RESOLUTION", true),
 		EnvironmentResolutionConfig: map[string]bool{
 			"/api/v1/vms":          getEnvBool("ENV_RESOLUTION_VMS", true),
 			"/api/v1/environments": getEnvBool("ENV_RESOLUTION_ENVIRONMENTS", false),
 			"/api/v1/users":        getEnvBool("ENV_RESOLUTION_USERS", false),
 		},
+		EnvironmentConfigPath:       getEnv("ENVIRONMENT_CONFIG_PATH", "config/environments.yaml"),
+		EnvironmentWatch:            getEnvBool("ENVIRONMENT_WATCH", false),
+		EnvironmentWatchDebounce:    getEnvDuration("ENVIRONMENT_WATCH_DEBOUNCE", 500*time.Millisecond),
+		EnvironmentSyncInterval:     getEnvDuration("ENVIRONMENT_SYNC_INTERVAL", 10*time.Second),
+		CacheMode:                   getEnv("CACHE_MODE", "tiered"),
+		CacheMaxEntries:             getEnvInt("CACHE_MAX_ENTRIES", 1000),
+		CacheLocalTTL:               getEnvDuration("CACHE_LOCAL_TTL", time.Minute),
+		CacheTTL:                    getEnvDuration("CACHE_TTL", 24*time.Hour),
+		CacheQueryTTL:               getEnvDuration("CACHE_QUERY_TTL", 10*time.Minute),
+		CacheChunkSize:              getEnvInt("CACHE_CHUNK_SIZE", 1<<20),
+		CacheSyncPoll:               getEnvDuration("CACHE_SYNC_POLL", time.Minute),
+		RedisURL:                    getEnv("REDIS_URL", "redis://localhost:6379/0"),
+		RedisAddrs:                  getEnvList("REDIS_ADDRS"),
+		RedisPassword:               getEnv("REDIS_PASSWORD", ""),
+		RedisDB:                     getEnvInt("REDIS_DB", -1),
+		RedisTLS:                    getEnvBool("REDIS_TLS", false),
+		RedisTLSInsecureSkipVerify:  getEnvBool("REDIS_TLS_INSECURE_SKIP_VERIFY", false),
+		RedisSentinelMaster:         getEnv("REDIS_SENTINEL_MASTER", ""),
+		RedisCluster:                getEnvBool("REDIS_CLUSTER", false),
+		RedisPoolSize:               getEnvInt("REDIS_POOL_SIZE", 0),
+		RedisDialTimeout:            getEnvDuration("REDIS_DIAL_TIMEOUT", 5*time.Second),
+		RedisReadTimeout:            getEnvDuration("REDIS_READ_TIMEOUT", 3*time.Second),
+		RedisWriteTimeout:           getEnvDuration("REDIS_WRITE_TIMEOUT", 3*time.Second),
+		RedisConnectRetries:         getEnvInt("REDIS_CONNECT_RETRIES", 3),
+		RedisConnectBackoff:         getEnvDuration("REDIS_CONNECT_BACKOFF", time.Second),
 	}
 }
 
@@ -65,4 +175,43 @@ func getEnvBool(key string, defaultValue bool) bool {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvInt gets an environment variable and returns an integer
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if i, err := strconv.Atoi(value); err == nil {
+			return i
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDuration gets an environment variable and returns a duration (e.g. "30s", "5m")
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList gets a comma-separated environment variable as a list, skipping empty items
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvListDefault gets a comma-separated environment variable as a list, or the default when unset
+func getEnvListDefault(key string, defaultValue []string) []string {
+	if items := getEnvList(key); len(items) > 0 {
+		return items
+	}
+	return defaultValue
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAuthMode(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	assert.NoError(t, (&Config{AuthMode: AuthModeOIDC, Environment: "production"}).ValidateAuthMode())
+	assert.NoError(t, (&Config{AuthMode: AuthModeDev, Environment: "development"}).ValidateAuthMode())
+	assert.Error(t, (&Config{AuthMode: "bypass", Environment: "development"}).ValidateAuthMode())
+	assert.Error(t, (&Config{AuthMode: AuthModeDev, Environment: "production"}).ValidateAuthMode())
+	assert.Error(t, (&Config{AuthMode: AuthModeDev, Environment: "staging"}).ValidateAuthMode())
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	assert.Error(t, (&Config{AuthMode: AuthModeDev, Environment: "development"}).ValidateAuthMode())
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"math"
+	"sort"
+
+	"golang-service/internal/models"
+)
+
+// Coverage reports how well the environments cover a VM inventory
+type Coverage struct {
+	TotalVMs    int     `json:"totalVms"`
+	AssignedVMs int     `json:"assignedVms"`
+	Percentage  float64 `json:"percentage"`
+	// Clouds breaks the coverage down per cloud type
+	Clouds []CloudCoverage `json:"clouds"`
+	// Unassigned groups the VMs matching no environment by account and location
+	Unassigned []UnassignedGroup `json:"unassigned"`
+	// Tied lists the VMs whose best environments tie on priority and score
+	Tied []TiedVM `json:"tied"`
+	// UnusedEnvironments lists the environments matching none of the VMs
+	UnusedEnvironments []UnusedEnvironment `json:"unusedEnvironments"`
+}
+
+// CloudCoverage is the coverage of one cloud type
+type CloudCoverage struct {
+	CloudType  string  `json:"cloudType"`
+	TotalVMs   int     `json:"totalVms"`
+	Assigned   int     `json:"assigned"`
+	Unassigned int     `json:"unassigned"`
+	Tied       int     `json:"tied"`
+	Percentage float64 `json:"percentage"`
+}
+
+// UnassignedGroup counts the VMs of one account and location that match no environment
+type UnassignedGroup struct {
+	CloudType string   `json:"cloudType"`
+	Account   string   `json:"account"`
+	Location  string   `json:"location"`
+	VMCount   int      `json:"vmCount"`
+	SampleVMs []string `json:"sampleVms"`
+}
+
+// TiedVM is a VM whose environment was decided by ID order between equally ranked matches
+type TiedVM struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	CloudType    string   `json:"cloudType"`
+	Environments []string `json:"environments"`
+	ResolvedTo   string   `json:"resolvedTo"`
+}
+
+// UnusedEnvironment is an environment no VM matches
+type UnusedEnvironment struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ComputeCoverage evaluates every VM against the environments and reports unassigned VMs,
+// tied VMs and environments without any matching VM
+func ComputeCoverage(environments []models.Environment, vms []models.VM) Coverage {
+	coverage := Coverage{
+		TotalVMs:           len(vms),
+		Clouds:             []CloudCoverage{},
+		Unassigned:         []UnassignedGroup{},
+		Tied:               []TiedVM{},
+		UnusedEnvironments: []UnusedEnvironment{},
+	}
+
+	effective := InheritEnvironments(environments)
+	clouds := make(map[string]*CloudCoverage)
+	groups := make(map[[3]string]*UnassignedGroup)
+	matchedEnvironments := make(map[string]bool)
+
+	for _, vm := range vms {
+		cloud, ok := clouds[vm.CloudType]
+		if !ok {
+			cloud = &CloudCoverage{CloudType: vm.CloudType}
+			clouds[vm.CloudType] = cloud
+		}
+		cloud.TotalVMs++
+
+		candidates := EvaluateEnvironments(effective, vm)
+		for _, candidate := range candidates {
+			if !candidate.Matched {
+				break
+			}
+			matchedEnvironments[candidate.Environment.ID] = true
+		}
+
+		if len(candidates) == 0 || !candidates[0].Matched {
+			cloud.Unassigned++
+			key := [3]string{vm.CloudType, vm.CloudAccountID, vm.Location}
+			group, ok := groups[key]
+			if !ok {
+				group = &UnassignedGroup{CloudType: vm.CloudType, Account: vm.CloudAccountID, Location: vm.Location}
+				groups[key] = group
+			}
+			group.VMCount++
+			if len(group.SampleVMs) < maxSampleVMs {
+				group.SampleVMs = append(group.SampleVMs, vm.ID)
+			}
+			continue
+		}
+
+		coverage.AssignedVMs++
+		cloud.Assigned++
+
+		if tied := TiedCandidates(candidates); len(tied) > 1 {
+			cloud.Tied++
+			ids := make([]string, len(tied))
+			for i, candidate := range tied {
+				ids[i] = candidate.Environment.ID
+			}
+			coverage.Tied = append(coverage.Tied, TiedVM{
+				ID:           vm.ID,
+				Name:         vm.Name,
+				CloudType:    vm.CloudType,
+				Environments: ids,
+				ResolvedTo:   ids[0],
+			})
+		}
+	}
+
+	coverage.Percentage = percentage(coverage.AssignedVMs, coverage.TotalVMs)
+	for _, cloud := range clouds {
+		cloud.Percentage = percentage(cloud.Assigned, cloud.TotalVMs)
+		coverage.Clouds = append(coverage.Clouds, *cloud)
+	}
+	sort.Slice(coverage.Clouds, func(i, j int) bool {
+		return coverage.Clouds[i].CloudType < coverage.Clouds[j].CloudType
+	})
+
+	for _, group := range groups {
+		coverage.Unassigned = append(coverage.Unassigned, *group)
+	}
+	sort.Slice(coverage.Unassigned, func(i, j int) bool {
+		a, b := coverage.Unassigned[i], coverage.Unassigned[j]
+		if a.VMCount != b.VMCount {
+			return a.VMCount > b.VMCount
+		}
+		if a.CloudType != b.CloudType {
+			return a.CloudType < b.CloudType
+		}
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		return a.Location < b.Location
+	})
+
+	sort.Slice(coverage.Tied, func(i, j int) bool {
+		return coverage.Tied[i].ID < coverage.Tied[j].ID
+	})
+
+	for _, env := range environments {
+		if !matchedEnvironments[env.ID] {
+			coverage.UnusedEnvironments = append(coverage.UnusedEnvironments, UnusedEnvironment{ID: env.ID, Name: env.Name})
+		}
+	}
+	sort.Slice(coverage.UnusedEnvironments, func(i, j int) bool {
+		return coverage.UnusedEnvironments[i].ID < coverage.UnusedEnvironments[j].ID
+	})
+
+	return coverage
+}
+
+// percentage returns part as a percentage of total, rounded to one decimal
+func percentage(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*1000/float64(total)) / 10
+}
+
+// ComputeCoverage reports how the current configuration covers the given VMs
+func (s *EnvironmentService) ComputeCoverage(vms []models.VM) (Coverage, error) {
+	environments, err := s.GetEnvironments()
+	if err != nil {
+		return Coverage{}, err
+	}
+	return ComputeCoverage(environments, vms), nil
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeCoverage(t *testing.T) {
+	environments := []models.Environment{
+		{ID: "prod", Name: "Production", Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "111", Region: "us-east-1"}},
+		{ID: "prod-copy", Name: "Production copy", Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "111", Region: "us-east-1"}},
+		{ID: "gcp", Name: "GCP", Criteria: models.EnvironmentCriteria{CloudType: "gcp", Project: "p", Zone: "us-central1"}},
+		{ID: "azure", Name: "Azure", Criteria: models.EnvironmentCriteria{CloudType: "azure", Subscription: "sub", Location: "eastus"}},
+	}
+	vms := []models.VM{
+		{ID: "aws-1", Name: "web", CloudType: "aws", CloudAccountID: "111", Location: "us-east-1"},
+		{ID: "aws-2", CloudType: "aws", CloudAccountID: "222", Location: "eu-west-1"},
+		{ID: "aws-3", CloudType: "aws", CloudAccountID: "222", Location: "eu-west-1"},
+		{ID: "aws-4", CloudType: "aws", CloudAccountID: "111", Location: "us-west-2"},
+		{ID: "gcp-1", CloudType: "gcp", CloudAccountID: "p", Location: "us-central1-a"},
+	}
+
+	coverage := ComputeCoverage(environments, vms)
+	assert.Equal(t, 5, coverage.TotalVMs)
+	assert.Equal(t, 2, coverage.AssignedVMs)
+	assert.Equal(t, 40.0, coverage.Percentage)
+
+	require.Len(t, coverage.Clouds, 2)
+	assert.Equal(t, CloudCoverage{CloudType: "aws", TotalVMs: 4, Assigned: 1, Unassigned: 3, Tied: 1, Percentage: 25}, coverage.Clouds[0])
+	assert.Equal(t, CloudCoverage{CloudType: "gcp", TotalVMs: 1, Assigned: 1, Percentage: 100}, coverage.Clouds[1])
+
+	require.Len(t, coverage.Unassigned, 2)
+	assert.Equal(t, UnassignedGroup{CloudType: "aws", Account: "222", Location: "eu-west-1", VMCount: 2, SampleVMs: []string{"aws-2", "aws-3"}}, coverage.Unassigned[0])
+	assert.Equal(t, "us-west-2", coverage.Unassigned[1].Location)
+
+	require.Len(t, coverage.Tied, 1)
+	assert.Equal(t, TiedVM{ID: "aws-1", Name: "web", CloudType: "aws", Environments: []string{"prod", "prod-copy"}, ResolvedTo: "prod"}, coverage.Tied[0])
+
+	assert.Equal(t, []UnusedEnvironment{{ID: "azure", Name: "Azure"}}, coverage.UnusedEnvironments)
+}
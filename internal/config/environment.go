@@ -1,60 +1,206 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
 	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"golang-service/internal/models"
 )
 
-// EnvironmentService manages environment configurations
+// Environment store errors
+var (
+	ErrEnvironmentNotFound = errors.New("environment not found")
+	ErrEnvironmentExists   = errors.New("environment already exists")
+	ErrInvalidEnvironment  = errors.New("invalid environment configuration")
+	ErrReadOnly            = errors.New("environment configuration is read-only without a database")
+	ErrSeedOnly            = errors.New("with a database the environments file only seeds an empty table; import changes through the API")
+)
+
+// EnvironmentService manages environment configurations.
+// Without a database the YAML file is the only source and the service is read-only;
+// with a database the environments table is the source of truth and the YAML file
+// only seeds it when empty.
 type EnvironmentService struct {
 	configPath string
+	db         *gorm.DB
 	config     *models.EnvironmentConfig
+	effective  []models.Environment // config.Environments with inheritance applied
 	mu         sync.RWMutex
+	writeMu    sync.Mutex // serializes writes so validation sees the latest snapshot
 	lastLoad   time.Time
+	listeners  []func()
+	version    int                               // active configuration version
+	versions   []models.EnvironmentConfigVersion // version history without a database
 }
 
-// NewEnvironmentService creates a new environment service
+// NewEnvironmentService creates a new environment service backed by a YAML file
 func NewEnvironmentService(configPath string) *EnvironmentService {
 	return &EnvironmentService{
 		configPath: configPath,
 	}
 }
 
-// LoadConfig loads the environment configuration from the YAML file
+// NewDatabaseEnvironmentService creates a new environment service backed by the environments table.
+// The YAML file at bootstrapPath is imported when the table is empty.
+func NewDatabaseEnvironmentService(db *gorm.DB, bootstrapPath string) *EnvironmentService {
+	return &EnvironmentService{
+		configPath: bootstrapPath,
+		db:         db,
+	}
+}
+
+// OnReload registers a callback invoked after every successful configuration load
+func (s *EnvironmentService) OnReload(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// LoadConfig loads the environment configuration from the YAML file and notifies reload listeners
 func (s *EnvironmentService) LoadConfig() error {
+	return s.reload(SystemAuthor, SourceLoad)
+}
+
+// reload loads the configuration, recording it as a new version by author when it changed,
+// and notifies reload listeners
+func (s *EnvironmentService) reload(author, source string) error {
+	if err := s.loadConfig(author, source); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	listeners := s.listeners
+	s.mu.RUnlock()
+
+	for _, fn := range listeners {
+		fn()
+	}
+	return nil
+}
+
+// loadConfig reads the configuration from its source and swaps it in
+func (s *EnvironmentService) loadConfig(author, source string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	var config *models.EnvironmentConfig
+	var err error
+	if s.db != nil {
+		config, err = s.loadFromDatabase()
+	} else {
+		config, err = s.loadFromFile()
+	}
+	if err != nil {
+		return err
+	}
+	// Validate before swapping so an invalid source never replaces a working configuration
+	if err := ValidateEnvironments(config.Environments); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEnvironment, err)
+	}
+
+	// Resolve the hierarchy once per load; the configured environments keep their own
+	// settings but expose their ancestry
+	s.effective = InheritEnvironments(config.Environments)
+	for i := range config.Environments {
+		config.Environments[i].Ancestors = s.effective[i].Ancestors
+	}
+
+	s.config = config
+	s.lastLoad = time.Now()
+	s.recordVersion(config.Environments, author, source)
+
+	return nil
+}
+
+// loadFromFile reads and parses the YAML file. Timestamps reflect the file's modification time.
+func (s *EnvironmentService) loadFromFile() (*models.EnvironmentConfig, error) {
 	// Read the configuration file
 	data, err := os.ReadFile(s.configPath)
 	if err != nil {
-		return fmt.Errorf("failed to read environment config file: %w", err)
+		return nil, fmt.Errorf("failed to read environment config file: %w", err)
 	}
 
-	// Parse the YAML configuration
-	var config models.EnvironmentConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to parse environment config YAML: %w", err)
+	config, err := ParseEnvironmentConfig(data)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add timestamps to environments
-	now := time.Now()
-	for i := range config.Environments {
-		config.Environments[i].CreatedAt = now
-		config.Environments[i].UpdatedAt = now
+	if info, err := os.Stat(s.configPath); err == nil {
+		for i := range config.Environments {
+			config.Environments[i].CreatedAt = info.ModTime()
+			config.Environments[i].UpdatedAt = info.ModTime()
+		}
 	}
 
-	s.config = &config
-	s.lastLoad = now
+	return config, nil
+}
 
-	return nil
+// loadFromDatabase reads all environments from the database, seeding it from the YAML file when empty
+func (s *EnvironmentService) loadFromDatabase() (*models.EnvironmentConfig, error) {
+	var environments []models.Environment
+	if err := s.db.Order("id").Find(&environments).Error; err != nil {
+		return nil, fmt.Errorf("failed to load environments from database: %w", err)
+	}
+
+	if len(environments) == 0 && s.configPath != "" {
+		seeded, err := s.seedDatabase()
+		if err != nil {
+			return nil, err
+		}
+		// Replicas starting together may seed at the same time; read back what was stored
+		if seeded {
+			if err := s.db.Order("id").Find(&environments).Error; err != nil {
+				return nil, fmt.Errorf("failed to load environments from database: %w", err)
+			}
+		}
+	}
+
+	return &models.EnvironmentConfig{Environments: environments}, nil
+}
+
+// seedDatabase imports the YAML file into an empty environments table, keeping any
+// environment another replica seeded first. It reports whether the file had any.
+func (s *EnvironmentService) seedDatabase() (bool, error) {
+	data, err := os.ReadFile(s.configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read environment bootstrap file: %w", err)
+	}
+
+	config, err := ParseEnvironmentConfig(data)
+	if err != nil {
+		return false, err
+	}
+	if err := ValidateEnvironments(config.Environments); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidEnvironment, err)
+	}
+	if len(config.Environments) == 0 {
+		return false, nil
+	}
+
+	err = s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&config.Environments).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to seed environments: %w", err)
+	}
+	return true, nil
+}
+
+// ParseEnvironmentConfig parses environments from their YAML representation
+func ParseEnvironmentConfig(data []byte) (*models.EnvironmentConfig, error) {
+	var config models.EnvironmentConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse environment config YAML: %w", err)
+	}
+	return &config, nil
 }
 
 // GetEnvironments returns all environments
@@ -149,68 +295,16 @@ func (s *EnvironmentService) GetEnvironmentsByTag(tags []string) ([]models.Envir
 	return matching, nil
 }
 
-// ResolveEnvironmentForResource resolves which environment a resource belongs to
+// ResolveEnvironmentForResource resolves which environment a resource belongs to from its
+// account, region and VPC alone, using the same evaluation as ResolveEnvironmentForVM
 func (s *EnvironmentService) ResolveEnvironmentForResource(accountID, region, vpc string) (*models.Environment, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	if s.config == nil {
-		return nil, fmt.Errorf("environment configuration not loaded")
-	}
-
-	// Find the best matching environment
-	var bestMatch *models.Environment
-	var bestScore int
-
-	for _, env := range s.config.Environments {
-		score := 0
-		
-		// Check account match (highest priority)
-		if env.Criteria.Account == accountID {
-			score += 10
-		} else if env.Criteria.Account != "" {
-			continue // Skip if account doesn't match and is required
-		}
-		
-		// Check region match
-		if env.Criteria.Region == region {
-			score += 5
-		} else if env.Criteria.Region != "" {
-			// For GCP, check if zone starts with the region (e.g., us-central1-a matches us-central1)
-			if strings.HasPrefix(region, env.Criteria.Region) {
-				score += 5
-			} else {
-				continue // Skip if region doesn't match and is required
-			}
-		}
-		
-		// Check VPC match (if specified)
-		if env.Criteria.VPC != "" {
-			if env.Criteria.VPC == vpc {
-				score += 3
-			} else if vpc != "" {
-				// Only skip if VPC is specified in environment AND VM has a VPC that doesn't match
-				// This allows Azure/GCP environments (with empty VPC) to match VMs without VPC info
-				continue
-			}
-			// If environment has VPC but VM doesn't, still allow match (for Azure/GCP)
-		}
-		
-		// Update best match if this environment has a higher score
-		if score > bestScore {
-			bestScore = score
-			bestMatch = &env
-		}
-	}
-
-	if bestMatch == nil {
-		return nil, fmt.Errorf("no environment found matching criteria: account=%s, region=%s, vpc=%s", accountID, region, vpc)
-	}
-
-	return bestMatch, nil
+	return s.ResolveEnvironmentForVM(models.VM{CloudAccountID: accountID, Location: region, VpcID: vpc})
 }
 
-// ResolveEnvironmentForVM resolves environment for a VM based on its cloud-specific properties
+// ResolveEnvironmentForVM resolves environment for a VM based on its cloud-specific properties.
+// Of the environments whose criteria and rules all match, the one with the highest priority wins;
+// equal priorities go to the environment matching the most conditions, then to the deepest in the
+// hierarchy, then to the lowest ID. The returned environment has inheritance applied.
 func (s *EnvironmentService) ResolveEnvironmentForVM(vm models.VM) (*models.Environment, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -219,87 +313,39 @@ func (s *EnvironmentService) ResolveEnvironmentForVM(vm models.VM) (*models.Envi
 		return nil, fmt.Errorf("environment configuration not loaded")
 	}
 
-	// Find the best matching environment based on cloud type
-	var bestMatch *models.Environment
-	var bestScore int
-
-	for _, env := range s.config.Environments {
-		// First check if cloud type matches
-		if env.Criteria.CloudType != "" && env.Criteria.CloudType != vm.CloudType {
-			continue // Skip if cloud type doesn't match
-		}
-
-		score := 0
-		
-		switch vm.CloudType {
-		case "aws":
-			// AWS criteria: account, region, vpc
-			if env.Criteria.Account == vm.CloudAccountID {
-				score += 10
-			} else if env.Criteria.Account != "" {
-				continue // Skip if account doesn't match
-			}
-			
-			if env.Criteria.Region == vm.Location {
-				score += 5
-			} else if env.Criteria.Region != "" {
-				continue // Skip if region doesn't match
-			}
-			
-			// VPC matching would need to be extracted from VM data
-			// For now, we'll skip VPC matching as it requires additional data extraction
-			
-		case "azure":
-			// Azure criteria: subscription, location
-			if env.Criteria.Subscription == vm.CloudAccountID {
-				score += 10
-			} else if env.Criteria.Subscription != "" {
-				continue // Skip if subscription doesn't match
-			}
-			
-			if env.Criteria.Location == vm.Location {
-				score += 5
-			} else if env.Criteria.Location != "" {
-				continue // Skip if location doesn't match
-			}
-			
-		case "gcp":
-			// GCP criteria: project, zone
-			if env.Criteria.Project == vm.CloudAccountID {
-				score += 10
-			} else if env.Criteria.Project != "" {
-				continue // Skip if project doesn't match
-			}
-			
-			if env.Criteria.Zone != "" {
-				// Check if zone starts with the environment zone (e.g., us-central1-a matches us-central1)
-				if strings.HasPrefix(vm.Location, env.Criteria.Zone) {
-					score += 5
-				} else {
-					continue // Skip if zone doesn't match
-				}
-			}
-		}
-		
-		// Update best match if this environment has a higher score
-		if score > bestScore {
-			bestScore = score
-			bestMatch = &env
-		}
-	}
-
-	if bestMatch == nil {
+	candidates := EvaluateEnvironments(s.effective, vm)
+	if len(candidates) == 0 || !candidates[0].Matched {
 		return nil, fmt.Errorf("no environment found matching criteria for VM: cloudType=%s, account=%s, location=%s", vm.CloudType, vm.CloudAccountID, vm.Location)
 	}
 
-	return bestMatch, nil
+	environment := candidates[0].Environment
+	environment.ConfigVersion = s.version
+	return &environment, nil
 }
 
+// ExplainResolution explains how a VM resolves against the active configuration
+func (s *EnvironmentService) ExplainResolution(vm models.VM) (Resolution, error) {
+	environments, err := s.GetEnvironments()
+	if err != nil {
+		return Resolution{}, err
+	}
+	return ExplainResolution(environments, vm), nil
+}
 
+// ReloadConfig reloads the configuration from its source on behalf of author
+func (s *EnvironmentService) ReloadConfig(author string) error {
+	return s.reload(author, SourceReload)
+}
 
-// ReloadConfig reloads the configuration from the file
-func (s *EnvironmentService) ReloadConfig() error {
-	return s.LoadConfig()
+// ApplyConfigFile reloads the YAML file when it is the configuration's source; an invalid
+// file leaves the current configuration in place. With a database the file only seeds
+// an empty table, so ErrSeedOnly is returned: applying it on every replica would race,
+// and replacing the stored environments would discard those created through the API.
+func (s *EnvironmentService) ApplyConfigFile() error {
+	if s.db != nil {
+		return ErrSeedOnly
+	}
+	return s.reload(SystemAuthor, SourceFile)
 }
 
 // GetLastLoadTime returns when the configuration was last loaded
@@ -323,28 +369,106 @@ func (s *EnvironmentService) ValidateConfig() error {
 		return fmt.Errorf("environment configuration not loaded")
 	}
 
-	// Check for duplicate IDs
+	return ValidateEnvironments(s.config.Environments)
+}
+
+// requiredCriteria lists the criteria each cloud type must specify, by YAML key
+var requiredCriteria = map[string][]string{
+	"":      {"account", "region"},
+	"aws":   {"account", "region"},
+	"azure": {"subscription", "location"},
+	"gcp":   {"project", "zone"},
+}
+
+// ValidateEnvironments checks a set of environments for duplicate IDs, missing required
+// fields and invalid rules, returning the first problem found
+func ValidateEnvironments(environments []models.Environment) error {
+	if issues := structuralIssues(environments); len(issues) > 0 {
+		return errors.New(issues[0].Message)
+	}
+	return nil
+}
+
+// structuralIssues reports every problem that prevents a set of environments from being loaded
+func structuralIssues(environments []models.Environment) []ValidationIssue {
+	var issues []ValidationIssue
+	add := func(env, field, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{
+			Severity:    SeverityError,
+			Environment: env,
+			Field:       field,
+			Message:     fmt.Sprintf(format, args...),
+		})
+	}
+
 	ids := make(map[string]bool)
-	for _, env := range s.config.Environments {
+	for _, env := range environments {
+		// Validate required fields
+		if env.ID == "" {
+			add("", "id", "environment missing required field: id")
+			continue
+		}
 		if ids[env.ID] {
-			return fmt.Errorf("duplicate environment ID: %s", env.ID)
+			add(env.ID, "id", "duplicate environment ID: %s", env.ID)
 		}
 		ids[env.ID] = true
 
-		// Validate required fields
-		if env.ID == "" {
-			return fmt.Errorf("environment missing required field: id")
-		}
 		if env.Name == "" {
-			return fmt.Errorf("environment '%s' missing required field: name", env.ID)
+			add(env.ID, "name", "environment '%s' missing required field: name", env.ID)
 		}
-		if env.Criteria.Account == "" {
-			return fmt.Errorf("environment '%s' missing required field: criteria.account", env.ID)
+
+		if _, ok := requiredCriteria[env.Criteria.CloudType]; !ok {
+			add(env.ID, "criteria.cloud_type", "environment '%s' has unsupported criteria.cloud_type: %s", env.ID, env.Criteria.CloudType)
 		}
-		if env.Criteria.Region == "" {
-			return fmt.Errorf("environment '%s' missing required field: criteria.region", env.ID)
+
+		for i, rule := range env.Criteria.Rules {
+			if err := validateRule(rule); err != nil {
+				add(env.ID, fmt.Sprintf("criteria.rules[%d]", i), "environment '%s' criteria.rules[%d]: %v", env.ID, i, err)
+			}
 		}
 	}
 
-	return nil
-} 
\ No newline at end of file
+	hierarchy := hierarchyIssues(environments)
+	issues = append(issues, hierarchy...)
+	if len(hierarchy) > 0 {
+		return issues
+	}
+
+	// Required criteria may be inherited from a parent
+	for _, env := range InheritEnvironments(environments) {
+		required, ok := requiredCriteria[env.Criteria.CloudType]
+		// Environments defined by rules need no provider criteria
+		if !ok || env.ID == "" || len(env.Criteria.Rules) > 0 {
+			continue
+		}
+		for _, key := range required {
+			if criteriaValue(env.Criteria, key) == "" {
+				add(env.ID, "criteria."+key, "environment '%s' missing required field: criteria.%s", env.ID, key)
+			}
+		}
+	}
+
+	return issues
+}
+
+// criteriaValue returns a criteria field by its YAML key
+func criteriaValue(criteria models.EnvironmentCriteria, key string) string {
+	switch key {
+	case "account":
+		return criteria.Account
+	case "region":
+		return criteria.Region
+	case "vpc":
+		return criteria.VPC
+	case "subscription":
+		return criteria.Subscription
+	case "location":
+		return criteria.Location
+	case "project":
+		return criteria.Project
+	case "zone":
+		return criteria.Zone
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,261 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"golang-service/internal/models"
+)
+
+// Import modes for ImportEnvironments
+const (
+	// ImportModeMerge creates or replaces the imported environments and keeps all others
+	ImportModeMerge = "merge"
+	// ImportModeReplace makes the imported environments the complete configuration
+	ImportModeReplace = "replace"
+)
+
+// CreateEnvironment validates and stores a new environment on behalf of author
+func (s *EnvironmentService) CreateEnvironment(env models.Environment, author string) (*models.Environment, error) {
+	if s.db == nil {
+		return nil, ErrReadOnly
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	current, err := s.GetEnvironments()
+	if err != nil {
+		return nil, err
+	}
+	if findEnvironment(current, env.ID) != nil {
+		return nil, fmt.Errorf("%w: %s", ErrEnvironmentExists, env.ID)
+	}
+	if err := ValidateEnvironments(append(current, env)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnvironment, err)
+	}
+
+	if err := s.db.Create(&env).Error; err != nil {
+		return nil, fmt.Errorf("failed to create environment: %w", err)
+	}
+
+	return s.reloadAndGet(env.ID, author, SourceCreate)
+}
+
+// UpdateEnvironment validates and replaces an existing environment on behalf of author,
+// keeping its creation time
+func (s *EnvironmentService) UpdateEnvironment(id string, env models.Environment, author string) (*models.Environment, error) {
+	if s.db == nil {
+		return nil, ErrReadOnly
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	current, err := s.GetEnvironments()
+	if err != nil {
+		return nil, err
+	}
+	existing := findEnvironment(current, id)
+	if existing == nil {
+		return nil, fmt.Errorf("%w: %s", ErrEnvironmentNotFound, id)
+	}
+
+	env.ID = id
+	env.CreatedAt = existing.CreatedAt
+	*existing = env
+	if err := ValidateEnvironments(current); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEnvironment, err)
+	}
+
+	if err := s.db.Save(&env).Error; err != nil {
+		return nil, fmt.Errorf("failed to update environment: %w", err)
+	}
+
+	return s.reloadAndGet(id, author, SourceUpdate)
+}
+
+// DeleteEnvironment removes an environment on behalf of author
+func (s *EnvironmentService) DeleteEnvironment(id, author string) error {
+	if s.db == nil {
+		return ErrReadOnly
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	// Environments that inherit from this one would be left with an unknown parent
+	current, err := s.GetEnvironments()
+	if err != nil {
+		return err
+	}
+	if children := Children(current, id); len(children) > 0 {
+		return fmt.Errorf("%w: environment '%s' has child environments (%s)", ErrInvalidEnvironment, id, children[0].ID)
+	}
+
+	result := s.db.Delete(&models.Environment{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete environment: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrEnvironmentNotFound, id)
+	}
+
+	return s.reload(author, SourceDelete)
+}
+
+// ImportEnvironments stores environments from their YAML representation in a single transaction
+// on behalf of author. It returns the number of environments imported.
+func (s *EnvironmentService) ImportEnvironments(data []byte, mode, author string) (int, error) {
+	return s.importEnvironments(data, mode, author, SourceImport)
+}
+
+// importEnvironments imports environments, recording source with the resulting version
+func (s *EnvironmentService) importEnvironments(data []byte, mode, author, source string) (int, error) {
+	if s.db == nil {
+		return 0, ErrReadOnly
+	}
+	if mode != ImportModeMerge && mode != ImportModeReplace {
+		return 0, fmt.Errorf("unsupported import mode: %s", mode)
+	}
+
+	imported, err := ParseEnvironmentConfig(data)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidEnvironment, err)
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	current, err := s.GetEnvironments()
+	if err != nil {
+		return 0, err
+	}
+
+	// Build the resulting configuration and validate it as a whole
+	result := imported.Environments
+	if mode == ImportModeMerge {
+		result = mergeEnvironments(current, imported.Environments)
+	}
+	if err := ValidateEnvironments(result); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidEnvironment, err)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if mode == ImportModeReplace {
+			if err := tx.Where("1 = 1").Delete(&models.Environment{}).Error; err != nil {
+				return err
+			}
+		}
+		for _, env := range imported.Environments {
+			if existing := findEnvironment(current, env.ID); existing != nil {
+				env.CreatedAt = existing.CreatedAt
+			}
+			if err := tx.Save(&env).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to import environments: %w", err)
+	}
+
+	return len(imported.Environments), s.reload(author, source)
+}
+
+// ExportEnvironments returns the current environments within scope in their YAML representation
+func (s *EnvironmentService) ExportEnvironments(scope *Scope) ([]byte, error) {
+	environments, err := s.GetEnvironments()
+	if err != nil {
+		return nil, err
+	}
+	environments = scope.FilterEnvironments(environments)
+
+	data, err := marshalEnvironments(environments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export environments: %w", err)
+	}
+	return data, nil
+}
+
+// WatchDatabase keeps the configuration in step with changes made through other replicas
+// sharing the database, until ctx is done. Every interval it compares the latest recorded
+// configuration version with the active one and reloads when they differ.
+func (s *EnvironmentService) WatchDatabase(ctx context.Context, interval time.Duration) {
+	if s.db == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := s.syncFromDatabase(); err != nil {
+				log.Printf("Failed to pick up environment changes from the database: %v", err)
+			}
+		}
+	}()
+}
+
+// syncFromDatabase reloads the configuration when another replica recorded a version
+// this replica has not loaded
+func (s *EnvironmentService) syncFromDatabase() error {
+	latest, err := s.latestVersion()
+	if err != nil {
+		return err
+	}
+	if latest == nil || latest.Version == s.ConfigVersion() {
+		return nil
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.reload(SystemAuthor, SourceSync)
+}
+
+// IsWritable reports whether environments can be changed through the service
+func (s *EnvironmentService) IsWritable() bool {
+	return s.db != nil
+}
+
+// reloadAndGet reloads the configuration and returns the environment with the given ID
+func (s *EnvironmentService) reloadAndGet(id, author, source string) (*models.Environment, error) {
+	if err := s.reload(author, source); err != nil {
+		return nil, err
+	}
+	return s.GetEnvironmentByID(id)
+}
+
+// findEnvironment returns a pointer to the environment with the given ID, or nil
+func findEnvironment(environments []models.Environment, id string) *models.Environment {
+	for i := range environments {
+		if environments[i].ID == id {
+			return &environments[i]
+		}
+	}
+	return nil
+}
+
+// mergeEnvironments overlays the updates onto the current environments by ID
+func mergeEnvironments(current, updates []models.Environment) []models.Environment {
+	merged := make([]models.Environment, len(current))
+	copy(merged, current)
+	for _, env := range updates {
+		if existing := findEnvironment(merged, env.ID); existing != nil {
+			*existing = env
+		} else {
+			merged = append(merged, env)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,265 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang-service/internal/database/dbtest"
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testEnvironmentsYAML = `environments:
+  - id: "prod"
+    name: "AWS Production"
+    criteria:
+      cloud_type: "aws"
+      account: "123456789012"
+      region: "us-east-1"
+    tags: ["production"]
+    metadata:
+      owner: "platform-team"
+  - id: "azure-dev"
+    name: "Azure Development"
+    criteria:
+      cloud_type: "azure"
+      subscription: "subscription-1"
+      location: "eastus"
+`
+
+func writeEnvironmentsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "environments.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func newDatabaseEnvironmentService(t *testing.T, bootstrap string) *EnvironmentService {
+	t.Helper()
+	db := dbtest.Open(t, &models.Environment{}, &models.EnvironmentConfigVersion{})
+	service := NewDatabaseEnvironmentService(db, writeEnvironmentsFile(t, bootstrap))
+	require.NoError(t, service.LoadConfig())
+	return service
+}
+
+func TestEnvironmentServiceFile(t *testing.T) {
+	path := writeEnvironmentsFile(t, testEnvironmentsYAML)
+	service := NewEnvironmentService(path)
+	require.NoError(t, service.LoadConfig())
+	assert.NoError(t, service.ValidateConfig())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	env, err := service.GetEnvironmentByID("prod")
+	require.NoError(t, err)
+	assert.Equal(t, info.ModTime(), env.CreatedAt)
+	assert.Equal(t, info.ModTime(), env.UpdatedAt)
+
+	assert.False(t, service.IsWritable())
+	_, err = service.CreateEnvironment(models.Environment{ID: "new"}, "tester")
+	assert.ErrorIs(t, err, ErrReadOnly)
+}
+
+func TestEnvironmentServiceDatabase(t *testing.T) {
+	newEnv := models.Environment{
+		ID:   "gcp-prod",
+		Name: "GCP Production",
+		Criteria: models.EnvironmentCriteria{
+			CloudType: "gcp",
+			Project:   "project-1",
+			Zone:      "us-central1",
+		},
+	}
+
+	t.Run("Seeds an empty table from the bootstrap file", func(t *testing.T) {
+		service := newDatabaseEnvironmentService(t, testEnvironmentsYAML)
+
+		environments, err := service.GetEnvironments()
+		require.NoError(t, err)
+		require.Len(t, environments, 2)
+		assert.Equal(t, "azure-dev", environments[0].ID)
+		assert.Equal(t, "platform-team", environments[1].Metadata["owner"])
+		assert.False(t, environments[1].CreatedAt.IsZero())
+	})
+
+	t.Run("Replicas seeding together keep one copy", func(t *testing.T) {
+		first := newDatabaseEnvironmentService(t, testEnvironmentsYAML)
+
+		// A second replica that found the table empty seeds it again
+		second := NewDatabaseEnvironmentService(first.db, first.configPath)
+		seeded, err := second.seedDatabase()
+		require.NoError(t, err)
+		assert.True(t, seeded)
+
+		require.NoError(t, second.LoadConfig())
+		environments, err := second.GetEnvironments()
+		require.NoError(t, err)
+		assert.Len(t, environments, 2)
+	})
+
+	t.Run("Create, update and delete", func(t *testing.T) {
+		service := newDatabaseEnvironmentService(t, testEnvironmentsYAML)
+		reloads := 0
+		service.OnReload(func() { reloads++ })
+
+		created, err := service.CreateEnvironment(newEnv, "tester")
+		require.NoError(t, err)
+		assert.Equal(t, "GCP Production", created.Name)
+		assert.False(t, created.CreatedAt.IsZero())
+
+		_, err = service.CreateEnvironment(newEnv, "tester")
+		assert.ErrorIs(t, err, ErrEnvironmentExists)
+
+		changed := newEnv
+		changed.Name = "GCP Production (us)"
+		updated, err := service.UpdateEnvironment("gcp-prod", changed, "tester")
+		require.NoError(t, err)
+		assert.Equal(t, "GCP Production (us)", updated.Name)
+		assert.Equal(t, created.CreatedAt.Unix(), updated.CreatedAt.Unix())
+		assert.False(t, updated.UpdatedAt.Before(created.UpdatedAt))
+
+		_, err = service.UpdateEnvironment("missing", changed, "tester")
+		assert.ErrorIs(t, err, ErrEnvironmentNotFound)
+
+		require.NoError(t, service.DeleteEnvironment("gcp-prod", "tester"))
+		assert.ErrorIs(t, service.DeleteEnvironment("gcp-prod", "tester"), ErrEnvironmentNotFound)
+		_, err = service.GetEnvironmentByID("gcp-prod")
+		assert.Error(t, err)
+
+		assert.Equal(t, 3, reloads)
+	})
+
+	t.Run("Invalid environments are rejected", func(t *testing.T) {
+		service := newDatabaseEnvironmentService(t, testEnvironmentsYAML)
+
+		invalid := newEnv
+		invalid.Criteria.Project = ""
+		_, err := service.CreateEnvironment(invalid, "tester")
+		assert.ErrorIs(t, err, ErrInvalidEnvironment)
+		assert.Contains(t, err.Error(), "criteria.project")
+
+		_, err = service.GetEnvironmentByID("gcp-prod")
+		assert.Error(t, err)
+	})
+
+	t.Run("Import merges or replaces and export round-trips", func(t *testing.T) {
+		service := newDatabaseEnvironmentService(t, testEnvironmentsYAML)
+
+		imported, err := service.ImportEnvironments([]byte(`environments:
+  - id: "prod"
+    name: "AWS Production (renamed)"
+    criteria: {cloud_type: "aws", account: "123456789012", region: "us-east-1"}
+  - id: "gcp-prod"
+    name: "GCP Production"
+    criteria: {cloud_type: "gcp", project: "project-1", zone: "us-central1"}
+`), ImportModeMerge, "tester")
+		require.NoError(t, err)
+		assert.Equal(t, 2, imported)
+
+		environments, err := service.GetEnvironments()
+		require.NoError(t, err)
+		assert.Len(t, environments, 3)
+		prod, err := service.GetEnvironmentByID("prod")
+		require.NoError(t, err)
+		assert.Equal(t, "AWS Production (renamed)", prod.Name)
+
+		exported, err := service.ExportEnvironments(nil)
+		require.NoError(t, err)
+		assert.NotContains(t, string(exported), "createdat")
+
+		_, err = service.ImportEnvironments([]byte(testEnvironmentsYAML), ImportModeReplace, "tester")
+		require.NoError(t, err)
+		environments, err = service.GetEnvironments()
+		require.NoError(t, err)
+		assert.Len(t, environments, 2)
+
+		reparsed, err := ParseEnvironmentConfig(exported)
+		require.NoError(t, err)
+		_, err = service.ImportEnvironments(exported, ImportModeReplace, "tester")
+		require.NoError(t, err)
+		environments, err = service.GetEnvironments()
+		require.NoError(t, err)
+		assert.Len(t, environments, len(reparsed.Environments))
+	})
+
+	t.Run("Invalid imports leave the configuration unchanged", func(t *testing.T) {
+		service := newDatabaseEnvironmentService(t, testEnvironmentsYAML)
+
+		_, err := service.ImportEnvironments([]byte(`environments:
+  - id: "prod"
+    name: ""
+`), ImportModeReplace, "tester")
+		assert.ErrorIs(t, err, ErrInvalidEnvironment)
+
+		environments, err := service.GetEnvironments()
+		require.NoError(t, err)
+		assert.Len(t, environments, 2)
+	})
+
+	t.Run("Invalid stored environments are rejected before a reload applies them", func(t *testing.T) {
+		service := newDatabaseEnvironmentService(t, testEnvironmentsYAML)
+		version := service.ConfigVersion()
+
+		require.NoError(t, service.db.Model(&models.Environment{}).Where("id = ?", "prod").Update("name", "").Error)
+		assert.ErrorIs(t, service.ReloadConfig("tester"), ErrInvalidEnvironment)
+
+		env, err := service.GetEnvironmentByID("prod")
+		require.NoError(t, err)
+		assert.Equal(t, "AWS Production", env.Name)
+		assert.Equal(t, version, service.ConfigVersion())
+	})
+}
+
+func TestEnvironmentServiceSyncsReplicas(t *testing.T) {
+	first := newDatabaseEnvironmentService(t, testEnvironmentsYAML)
+	second := NewDatabaseEnvironmentService(first.db, first.configPath)
+	require.NoError(t, second.LoadConfig())
+	reloads := 0
+	second.OnReload(func() { reloads++ })
+
+	// Nothing changed yet
+	require.NoError(t, second.syncFromDatabase())
+	assert.Equal(t, 0, reloads)
+
+	_, err := first.CreateEnvironment(models.Environment{
+		ID:       "gcp-prod",
+		Name:     "GCP Production",
+		Criteria: models.EnvironmentCriteria{CloudType: "gcp", Project: "project-1", Zone: "us-central1"},
+	}, "tester")
+	require.NoError(t, err)
+
+	require.NoError(t, second.syncFromDatabase())
+	assert.Equal(t, 1, reloads)
+	_, err = second.GetEnvironmentByID("gcp-prod")
+	assert.NoError(t, err)
+	assert.Equal(t, first.ConfigVersion(), second.ConfigVersion())
+
+	// Picking the change up records no version of its own
+	versions, total, err := second.ListVersions(1, 10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, "tester", versions[0].Author)
+}
+
+func TestValidateEnvironments(t *testing.T) {
+	valid := models.Environment{
+		ID:       "azure-prod",
+		Name:     "Azure Production",
+		Criteria: models.EnvironmentCriteria{CloudType: "azure", Subscription: "sub-1", Location: "eastus"},
+	}
+	assert.NoError(t, ValidateEnvironments([]models.Environment{valid}))
+
+	assert.ErrorContains(t, ValidateEnvironments([]models.Environment{valid, valid}), "duplicate environment ID")
+
+	missing := valid
+	missing.Criteria.Location = ""
+	assert.ErrorContains(t, ValidateEnvironments([]models.Environment{missing}), "criteria.location")
+
+	unknown := valid
+	unknown.Criteria.CloudType = "oracle"
+	assert.ErrorContains(t, ValidateEnvironments([]models.Environment{unknown}), "unsupported criteria.cloud_type")
+}
@@ -29,6 +29,9 @@ const (
 	OperatorLessThan      FilterOperator = "lt"
 	OperatorLessEqual     FilterOperator = "lte"
 	OperatorBetween       FilterOperator = "between"
+
+	// Hierarchy operators
+	OperatorUnder FilterOperator = "under" // the environment or any of its descendants
 )
 
 // FieldType represents the data type of a field
@@ -89,7 +92,7 @@ func VMsFilterConfig() FilterConfig {
 			},
 			"env": {
 				Type:      FieldTypeString,
-				Operators: []FilterOperator{OperatorEquals, OperatorNotEquals, OperatorIn, OperatorNotIn, OperatorIsNull, OperatorIsNotNull},
+				Operators: []FilterOperator{OperatorEquals, OperatorNotEquals, OperatorIn, OperatorNotIn, OperatorIsNull, OperatorIsNotNull, OperatorUnder},
 			},
 			"environment": {
 				Type:      FieldTypeString,
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"golang-service/internal/models"
+)
+
+// InheritEnvironments returns the environments with inheritance applied. An environment
+// with a parent starts from the parent's effective criteria, tags and metadata:
+//   - criteria it sets override the parent's, and its rules are added to the parent's
+//   - tags are the union of both
+//   - metadata keys it sets override the parent's
+//   - a zero priority inherits the parent's priority
+//
+// Ancestors is set on every environment, root first. Environments whose parent is
+// unknown or part of a cycle are returned without inheritance.
+func InheritEnvironments(environments []models.Environment) []models.Environment {
+	byID := make(map[string]models.Environment, len(environments))
+	for _, env := range environments {
+		byID[env.ID] = env
+	}
+
+	effective := make(map[string]models.Environment, len(environments))
+	var resolve func(id string, visiting map[string]bool) (models.Environment, bool)
+	resolve = func(id string, visiting map[string]bool) (models.Environment, bool) {
+		if env, ok := effective[id]; ok {
+			return env, true
+		}
+		env, ok := byID[id]
+		if !ok || visiting[id] {
+			return models.Environment{}, false
+		}
+
+		env.Ancestors = nil
+		if env.Parent != "" {
+			visiting[id] = true
+			parent, ok := resolve(env.Parent, visiting)
+			delete(visiting, id)
+			if !ok {
+				return env, false
+			}
+			env = inherit(parent, env)
+		}
+		effective[id] = env
+		return env, true
+	}
+
+	result := make([]models.Environment, len(environments))
+	for i, env := range environments {
+		if resolved, ok := resolve(env.ID, map[string]bool{}); ok {
+			result[i] = resolved
+		} else {
+			env.Ancestors = nil
+			result[i] = env
+		}
+	}
+	return result
+}
+
+// inherit applies a parent's effective settings to its child
+func inherit(parent, child models.Environment) models.Environment {
+	child.Ancestors = append(append([]string{}, parent.Ancestors...), parent.ID)
+
+	criteria := parent.Criteria
+	for _, field := range []struct {
+		target *string
+		value  string
+	}{
+		{&criteria.CloudType, child.Criteria.CloudType},
+		{&criteria.Account, child.Criteria.Account},
+		{&criteria.Region, child.Criteria.Region},
+		{&criteria.VPC, child.Criteria.VPC},
+		{&criteria.Subscription, child.Criteria.Subscription},
+		{&criteria.Location, child.Criteria.Location},
+		{&criteria.Project, child.Criteria.Project},
+		{&criteria.Zone, child.Criteria.Zone},
+	} {
+		if field.value != "" {
+			*field.target = field.value
+		}
+	}
+	criteria.Rules = append(append([]models.MatchRule{}, parent.Criteria.Rules...), child.Criteria.Rules...)
+	if len(criteria.Rules) == 0 {
+		criteria.Rules = nil
+	}
+	child.Criteria = criteria
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range append(append([]string{}, parent.Tags...), child.Tags...) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	child.Tags = tags
+
+	if len(parent.Metadata) > 0 {
+		metadata := make(map[string]interface{}, len(parent.Metadata)+len(child.Metadata))
+		for key, value := range parent.Metadata {
+			metadata[key] = value
+		}
+		for key, value := range child.Metadata {
+			metadata[key] = value
+		}
+		child.Metadata = metadata
+	}
+
+	if child.Priority == 0 {
+		child.Priority = parent.Priority
+	}
+	return child
+}
+
+// hierarchyIssues reports parents that do not exist and parent cycles
+func hierarchyIssues(environments []models.Environment) []ValidationIssue {
+	parents := make(map[string]string, len(environments))
+	for _, env := range environments {
+		parents[env.ID] = env.Parent
+	}
+
+	var issues []ValidationIssue
+	add := func(env, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Severity: SeverityError, Environment: env, Field: "parent", Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, env := range environments {
+		if env.Parent == "" {
+			continue
+		}
+		if env.Parent == env.ID {
+			add(env.ID, "environment '%s' cannot be its own parent", env.ID)
+			continue
+		}
+		if _, ok := parents[env.Parent]; !ok {
+			add(env.ID, "environment '%s' has unknown parent '%s'", env.ID, env.Parent)
+			continue
+		}
+
+		seen := map[string]bool{env.ID: true}
+		for id := env.Parent; id != ""; id = parents[id] {
+			if seen[id] {
+				add(env.ID, "environment '%s' is part of a parent cycle", env.ID)
+				break
+			}
+			seen[id] = true
+		}
+	}
+	return issues
+}
+
+// isAncestor reports whether ancestor is among the ancestors of an effective environment
+func isAncestor(ancestor string, env models.Environment) bool {
+	return contains(env.Ancestors, ancestor)
+}
+
+// Children returns the environments whose parent is id
+func Children(environments []models.Environment, id string) []models.Environment {
+	var children []models.Environment
+	for _, env := range environments {
+		if env.Parent == id {
+			children = append(children, env)
+		}
+	}
+	return children
+}
+
+// EnvironmentNode is an environment with its descendants, for tree views
+type EnvironmentNode struct {
+	models.Environment
+	Children []EnvironmentNode `json:"children"`
+}
+
+// EnvironmentTree arranges environments into trees below their roots, or below the
+// environment with the given ID when root is set. Children are ordered by ID.
+func EnvironmentTree(environments []models.Environment, root string) []EnvironmentNode {
+	byParent := make(map[string][]models.Environment)
+	ids := make(map[string]bool, len(environments))
+	for _, env := range environments {
+		ids[env.ID] = true
+	}
+	for _, env := range environments {
+		parent := env.Parent
+		// Orphans are shown as roots so they stay visible
+		if !ids[parent] {
+			parent = ""
+		}
+		byParent[parent] = append(byParent[parent], env)
+	}
+
+	var build func(parent string, visited map[string]bool) []EnvironmentNode
+	build = func(parent string, visited map[string]bool) []EnvironmentNode {
+		nodes := []EnvironmentNode{}
+		for _, env := range sortedByID(byParent[parent]) {
+			if visited[env.ID] {
+				continue
+			}
+			visited[env.ID] = true
+			nodes = append(nodes, EnvironmentNode{Environment: env, Children: build(env.ID, visited)})
+		}
+		return nodes
+	}
+	return build(root, map[string]bool{})
+}
+
+// sortedByID returns a copy of the environments ordered by ID
+func sortedByID(environments []models.Environment) []models.Environment {
+	sorted := make([]models.Environment, len(environments))
+	copy(sorted, environments)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
+
+// GetChildren returns the direct children of an environment within scope, ordered by ID.
+// Environments outside the scope are reported as not found.
+func (s *EnvironmentService) GetChildren(id string, scope *Scope) ([]models.Environment, error) {
+	environments, err := s.GetEnvironments()
+	if err != nil {
+		return nil, err
+	}
+	environments = scope.FilterEnvironments(environments)
+	if findEnvironment(environments, id) == nil {
+		return nil, fmt.Errorf("%w: %s", ErrEnvironmentNotFound, id)
+	}
+	return sortedByID(Children(environments, id)), nil
+}
+
+// GetEnvironmentTree returns the hierarchy of the environments within scope, or the subtree
+// below root when set. Environments whose parent is out of scope appear as roots.
+func (s *EnvironmentService) GetEnvironmentTree(root string, scope *Scope) ([]EnvironmentNode, error) {
+	environments, err := s.GetEnvironments()
+	if err != nil {
+		return nil, err
+	}
+	environments = scope.FilterEnvironments(environments)
+	if root != "" && findEnvironment(environments, root) == nil {
+		return nil, fmt.Errorf("%w: %s", ErrEnvironmentNotFound, root)
+	}
+	return EnvironmentTree(environments, root), nil
+}
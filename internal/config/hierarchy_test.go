@@ -0,0 +1,139 @@
+package config
+
+import (
+	"testing"
+
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const hierarchyYAML = `environments:
+  - id: "prod"
+    name: "Production"
+    tags: ["production"]
+    metadata: {owner: "platform", tier: "1"}
+    criteria:
+      cloud_type: "aws"
+      account: "123456789012"
+      region: "us-east-1"
+  - id: "prod-us"
+    name: "Production US"
+    parent: "prod"
+    tags: ["us"]
+    metadata: {tier: "2"}
+    criteria:
+      rules: [{tag: "Team"}]
+  - id: "prod-us-east1"
+    name: "Production US East 1"
+    parent: "prod-us"
+    criteria:
+      vpc: "vpc-12345678"
+  - id: "staging"
+    name: "Staging"
+    criteria: {cloud_type: "aws", account: "210987654321", region: "us-east-1"}
+`
+
+func TestInheritEnvironments(t *testing.T) {
+	config, err := ParseEnvironmentConfig([]byte(hierarchyYAML))
+	require.NoError(t, err)
+
+	effective := InheritEnvironments(config.Environments)
+	leaf := effective[2]
+	assert.Equal(t, []string{"prod", "prod-us"}, leaf.Ancestors)
+	assert.Equal(t, "aws", leaf.Criteria.CloudType)
+	assert.Equal(t, "123456789012", leaf.Criteria.Account)
+	assert.Equal(t, "vpc-12345678", leaf.Criteria.VPC)
+	assert.Len(t, leaf.Criteria.Rules, 1)
+	assert.Equal(t, []string{"production", "us"}, leaf.Tags)
+	assert.Equal(t, map[string]interface{}{"owner": "platform", "tier": "2"}, leaf.Metadata)
+
+	assert.Empty(t, effective[0].Ancestors)
+	assert.Empty(t, config.Environments[2].Criteria.Account, "inheritance must not modify the input")
+}
+
+func TestHierarchyValidation(t *testing.T) {
+	config, err := ParseEnvironmentConfig([]byte(hierarchyYAML))
+	require.NoError(t, err)
+	assert.NoError(t, ValidateEnvironments(config.Environments), "criteria are inherited")
+
+	orphan := append(config.Environments, models.Environment{ID: "orphan", Name: "Orphan", Parent: "missing"})
+	assert.ErrorContains(t, ValidateEnvironments(orphan), "unknown parent 'missing'")
+
+	cycle := []models.Environment{
+		{ID: "a", Name: "A", Parent: "b", Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "1", Region: "r"}},
+		{ID: "b", Name: "B", Parent: "a"},
+	}
+	assert.ErrorContains(t, ValidateEnvironments(cycle), "parent cycle")
+
+	report := ValidateEnvironmentConfig([]byte(hierarchyYAML))
+	assert.True(t, report.Valid)
+	assert.Empty(t, report.Warnings, "descendants are not reported as shadowed by their ancestors")
+}
+
+func TestResolveMostSpecificEnvironment(t *testing.T) {
+	service := NewEnvironmentService(writeEnvironmentsFile(t, hierarchyYAML))
+	require.NoError(t, service.LoadConfig())
+
+	vm := models.VM{
+		CloudType:      "aws",
+		CloudAccountID: "123456789012",
+		Location:       "us-east-1",
+		Tags:           map[string]string{"Team": "web"},
+		VpcID:          "vpc-12345678",
+	}
+	env, err := service.ResolveEnvironmentForVM(vm)
+	require.NoError(t, err)
+	assert.Equal(t, "prod-us-east1", env.ID)
+	assert.Equal(t, []string{"prod", "prod-us"}, env.Ancestors)
+
+	// Without network information the leaf adds no condition over its parent, so depth decides
+	vm.VpcID = ""
+	resolution, err := service.ExplainResolution(vm)
+	require.NoError(t, err)
+	assert.Equal(t, "prod-us-east1", resolution.Winner.Environment.ID)
+	assert.False(t, resolution.Tied)
+	assert.Equal(t, "less specific than prod-us-east1", resolution.Candidates[1].Reason)
+
+	vm.Tags = nil
+	env, err = service.ResolveEnvironmentForVM(vm)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", env.ID)
+}
+
+func TestEnvironmentTree(t *testing.T) {
+	service := NewEnvironmentService(writeEnvironmentsFile(t, hierarchyYAML))
+	require.NoError(t, service.LoadConfig())
+
+	children, err := service.GetChildren("prod", nil)
+	require.NoError(t, err)
+	require.Len(t, children, 1)
+	assert.Equal(t, "prod-us", children[0].ID)
+	assert.Equal(t, []string{"prod"}, children[0].Ancestors)
+
+	_, err = service.GetChildren("missing", nil)
+	assert.ErrorIs(t, err, ErrEnvironmentNotFound)
+
+	tree, err := service.GetEnvironmentTree("", nil)
+	require.NoError(t, err)
+	require.Len(t, tree, 2)
+	assert.Equal(t, "prod", tree[0].ID)
+	assert.Equal(t, "prod-us-east1", tree[0].Children[0].Children[0].ID)
+	assert.Equal(t, "staging", tree[1].ID)
+	assert.Empty(t, tree[1].Children)
+
+	subtree, err := service.GetEnvironmentTree("prod-us", nil)
+	require.NoError(t, err)
+	require.Len(t, subtree, 1)
+	assert.Equal(t, "prod-us-east1", subtree[0].ID)
+}
+
+func TestDeleteEnvironmentWithChildren(t *testing.T) {
+	service := newDatabaseEnvironmentService(t, hierarchyYAML)
+
+	assert.ErrorIs(t, service.DeleteEnvironment("prod", "tester"), ErrInvalidEnvironment)
+	require.NoError(t, service.DeleteEnvironment("prod-us-east1", "tester"))
+	require.NoError(t, service.DeleteEnvironment("prod-us", "tester"))
+	require.NoError(t, service.DeleteEnvironment("prod", "tester"))
+}
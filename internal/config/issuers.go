@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IssuerConfig describes an OpenID Connect issuer whose access tokens are accepted
+type IssuerConfig struct {
+	Name string `yaml:"name"`
+	// Issuer is the exact iss claim of the issuer's tokens
+	Issuer string `yaml:"issuer"`
+	// Namespace qualifies the subjects and object IDs in the issuer's tokens, so that the
+	// same ID from another issuer is never taken for the same caller. Issuers vouching for
+	// one directory share a namespace; it defaults to the issuer.
+	Namespace string `yaml:"namespace"`
+	// DiscoveryURL is the issuer's OpenID configuration document, used to find its JWKS
+	DiscoveryURL string `yaml:"discovery_url"`
+	// JWKSURL overrides the JWKS URL from discovery, e.g. to point at a local stub
+	JWKSURL string `yaml:"jwks_url"`
+	// Audiences lists the accepted aud values; a token must carry at least one
+	Audiences []string `yaml:"audiences"`
+	// Algorithms lists the accepted signing algorithms (RS256, ES256); defaults to RS256
+	Algorithms []string     `yaml:"algorithms"`
+	Claims     ClaimMapping `yaml:"claims"`
+}
+
+// ClaimMapping names the token claims identity fields are read from. Nested claims are
+// addressed with dots, e.g. "realm_access.roles".
+type ClaimMapping struct {
+	Subject  string `yaml:"subject"`
+	Roles    string `yaml:"roles"`
+	Groups   string `yaml:"groups"`
+	ObjectID string `yaml:"object_id"`
+	TenantID string `yaml:"tenant_id"`
+	ClientID string `yaml:"client_id"`
+	Name     string `yaml:"name"`
+	Email    string `yaml:"email"`
+}
+
+// supportedAlgorithms lists the signing algorithms issuers may accept
+var supportedAlgorithms = map[string]bool{"RS256": true, "ES256": true}
+
+// issuersFile is the root of the issuers file
+type issuersFile struct {
+	Issuers []IssuerConfig `yaml:"issuers"`
+}
+
+// LoadIssuers reads the token issuers from the file at cfg.AuthIssuersPath, expanding
+// ${VAR} references to environment variables. Without the file, Entra ID v1 and v2
+// issuers are derived from AZURE_TENANT_ID and AZURE_CLIENT_ID when those are set.
+func LoadIssuers(cfg *Config) ([]IssuerConfig, error) {
+	data, err := os.ReadFile(cfg.AuthIssuersPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return EntraIssuers(cfg.AzureTenantID, cfg.AzureClientID), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token issuers: %w", err)
+	}
+	return ParseIssuers([]byte(os.ExpandEnv(string(data))))
+}
+
+// ParseIssuers parses and validates token issuers from their YAML representation,
+// filling in default algorithms and claim names
+func ParseIssuers(data []byte) ([]IssuerConfig, error) {
+	var file issuersFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse token issuers YAML: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range file.Issuers {
+		issuer := &file.Issuers[i]
+		if issuer.Name == "" {
+			issuer.Name = issuer.Issuer
+		}
+		if issuer.Issuer == "" {
+			return nil, fmt.Errorf("token issuer %d is missing issuer", i+1)
+		}
+		if issuer.Namespace == "" {
+			issuer.Namespace = issuer.Issuer
+		}
+		if seen[issuer.Issuer] {
+			return nil, fmt.Errorf("duplicate token issuer: %s", issuer.Issuer)
+		}
+		seen[issuer.Issuer] = true
+		if issuer.DiscoveryURL == "" && issuer.JWKSURL == "" {
+			return nil, fmt.Errorf("token issuer '%s' needs discovery_url or jwks_url", issuer.Name)
+		}
+		if len(issuer.Audiences) == 0 {
+			return nil, fmt.Errorf("token issuer '%s' accepts no audiences", issuer.Name)
+		}
+		if len(issuer.Algorithms) == 0 {
+			issuer.Algorithms = []string{"RS256"}
+		}
+		for _, algorithm := range issuer.Algorithms {
+			if !supportedAlgorithms[algorithm] {
+				return nil, fmt.Errorf("token issuer '%s' has unsupported algorithm %s", issuer.Name, algorithm)
+			}
+		}
+		issuer.Claims = issuer.Claims.withDefaults()
+	}
+	return file.Issuers, nil
+}
+
+// withDefaults fills in the standard claim names for unset mappings
+func (m ClaimMapping) withDefaults() ClaimMapping {
+	for _, field := range []struct {
+		value    *string
+		fallback string
+	}{
+		{&m.Subject, "sub"},
+		{&m.Roles, "roles"},
+		{&m.Groups, "groups"},
+		{&m.ObjectID, "oid"},
+		{&m.TenantID, "tid"},
+		{&m.ClientID, "azp"},
+		{&m.Name, "name"},
+		{&m.Email, "email"},
+	} {
+		if *field.value == "" {
+			*field.value = field.fallback
+		}
+	}
+	return m
+}
+
+// EntraNamespace is the identity namespace of an Entra ID tenant, shared by its v1.0 and
+// v2.0 issuers since both carry the same object IDs
+func EntraNamespace(tenantID string) string {
+	return "entra:" + tenantID
+}
+
+// EntraIssuers returns the Entra ID issuers of a tenant: v1.0 tokens (issued by
+// sts.windows.net) and v2.0 tokens (issued by login.microsoftonline.com). Both are
+// accepted for the client ID and its api:// application ID URI.
+func EntraIssuers(tenantID, clientID string) []IssuerConfig {
+	if tenantID == "" || clientID == "" {
+		return nil
+	}
+
+	discovery := "https://login.microsoftonline.com/" + tenantID + "/v2.0/.well-known/openid-configuration"
+	audiences := []string{clientID}
+	if !strings.HasPrefix(clientID, "api://") {
+		audiences = append(audiences, "api://"+clientID)
+	}
+	// Entra ID only includes email as an optional claim. v1.0 tokens always carry the
+	// user principal name the tenant assigned; v2.0's preferred_username is not verified
+	// and can be any string, so v2.0 tokens only provide the email claim when configured.
+	v1Claims := ClaimMapping{ClientID: "appid", Email: "upn"}.withDefaults()
+	v2Claims := ClaimMapping{}.withDefaults()
+
+	return []IssuerConfig{
+		{
+			Name:         "entra-v1",
+			Issuer:       "https://sts.windows.net/" + tenantID + "/",
+			Namespace:    EntraNamespace(tenantID),
+			DiscoveryURL: discovery,
+			Audiences:    audiences,
+			Algorithms:   []string{"RS256"},
+			Claims:       v1Claims,
+		},
+		{
+			Name:         "entra-v2",
+			Issuer:       "https://login.microsoftonline.com/" + tenantID + "/v2.0",
+			Namespace:    EntraNamespace(tenantID),
+			DiscoveryURL: discovery,
+			Audiences:    audiences,
+			Algorithms:   []string{"RS256"},
+			Claims:       v2Claims,
+		},
+	}
+}
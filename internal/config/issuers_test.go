@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIssuers(t *testing.T) {
+	issuers, err := ParseIssuers([]byte(`issuers:
+  - issuer: https://idp.example.com
+    discovery_url: https://idp.example.com/.well-known/openid-configuration
+    audiences: [atlas]
+`))
+	require.NoError(t, err)
+	require.Len(t, issuers, 1)
+	assert.Equal(t, "https://idp.example.com", issuers[0].Name)
+	assert.Equal(t, []string{"RS256"}, issuers[0].Algorithms)
+	assert.Equal(t, "roles", issuers[0].Claims.Roles)
+	assert.Equal(t, "azp", issuers[0].Claims.ClientID)
+	assert.Equal(t, "https://idp.example.com", issuers[0].Namespace)
+
+	for name, yaml := range map[string]string{
+		"no keys":        "issuers: [{issuer: a, audiences: [x]}]",
+		"no audiences":   "issuers: [{issuer: a, jwks_url: http://k}]",
+		"bad algorithm":  "issuers: [{issuer: a, jwks_url: http://k, audiences: [x], algorithms: [HS256]}]",
+		"duplicate":      "issuers: [{issuer: a, jwks_url: http://k, audiences: [x]}, {issuer: a, jwks_url: http://k, audiences: [x]}]",
+		"missing issuer": "issuers: [{jwks_url: http://k, audiences: [x]}]",
+	} {
+		_, err := ParseIssuers([]byte(yaml))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestEntraIssuers(t *testing.T) {
+	assert.Nil(t, EntraIssuers("", "client"))
+
+	issuers := EntraIssuers("tenant", "client")
+	require.Len(t, issuers, 2)
+	assert.Equal(t, "https://sts.windows.net/tenant/", issuers[0].Issuer)
+	assert.Equal(t, "appid", issuers[0].Claims.ClientID)
+	assert.Equal(t, "https://login.microsoftonline.com/tenant/v2.0", issuers[1].Issuer)
+	assert.Equal(t, "azp", issuers[1].Claims.ClientID)
+	assert.Equal(t, "upn", issuers[0].Claims.Email)
+	assert.Equal(t, "email", issuers[1].Claims.Email)
+	assert.Equal(t, "entra:tenant", issuers[0].Namespace)
+	assert.Equal(t, issuers[0].Namespace, issuers[1].Namespace)
+	assert.Equal(t, []string{"client", "api://client"}, issuers[1].Audiences)
+}
+
+func TestLoadIssuers(t *testing.T) {
+	cfg := &Config{AuthIssuersPath: filepath.Join(t.TempDir(), "missing.yaml"), AzureTenantID: "tenant", AzureClientID: "client"}
+	issuers, err := LoadIssuers(cfg)
+	require.NoError(t, err)
+	assert.Len(t, issuers, 2)
+
+	t.Setenv("TEST_IDP_AUDIENCE", "atlas-api")
+	cfg.AuthIssuersPath = filepath.Join(t.TempDir(), "issuers.yaml")
+	require.NoError(t, os.WriteFile(cfg.AuthIssuersPath, []byte(`issuers:
+  - issuer: https://idp.example.com
+    jwks_url: https://idp.example.com/keys
+    audiences: ["${TEST_IDP_AUDIENCE}"]
+`), 0o644))
+	issuers, err = LoadIssuers(cfg)
+	require.NoError(t, err)
+	require.Len(t, issuers, 1)
+	assert.Equal(t, []string{"atlas-api"}, issuers[0].Audiences)
+}
@@ -0,0 +1,427 @@
+package config
+
+import (
+	"container/list"
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang-service/internal/models"
+)
+
+// Candidate is the outcome of evaluating one environment against a VM
+type Candidate struct {
+	Environment models.Environment `json:"environment"`
+	// Matched reports whether every criterion and rule of the environment held
+	Matched bool `json:"matched"`
+	// Priority is the environment's explicit priority; it ranks matches first
+	Priority int `json:"priority"`
+	// Score counts the conditions that matched; it ranks matches of equal priority,
+	// so more specific environments win
+	Score int `json:"score"`
+	// Conditions lists the conditions that matched, e.g. "account=123456789012"
+	Conditions []string `json:"conditions"`
+	// Reason explains why a non-matching environment was rejected, or, in a Resolution,
+	// why a matching environment lost to the winner
+	Reason string `json:"reason,omitempty"`
+}
+
+// Resolution explains how a VM resolves against a set of environments
+type Resolution struct {
+	VM models.VM `json:"vm"`
+	// Winner is the environment the VM resolves to, nil when none matched
+	Winner *Candidate `json:"winner"`
+	// Tied reports that the winner shares its priority and score with another match
+	// and was chosen by ID order
+	Tied       bool        `json:"tied"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+// EvaluateEnvironments evaluates every environment against a VM. The environments must have
+// inheritance applied (see InheritEnvironments). Matching candidates come first, ordered by
+// priority, then score, then depth in the hierarchy so the most specific environment wins,
+// then ID; rejected candidates follow in ID order.
+func EvaluateEnvironments(environments []models.Environment, vm models.VM) []Candidate {
+	candidates := make([]Candidate, 0, len(environments))
+	for _, env := range environments {
+		candidates = append(candidates, evaluateEnvironment(env, vm))
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Matched != b.Matched {
+			return a.Matched
+		}
+		if a.Matched {
+			if a.Priority != b.Priority {
+				return a.Priority > b.Priority
+			}
+			if a.Score != b.Score {
+				return a.Score > b.Score
+			}
+			if len(a.Environment.Ancestors) != len(b.Environment.Ancestors) {
+				return len(a.Environment.Ancestors) > len(b.Environment.Ancestors)
+			}
+		}
+		return a.Environment.ID < b.Environment.ID
+	})
+	return candidates
+}
+
+// ExplainResolution evaluates every environment against a VM and explains the outcome,
+// including why each matching environment other than the winner lost. The winner's
+// environment carries its ancestry chain.
+func ExplainResolution(environments []models.Environment, vm models.VM) Resolution {
+	candidates := EvaluateEnvironments(InheritEnvironments(environments), vm)
+	resolution := Resolution{VM: vm, Candidates: candidates}
+	if len(candidates) == 0 || !candidates[0].Matched {
+		return resolution
+	}
+
+	winner := candidates[0]
+	resolution.Winner = &winner
+	resolution.Tied = len(TiedCandidates(candidates)) > 1
+
+	for i := 1; i < len(candidates) && candidates[i].Matched; i++ {
+		c := &candidates[i]
+		switch {
+		case c.Priority < winner.Priority:
+			c.Reason = fmt.Sprintf("priority %d is lower than %s's %d", c.Priority, winner.Environment.ID, winner.Priority)
+		case c.Score < winner.Score:
+			c.Reason = fmt.Sprintf("matched %d conditions, fewer than %s's %d", c.Score, winner.Environment.ID, winner.Score)
+		case len(c.Environment.Ancestors) < len(winner.Environment.Ancestors):
+			c.Reason = fmt.Sprintf("less specific than %s", winner.Environment.ID)
+		default:
+			c.Reason = fmt.Sprintf("tied with %s on priority and score; %s wins by ID order", winner.Environment.ID, winner.Environment.ID)
+		}
+	}
+	return resolution
+}
+
+// evaluateEnvironment checks one environment's criteria and rules against a VM
+func evaluateEnvironment(env models.Environment, vm models.VM) Candidate {
+	candidate := Candidate{Environment: env, Priority: env.Priority}
+	criteria := env.Criteria
+
+	reject := func(format string, args ...interface{}) Candidate {
+		candidate.Conditions = nil
+		candidate.Score = 0
+		candidate.Reason = fmt.Sprintf(format, args...)
+		return candidate
+	}
+	match := func(condition string) {
+		candidate.Conditions = append(candidate.Conditions, condition)
+		candidate.Score++
+	}
+
+	// A resource without a known cloud type is only matched on its other attributes
+	if criteria.CloudType != "" && vm.CloudType != "" {
+		if criteria.CloudType != vm.CloudType {
+			return reject("cloud_type %s does not match %s", criteria.CloudType, vm.CloudType)
+		}
+		match("cloud_type=" + criteria.CloudType)
+	}
+
+	// Account-level criteria all identify the VM's account, subscription or project
+	for _, c := range []struct{ key, value string }{
+		{"account", criteria.Account},
+		{"subscription", criteria.Subscription},
+		{"project", criteria.Project},
+	} {
+		if c.value == "" {
+			continue
+		}
+		if c.value != vm.CloudAccountID {
+			return reject("%s %s does not match %s", c.key, c.value, vm.CloudAccountID)
+		}
+		match(c.key + "=" + c.value)
+	}
+
+	for _, c := range []struct{ key, value string }{
+		{"region", criteria.Region},
+		{"location", criteria.Location},
+	} {
+		if c.value == "" {
+			continue
+		}
+		if c.value != vm.Location {
+			return reject("%s %s does not match %s", c.key, c.value, vm.Location)
+		}
+		match(c.key + "=" + c.value)
+	}
+
+	// A zone may name a region, matching every zone within it (us-central1 matches us-central1-a)
+	if criteria.Zone != "" {
+		if !strings.HasPrefix(vm.Location, criteria.Zone) {
+			return reject("zone %s does not match %s", criteria.Zone, vm.Location)
+		}
+		match("zone=" + criteria.Zone)
+	}
+
+	// The VPC (AWS), network (GCP) or virtual network (Azure) separates environments sharing an
+	// account and region. A VM whose network is unknown is not rejected, but gains no score.
+	if criteria.VPC != "" && vm.VpcID != "" {
+		if !strings.EqualFold(criteria.VPC, vm.VpcID) {
+			return reject("vpc %s does not match %s", criteria.VPC, vm.VpcID)
+		}
+		match("vpc=" + criteria.VPC)
+	}
+
+	for i, rule := range criteria.Rules {
+		conditions, ok := matchRule(rule, vm)
+		if !ok {
+			return reject("rule %d (%s) does not match", i+1, describeRule(rule))
+		}
+		for _, condition := range conditions {
+			match(condition)
+		}
+	}
+
+	// An environment without any condition would claim every VM
+	if candidate.Score == 0 {
+		return reject("environment has no criteria")
+	}
+
+	candidate.Matched = true
+	return candidate
+}
+
+// matchRule evaluates a rule, returning the conditions that held when it matches
+func matchRule(rule models.MatchRule, vm models.VM) ([]string, bool) {
+	var conditions []string
+
+	if rule.Tag != "" {
+		value, ok := vm.Tags[rule.Tag]
+		if !ok {
+			return nil, false
+		}
+		if rule.Equals != nil {
+			if value != *rule.Equals {
+				return nil, false
+			}
+			conditions = append(conditions, fmt.Sprintf("tag %s=%s", rule.Tag, value))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("tag %s exists", rule.Tag))
+		}
+	}
+
+	if rule.Name != "" {
+		if ok, _ := path.Match(rule.Name, vm.Name); !ok {
+			return nil, false
+		}
+		conditions = append(conditions, "name matches "+rule.Name)
+	}
+
+	if rule.NameRegex != "" {
+		re, err := compileRegex(rule.NameRegex)
+		if err != nil || !re.MatchString(vm.Name) {
+			return nil, false
+		}
+		conditions = append(conditions, "name matches /"+rule.NameRegex+"/")
+	}
+
+	if rule.CIDR != "" {
+		network, err := parseCIDR(rule.CIDR)
+		ip := net.ParseIP(vm.PrivateIP)
+		if err != nil || ip == nil || !network.Contains(ip) {
+			return nil, false
+		}
+		conditions = append(conditions, "private IP in "+rule.CIDR)
+	}
+
+	if rule.VPC != "" {
+		if vm.VpcID == "" || !strings.EqualFold(vm.VpcID, rule.VPC) {
+			return nil, false
+		}
+		conditions = append(conditions, "vpc="+rule.VPC)
+	}
+
+	if rule.ResourceGroup != "" {
+		if !strings.EqualFold(vm.ResourceGroup, rule.ResourceGroup) {
+			return nil, false
+		}
+		conditions = append(conditions, "resource_group="+rule.ResourceGroup)
+	}
+
+	for _, sub := range rule.All {
+		subConditions, ok := matchRule(sub, vm)
+		if !ok {
+			return nil, false
+		}
+		conditions = append(conditions, subConditions...)
+	}
+
+	if len(rule.Any) > 0 {
+		matched := false
+		for _, sub := range rule.Any {
+			if subConditions, ok := matchRule(sub, vm); ok {
+				conditions = append(conditions, subConditions...)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return nil, false
+		}
+	}
+
+	return conditions, len(conditions) > 0
+}
+
+// validateRule checks that a rule has at least one condition and that its patterns compile
+func validateRule(rule models.MatchRule) error {
+	if rule.Equals != nil && rule.Tag == "" {
+		return fmt.Errorf("equals requires tag")
+	}
+	if rule.Name != "" {
+		if _, err := path.Match(rule.Name, ""); err != nil {
+			return fmt.Errorf("invalid name glob %q: %v", rule.Name, err)
+		}
+	}
+	if rule.NameRegex != "" {
+		if _, err := compileRegex(rule.NameRegex); err != nil {
+			return fmt.Errorf("invalid name_regex %q: %v", rule.NameRegex, err)
+		}
+	}
+	if rule.CIDR != "" {
+		if _, err := parseCIDR(rule.CIDR); err != nil {
+			return fmt.Errorf("invalid cidr %q: %v", rule.CIDR, err)
+		}
+	}
+	if describeRule(rule) == "" {
+		return fmt.Errorf("rule has no conditions")
+	}
+
+	for _, sub := range append(append([]models.MatchRule{}, rule.All...), rule.Any...) {
+		if err := validateRule(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeRule renders a rule compactly for explanations
+func describeRule(rule models.MatchRule) string {
+	var parts []string
+	if rule.Tag != "" {
+		if rule.Equals != nil {
+			parts = append(parts, fmt.Sprintf("tag %s=%s", rule.Tag, *rule.Equals))
+		} else {
+			parts = append(parts, fmt.Sprintf("tag %s exists", rule.Tag))
+		}
+	}
+	if rule.Name != "" {
+		parts = append(parts, "name "+rule.Name)
+	}
+	if rule.NameRegex != "" {
+		parts = append(parts, "name /"+rule.NameRegex+"/")
+	}
+	if rule.CIDR != "" {
+		parts = append(parts, "cidr "+rule.CIDR)
+	}
+	if rule.VPC != "" {
+		parts = append(parts, "vpc "+rule.VPC)
+	}
+	if rule.ResourceGroup != "" {
+		parts = append(parts, "resource_group "+rule.ResourceGroup)
+	}
+	if len(rule.All) > 0 {
+		parts = append(parts, "all("+describeRules(rule.All, " and ")+")")
+	}
+	if len(rule.Any) > 0 {
+		parts = append(parts, "any("+describeRules(rule.Any, " or ")+")")
+	}
+	return strings.Join(parts, " and ")
+}
+
+// describeRules renders nested rules joined by a connective
+func describeRules(rules []models.MatchRule, sep string) string {
+	parts := make([]string, len(rules))
+	for i, rule := range rules {
+		parts[i] = describeRule(rule)
+	}
+	return strings.Join(parts, sep)
+}
+
+// Compiled patterns are cached since every VM is evaluated against every environment.
+// Patterns can come from requests (proposed configurations), so the caches are bounded.
+const maxCompiledPatterns = 1024
+
+var (
+	regexCache = newPatternCache[*regexp.Regexp](maxCompiledPatterns)
+	cidrCache  = newPatternCache[*net.IPNet](maxCompiledPatterns)
+)
+
+// patternCache is a bounded cache of compiled patterns. When full, the least recently
+// used pattern is evicted.
+type patternCache[T any] struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+type patternEntry[T any] struct {
+	pattern string
+	value   T
+}
+
+func newPatternCache[T any](maxEntries int) *patternCache[T] {
+	return &patternCache[T]{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the compiled pattern, compiling and caching it on a miss
+func (pc *patternCache[T]) get(pattern string, compile func(string) (T, error)) (T, error) {
+	pc.mu.Lock()
+	if elem, ok := pc.entries[pattern]; ok {
+		pc.order.MoveToFront(elem)
+		pc.mu.Unlock()
+		return elem.Value.(*patternEntry[T]).value, nil
+	}
+	pc.mu.Unlock()
+
+	value, err := compile(pattern)
+	if err != nil {
+		return value, err
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if _, ok := pc.entries[pattern]; !ok {
+		pc.entries[pattern] = pc.order.PushFront(&patternEntry[T]{pattern: pattern, value: value})
+		for pc.order.Len() > pc.maxEntries {
+			oldest := pc.order.Back()
+			pc.order.Remove(oldest)
+			delete(pc.entries, oldest.Value.(*patternEntry[T]).pattern)
+		}
+	}
+	return value, nil
+}
+
+// len returns the number of cached patterns
+func (pc *patternCache[T]) len() int {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.order.Len()
+}
+
+// compileRegex compiles a pattern once
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	return regexCache.get(pattern, regexp.Compile)
+}
+
+// parseCIDR parses a network once
+func parseCIDR(cidr string) (*net.IPNet, error) {
+	return cidrCache.get(cidr, func(cidr string) (*net.IPNet, error) {
+		_, network, err := net.ParseCIDR(cidr)
+		return network, err
+	})
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"regexp"
+	"testing"
+
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestEvaluateEnvironments(t *testing.T) {
+	vm := models.VM{
+		ID:             "i-1",
+		Name:           "prod-web-01",
+		CloudType:      "aws",
+		CloudAccountID: "123456789012",
+		Location:       "us-east-1",
+		Tags:           map[string]string{"Environment": "prod", "Team": "web"},
+		PrivateIP:      "10.1.2.3",
+		VpcID:          "vpc-1",
+	}
+
+	environments := []models.Environment{
+		{
+			ID:       "aws-east",
+			Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "123456789012", Region: "us-east-1"},
+		},
+		{
+			ID:       "prod-tagged",
+			Priority: 10,
+			Criteria: models.EnvironmentCriteria{
+				CloudType: "aws",
+				Rules:     []models.MatchRule{{Tag: "Environment", Equals: strPtr("prod")}},
+			},
+		},
+		{
+			ID:       "staging-tagged",
+			Priority: 20,
+			Criteria: models.EnvironmentCriteria{
+				Rules: []models.MatchRule{{Tag: "Environment", Equals: strPtr("staging")}},
+			},
+		},
+		{
+			ID:       "azure",
+			Criteria: models.EnvironmentCriteria{CloudType: "azure", Subscription: "sub-1", Location: "eastus"},
+		},
+	}
+
+	candidates := EvaluateEnvironments(environments, vm)
+	require.Len(t, candidates, 4)
+
+	// Explicit priority beats the more specific account/region match
+	assert.Equal(t, "prod-tagged", candidates[0].Environment.ID)
+	assert.True(t, candidates[0].Matched)
+	assert.Equal(t, []string{"cloud_type=aws", "tag Environment=prod"}, candidates[0].Conditions)
+
+	assert.Equal(t, "aws-east", candidates[1].Environment.ID)
+	assert.True(t, candidates[1].Matched)
+	assert.Equal(t, 3, candidates[1].Score)
+
+	assert.Equal(t, "azure", candidates[2].Environment.ID)
+	assert.False(t, candidates[2].Matched)
+	assert.Equal(t, "cloud_type azure does not match aws", candidates[2].Reason)
+
+	assert.Equal(t, "staging-tagged", candidates[3].Environment.ID)
+	assert.Equal(t, "rule 1 (tag Environment=staging) does not match", candidates[3].Reason)
+}
+
+func TestEvaluateEnvironmentsVPC(t *testing.T) {
+	environments := []models.Environment{
+		{ID: "prod0", Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "123456789012", Region: "us-east-1", VPC: "vpc-12345678"}},
+		{ID: "prod1", Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "123456789012", Region: "us-east-1", VPC: "vpc-87654321"}},
+		{ID: "account", Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "123456789012", Region: "us-east-1"}},
+	}
+	vm := models.VM{CloudType: "aws", CloudAccountID: "123456789012", Location: "us-east-1", VpcID: "vpc-87654321"}
+
+	candidates := EvaluateEnvironments(environments, vm)
+	assert.Equal(t, "prod1", candidates[0].Environment.ID)
+	assert.Equal(t, 4, candidates[0].Score)
+	assert.Contains(t, candidates[0].Conditions, "vpc=vpc-87654321")
+	assert.Equal(t, "account", candidates[1].Environment.ID)
+	assert.False(t, candidates[2].Matched)
+	assert.Equal(t, "vpc vpc-12345678 does not match vpc-87654321", candidates[2].Reason)
+
+	// Without network information the VPC neither rejects nor scores
+	vm.VpcID = ""
+	candidates = EvaluateEnvironments(environments, vm)
+	assert.Len(t, TiedCandidates(candidates), 3)
+
+	service := NewEnvironmentService(writeEnvironmentsFile(t, `environments:
+  - id: "prod0"
+    name: "Production 0"
+    criteria: {cloud_type: "aws", account: "123456789012", region: "us-east-1", vpc: "vpc-12345678"}
+  - id: "prod1"
+    name: "Production 1"
+    criteria: {cloud_type: "aws", account: "123456789012", region: "us-east-1", vpc: "vpc-87654321"}
+`))
+	require.NoError(t, service.LoadConfig())
+	env, err := service.ResolveEnvironmentForResource("123456789012", "us-east-1", "vpc-87654321")
+	require.NoError(t, err)
+	assert.Equal(t, "prod1", env.ID)
+}
+
+func TestMatchRule(t *testing.T) {
+	vm := models.VM{
+		Name:          "prod-db-02",
+		Tags:          map[string]string{"Environment": "prod"},
+		PrivateIP:     "10.20.0.5",
+		VpcID:         "vpc-9",
+		ResourceGroup: "RG-Prod",
+	}
+
+	tests := []struct {
+		name  string
+		rule  models.MatchRule
+		match bool
+	}{
+		{"tag exists", models.MatchRule{Tag: "Environment"}, true},
+		{"tag missing", models.MatchRule{Tag: "Owner"}, false},
+		{"tag equals", models.MatchRule{Tag: "Environment", Equals: strPtr("prod")}, true},
+		{"tag differs", models.MatchRule{Tag: "Environment", Equals: strPtr("dev")}, false},
+		{"name glob", models.MatchRule{Name: "prod-*"}, true},
+		{"name glob miss", models.MatchRule{Name: "dev-*"}, false},
+		{"name regex", models.MatchRule{NameRegex: `^prod-db-\d+$`}, true},
+		{"cidr", models.MatchRule{CIDR: "10.20.0.0/16"}, true},
+		{"cidr miss", models.MatchRule{CIDR: "10.30.0.0/16"}, false},
+		{"vpc", models.MatchRule{VPC: "vpc-9"}, true},
+		{"resource group ignores case", models.MatchRule{ResourceGroup: "rg-prod"}, true},
+		{"conditions on one rule are ANDed", models.MatchRule{Name: "prod-*", VPC: "vpc-other"}, false},
+		{
+			"all",
+			models.MatchRule{All: []models.MatchRule{{Name: "prod-*"}, {CIDR: "10.20.0.0/16"}}},
+			true,
+		},
+		{
+			"all with a miss",
+			models.MatchRule{All: []models.MatchRule{{Name: "prod-*"}, {CIDR: "10.30.0.0/16"}}},
+			false,
+		},
+		{
+			"any",
+			models.MatchRule{Any: []models.MatchRule{{Name: "dev-*"}, {VPC: "vpc-9"}}},
+			true,
+		},
+		{
+			"any without a match",
+			models.MatchRule{Any: []models.MatchRule{{Name: "dev-*"}, {VPC: "vpc-1"}}},
+			false,
+		},
+		{"empty rule", models.MatchRule{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := matchRule(tt.rule, vm)
+			assert.Equal(t, tt.match, ok)
+		})
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	env := func(rule models.MatchRule) []models.Environment {
+		return []models.Environment{{
+			ID:       "tagged",
+			Name:     "Tagged",
+			Criteria: models.EnvironmentCriteria{CloudType: "gcp", Rules: []models.MatchRule{rule}},
+		}}
+	}
+
+	assert.NoError(t, ValidateEnvironments(env(models.MatchRule{Tag: "env", Equals: strPtr("prod")})))
+	assert.ErrorContains(t, ValidateEnvironments(env(models.MatchRule{})), "rule has no conditions")
+	assert.ErrorContains(t, ValidateEnvironments(env(models.MatchRule{Equals: strPtr("prod")})), "equals requires tag")
+	assert.ErrorContains(t, ValidateEnvironments(env(models.MatchRule{NameRegex: "("})), "invalid name_regex")
+	assert.ErrorContains(t, ValidateEnvironments(env(models.MatchRule{CIDR: "10.0.0.0/33"})), "invalid cidr")
+	assert.ErrorContains(t, ValidateEnvironments(env(models.MatchRule{Any: []models.MatchRule{{Name: "["}}})), "invalid name glob")
+}
+
+func TestResolveEnvironmentForVMRulesFromYAML(t *testing.T) {
+	service := NewEnvironmentService(writeEnvironmentsFile(t, `environments:
+  - id: "aws-account"
+    name: "AWS account"
+    criteria: {cloud_type: "aws", account: "123456789012", region: "us-east-1"}
+  - id: "prod"
+    name: "Production by tag or subnet"
+    priority: 100
+    criteria:
+      cloud_type: "aws"
+      rules:
+        - any:
+            - {tag: "Environment", equals: "prod"}
+            - {cidr: "10.1.0.0/16"}
+`))
+	require.NoError(t, service.LoadConfig())
+
+	base := models.VM{CloudType: "aws", CloudAccountID: "123456789012", Location: "us-east-1"}
+
+	env, err := service.ResolveEnvironmentForVM(base)
+	require.NoError(t, err)
+	assert.Equal(t, "aws-account", env.ID)
+
+	bySubnet := base
+	bySubnet.PrivateIP = "10.1.9.9"
+	env, err = service.ResolveEnvironmentForVM(bySubnet)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", env.ID)
+
+	byTag := base
+	byTag.Location = "eu-west-1"
+	byTag.Tags = map[string]string{"Environment": "prod"}
+	env, err = service.ResolveEnvironmentForVM(byTag)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", env.ID)
+
+	_, err = service.ResolveEnvironmentForVM(models.VM{CloudType: "gcp", CloudAccountID: "p", Location: "x"})
+	assert.Error(t, err)
+}
+
+func TestExplainResolution(t *testing.T) {
+	environments := []models.Environment{
+		{ID: "account", Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "123456789012"}},
+		{ID: "region", Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "123456789012", Region: "us-east-1"}},
+		{ID: "region-copy", Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "123456789012", Region: "us-east-1"}},
+		{ID: "pinned", Priority: -1, Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "123456789012", Region: "us-east-1", VPC: "vpc-1"}},
+		{ID: "gcp", Criteria: models.EnvironmentCriteria{CloudType: "gcp", Project: "p", Zone: "us-central1"}},
+	}
+	vm := models.VM{CloudType: "aws", CloudAccountID: "123456789012", Location: "us-east-1", VpcID: "vpc-1"}
+
+	resolution := ExplainResolution(environments, vm)
+	require.NotNil(t, resolution.Winner)
+	assert.Equal(t, "region", resolution.Winner.Environment.ID)
+	assert.True(t, resolution.Tied)
+
+	reasons := make(map[string]string)
+	for _, candidate := range resolution.Candidates {
+		reasons[candidate.Environment.ID] = candidate.Reason
+	}
+	assert.Empty(t, reasons["region"])
+	assert.Equal(t, "tied with region on priority and score; region wins by ID order", reasons["region-copy"])
+	assert.Equal(t, "matched 2 conditions, fewer than region's 3", reasons["account"])
+	assert.Equal(t, "priority -1 is lower than region's 0", reasons["pinned"])
+	assert.Equal(t, "cloud_type gcp does not match aws", reasons["gcp"])
+
+	resolution = ExplainResolution(environments, models.VM{CloudType: "azure", CloudAccountID: "sub"})
+	assert.Nil(t, resolution.Winner)
+	assert.Len(t, resolution.Candidates, 5)
+}
+
+func TestPatternCacheIsBounded(t *testing.T) {
+	cache := newPatternCache[*regexp.Regexp](2)
+
+	for _, pattern := range []string{"^a", "^b", "^a", "^c"} {
+		_, err := cache.get(pattern, regexp.Compile)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, cache.len())
+
+	// "^b" was least recently used and evicted; "^a" was touched again and kept
+	assert.Contains(t, cache.entries, "^a")
+	assert.NotContains(t, cache.entries, "^b")
+
+	_, err := cache.get("(", regexp.Compile)
+	assert.Error(t, err)
+	assert.Equal(t, 2, cache.len())
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy maps application roles to the routes they may call, and callers to the data
+// they may see (see DataAccess). Each permission is "METHOD /path", where METHOD may be
+// "*" and the path is matched against the route template (e.g. /api/v1/environments/:id)
+// segment by segment: "*" matches any one segment and a final "**" matches any remaining
+// segments, including none.
+type Policy struct {
+	Roles      map[string][]string `yaml:"roles"`
+	DataAccess DataAccess          `yaml:"data_access"`
+
+	permissions map[string][]permission
+}
+
+// permission is a parsed policy entry
+type permission struct {
+	method   string
+	segments []string
+}
+
+// knownMethods lists the methods a permission may name besides "*"
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// LoadPolicy reads and parses an authorization policy file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorization policy: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// ParsePolicy parses an authorization policy from its YAML representation
+func ParsePolicy(data []byte) (*Policy, error) {
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse authorization policy YAML: %w", err)
+	}
+	if len(policy.Roles) == 0 {
+		return nil, fmt.Errorf("authorization policy defines no roles")
+	}
+
+	policy.permissions = make(map[string][]permission, len(policy.Roles))
+	for role, entries := range policy.Roles {
+		for _, entry := range entries {
+			perm, err := parsePermission(entry)
+			if err != nil {
+				return nil, fmt.Errorf("role '%s': %w", role, err)
+			}
+			policy.permissions[role] = append(policy.permissions[role], perm)
+		}
+	}
+	if err := validateDataAccess(policy.DataAccess); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// parsePermission parses a "METHOD /path" entry
+func parsePermission(entry string) (permission, error) {
+	fields := strings.Fields(entry)
+	if len(fields) != 2 {
+		return permission{}, fmt.Errorf("invalid permission %q: expected \"METHOD /path\"", entry)
+	}
+
+	method := strings.ToUpper(fields[0])
+	if method != "*" && !knownMethods[method] {
+		return permission{}, fmt.Errorf("invalid permission %q: unknown method %s", entry, fields[0])
+	}
+	if !strings.HasPrefix(fields[1], "/") {
+		return permission{}, fmt.Errorf("invalid permission %q: path must start with /", entry)
+	}
+
+	segments := strings.Split(strings.Trim(fields[1], "/"), "/")
+	for i, segment := range segments {
+		if segment == "**" && i != len(segments)-1 {
+			return permission{}, fmt.Errorf("invalid permission %q: ** must be the last segment", entry)
+		}
+	}
+	return permission{method: method, segments: segments}, nil
+}
+
+// matches reports whether the permission covers a request to a route
+func (p permission) matches(method string, route []string) bool {
+	if p.method != "*" && p.method != method {
+		return false
+	}
+	for i, segment := range p.segments {
+		if segment == "**" {
+			return true
+		}
+		if i >= len(route) || (segment != "*" && segment != route[i]) {
+			return false
+		}
+	}
+	return len(p.segments) == len(route)
+}
+
+// Allows reports whether any of the roles may call method on the route template
+func (p *Policy) Allows(roles []string, method, route string) bool {
+	segments := strings.Split(strings.Trim(route, "/"), "/")
+	for _, role := range roles {
+		for _, perm := range p.permissions[role] {
+			if perm.matches(method, segments) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RolesFor returns the roles allowed to call method on the route template, sorted
+func (p *Policy) RolesFor(method, route string) []string {
+	var roles []string
+	for role := range p.permissions {
+		if p.Allows([]string{role}, method, route) {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
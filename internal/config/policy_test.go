@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyAllows(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`roles:
+  Reader:
+    - GET /api/v1/vms
+    - get /api/v1/environments/**
+    - POST /api/v1/environments/resolve
+  Editor:
+    - "* /api/v1/environments/:id"
+  Admin:
+    - "* /api/v1/**"
+`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		roles  []string
+		method string
+		route  string
+		allow  bool
+	}{
+		{[]string{"Reader"}, "GET", "/api/v1/vms", true},
+		{[]string{"Reader"}, "GET", "/api/v1/vms/extra", false},
+		{[]string{"Reader"}, "GET", "/api/v1/environments", true},
+		{[]string{"Reader"}, "GET", "/api/v1/environments/:id/children", true},
+		{[]string{"Reader"}, "POST", "/api/v1/environments/reload", false},
+		{[]string{"Reader"}, "POST", "/api/v1/environments/resolve", true},
+		{[]string{"Editor"}, "DELETE", "/api/v1/environments/:id", true},
+		{[]string{"Editor"}, "DELETE", "/api/v1/environments/reload", false},
+		{[]string{"Reader", "Admin"}, "POST", "/api/v1/admin/cache/invalidate", true},
+		{[]string{"Unknown"}, "GET", "/api/v1/vms", false},
+		{nil, "GET", "/api/v1/vms", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allow, policy.Allows(tt.roles, tt.method, tt.route), "%v %s %s", tt.roles, tt.method, tt.route)
+	}
+
+	assert.Equal(t, []string{"Admin", "Editor"}, policy.RolesFor("PUT", "/api/v1/environments/:id"))
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+	for data, message := range map[string]string{
+		`roles: {}`:                                    "defines no roles",
+		`roles: {Admin: ["/api/v1/**"]}`:               `expected "METHOD /path"`,
+		`roles: {Admin: ["FETCH /api/v1/vms"]}`:        "unknown method FETCH",
+		`roles: {Admin: ["GET api/v1/vms"]}`:           "path must start with /",
+		`roles: {Admin: ["GET /api/**/environments"]}`: "** must be the last segment",
+	} {
+		_, err := ParsePolicy([]byte(data))
+		assert.ErrorContains(t, err, message, data)
+	}
+}
+
+func TestShippedPolicy(t *testing.T) {
+	policy, err := LoadPolicy("../../config/policy.yaml")
+	require.NoError(t, err)
+	assert.True(t, policy.Allows([]string{"Atlas.Reader"}, "GET", "/api/v1/environments/:id"))
+	assert.False(t, policy.Allows([]string{"Atlas.Reader"}, "POST", "/api/v1/environments/reload"))
+	assert.True(t, policy.Allows([]string{"Atlas.Admin"}, "POST", "/api/v1/environments/reload"))
+}
@@ -0,0 +1,262 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang-service/internal/models"
+)
+
+// DataAccess limits which VMs and environments callers see. Each rule grants the callers
+// it applies to (by app role, group or object ID) environments, cloud accounts and tag
+// values; a caller sees the union of the rules that apply. Callers holding a bypass role
+// see everything, as does everyone when no rules are configured.
+type DataAccess struct {
+	BypassRoles []string         `yaml:"bypass_roles"`
+	Rules       []DataAccessRule `yaml:"rules"`
+}
+
+// DataAccessRule grants a set of callers access to part of the inventory
+type DataAccessRule struct {
+	// Callers the rule applies to; any match suffices. Object IDs are only matched for
+	// tokens from Issuer, an issuer namespace (see the issuers' namespace).
+	Roles     []string `yaml:"roles"`
+	Groups    []string `yaml:"groups"`
+	ObjectIDs []string `yaml:"object_ids"`
+	Issuer    string   `yaml:"issuer"`
+	// What they may see: environments (including their descendants), cloud accounts
+	// (AWS account, Azure subscription or GCP project) and tag values
+	Environments []string          `yaml:"environments"`
+	Accounts     []string          `yaml:"accounts"`
+	Tags         map[string]string `yaml:"tags"`
+}
+
+// Caller identifies who is making a request, as taken from their token. ObjectID is
+// qualified by Namespace, the identity namespace of the token's issuer.
+type Caller struct {
+	Roles     []string
+	Groups    []string
+	ObjectID  string
+	Namespace string
+}
+
+// Scope is the part of the inventory a caller may see. A nil *Scope is unrestricted.
+type Scope struct {
+	Environments []string            `json:"environments"`
+	Accounts     []string            `json:"accounts"`
+	Tags         map[string][]string `json:"tags"`
+}
+
+// validateDataAccess checks that every rule names callers and grants something
+func validateDataAccess(access DataAccess) error {
+	for i, rule := range access.Rules {
+		if len(rule.Roles) == 0 && len(rule.Groups) == 0 && len(rule.ObjectIDs) == 0 {
+			return fmt.Errorf("data_access rule %d applies to no roles, groups or object_ids", i+1)
+		}
+		if len(rule.ObjectIDs) > 0 && rule.Issuer == "" {
+			return fmt.Errorf("data_access rule %d lists object_ids without the issuer they belong to", i+1)
+		}
+		if len(rule.Environments) == 0 && len(rule.Accounts) == 0 && len(rule.Tags) == 0 {
+			return fmt.Errorf("data_access rule %d grants no environments, accounts or tags", i+1)
+		}
+	}
+	return nil
+}
+
+// Bypasses reports whether roles include one of the bypass roles
+func (p *Policy) Bypasses(roles []string) bool {
+	return intersects(roles, p.DataAccess.BypassRoles)
+}
+
+// ScopeFor returns the data a caller may see, or nil when the caller is unrestricted
+func (p *Policy) ScopeFor(caller Caller) *Scope {
+	access := p.DataAccess
+	if len(access.Rules) == 0 || p.Bypasses(caller.Roles) {
+		return nil
+	}
+
+	scope := &Scope{Environments: []string{}, Accounts: []string{}, Tags: map[string][]string{}}
+	for _, rule := range access.Rules {
+		applies := intersects(caller.Roles, rule.Roles) ||
+			intersects(caller.Groups, rule.Groups) ||
+			(caller.ObjectID != "" && caller.Namespace == rule.Issuer && contains(rule.ObjectIDs, caller.ObjectID))
+		if !applies {
+			continue
+		}
+		scope.Environments = appendMissing(scope.Environments, rule.Environments...)
+		scope.Accounts = appendMissing(scope.Accounts, rule.Accounts...)
+		for key, value := range rule.Tags {
+			scope.Tags[key] = appendMissing(scope.Tags[key], value)
+		}
+	}
+
+	sort.Strings(scope.Environments)
+	sort.Strings(scope.Accounts)
+	for key := range scope.Tags {
+		sort.Strings(scope.Tags[key])
+	}
+	return scope
+}
+
+// AllowsVM reports whether a VM is in scope: its environment or one of that environment's
+// ancestors is granted, its account is granted, or it carries a granted tag value
+func (s *Scope) AllowsVM(vm models.VM) bool {
+	if s == nil {
+		return true
+	}
+	if vm.Env != "" && contains(s.Environments, vm.Env) {
+		return true
+	}
+	if vm.Environment != nil && intersects(vm.Environment.Ancestors, s.Environments) {
+		return true
+	}
+	if contains(s.Accounts, vm.CloudAccountID) {
+		return true
+	}
+	for key, values := range s.Tags {
+		if value, ok := vm.Tags[key]; ok && contains(values, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsEnvironment reports whether an environment is in scope: it or one of its ancestors
+// is granted, or its criteria name a granted account. Tag grants expose VMs only.
+func (s *Scope) AllowsEnvironment(env models.Environment) bool {
+	if s == nil {
+		return true
+	}
+	if contains(s.Environments, env.ID) || intersects(env.Ancestors, s.Environments) {
+		return true
+	}
+	for _, account := range []string{env.Criteria.Account, env.Criteria.Subscription, env.Criteria.Project} {
+		if account != "" && contains(s.Accounts, account) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterVMs returns the VMs in scope
+func (s *Scope) FilterVMs(vms []models.VM) []models.VM {
+	if s == nil {
+		return vms
+	}
+	allowed := make([]models.VM, 0, len(vms))
+	for _, vm := range vms {
+		if s.AllowsVM(vm) {
+			allowed = append(allowed, vm)
+		}
+	}
+	return allowed
+}
+
+// FilterEnvironments returns the environments in scope
+func (s *Scope) FilterEnvironments(environments []models.Environment) []models.Environment {
+	if s == nil {
+		return environments
+	}
+	allowed := make([]models.Environment, 0, len(environments))
+	for _, env := range environments {
+		if s.AllowsEnvironment(env) {
+			allowed = append(allowed, env)
+		}
+	}
+	return allowed
+}
+
+// FilterResolution hides the candidates outside the scope. When the winner is one of them,
+// the resolution is withheld and the visible matches are told they lost to an environment
+// outside the scope rather than which.
+func (s *Scope) FilterResolution(resolution Resolution) Resolution {
+	if s == nil {
+		return resolution
+	}
+	candidates := make([]Candidate, 0, len(resolution.Candidates))
+	for _, candidate := range resolution.Candidates {
+		if s.AllowsEnvironment(candidate.Environment) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	if resolution.Winner != nil && !s.AllowsEnvironment(resolution.Winner.Environment) {
+		resolution.Winner = nil
+		resolution.Tied = false
+		for i := range candidates {
+			if candidates[i].Matched {
+				candidates[i].Reason = "lost to an environment outside the caller's scope"
+			}
+		}
+	}
+	resolution.Candidates = candidates
+	return resolution
+}
+
+// FilterAmbiguities limits each ambiguity to the tied environments in scope, given the
+// configured environments, and drops those between environments outside it
+func (s *Scope) FilterAmbiguities(ambiguities []Ambiguity, environments []models.Environment) []Ambiguity {
+	if s == nil {
+		return ambiguities
+	}
+	visible := map[string]bool{}
+	for _, env := range s.FilterEnvironments(environments) {
+		visible[env.ID] = true
+	}
+
+	allowed := make([]Ambiguity, 0, len(ambiguities))
+	for _, ambiguity := range ambiguities {
+		ids := make([]string, 0, len(ambiguity.Environments))
+		for _, id := range ambiguity.Environments {
+			if visible[id] {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) > 0 {
+			ambiguity.Environments = ids
+			allowed = append(allowed, ambiguity)
+		}
+	}
+	return allowed
+}
+
+// Key identifies the scope's grants, so that results computed for one caller can be
+// shared with others seeing the same data. The unrestricted scope has an empty key.
+func (s *Scope) Key() string {
+	if s == nil {
+		return ""
+	}
+	tags := make([]string, 0, len(s.Tags))
+	for key, values := range s.Tags {
+		for _, value := range values {
+			tags = append(tags, key+"="+value)
+		}
+	}
+	sort.Strings(tags)
+
+	normalized := strings.Join(s.Environments, ",") + "#" + strings.Join(s.Accounts, ",") + "#" + strings.Join(tags, ",")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:8])
+}
+
+// intersects reports whether the lists share an element
+func intersects(a, b []string) bool {
+	for _, item := range a {
+		if contains(b, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendMissing appends the items not already in list
+func appendMissing(list []string, items ...string) []string {
+	for _, item := range items {
+		if !contains(list, item) {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"testing"
+
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const scopedPolicyYAML = `roles:
+  Atlas.Reader: ["GET /api/v1/**"]
+data_access:
+  bypass_roles: [Atlas.Admin]
+  rules:
+    - roles: [Team.Payments]
+      environments: [prod]
+    - groups: [group-web]
+      accounts: ["222"]
+    - object_ids: [user-oid]
+      issuer: entra:tenant
+      tags: {Team: web}
+`
+
+func TestScopeFor(t *testing.T) {
+	policy, err := ParsePolicy([]byte(scopedPolicyYAML))
+	require.NoError(t, err)
+
+	assert.Nil(t, policy.ScopeFor(Caller{Roles: []string{"Atlas.Admin", "Team.Payments"}}))
+
+	scope := policy.ScopeFor(Caller{Roles: []string{"Team.Payments"}, Groups: []string{"group-web"}, ObjectID: "user-oid", Namespace: "entra:tenant"})
+	require.NotNil(t, scope)
+	assert.Equal(t, []string{"prod"}, scope.Environments)
+	assert.Equal(t, []string{"222"}, scope.Accounts)
+	assert.Equal(t, map[string][]string{"Team": {"web"}}, scope.Tags)
+
+	// Callers no rule applies to see nothing
+	none := policy.ScopeFor(Caller{Roles: []string{"Atlas.Reader"}})
+	require.NotNil(t, none)
+	assert.False(t, none.AllowsVM(models.VM{Env: "prod"}))
+	assert.NotEqual(t, none.Key(), scope.Key())
+	assert.Equal(t, scope.Key(), policy.ScopeFor(Caller{Roles: []string{"Team.Payments"}, Groups: []string{"group-web"}, ObjectID: "user-oid", Namespace: "entra:tenant"}).Key())
+
+	// The same object ID from another issuer is someone else
+	other := policy.ScopeFor(Caller{ObjectID: "user-oid", Namespace: "https://idp.example.com"})
+	require.NotNil(t, other)
+	assert.Empty(t, other.Tags)
+
+	// Without rules everyone is unrestricted
+	open, err := ParsePolicy([]byte(`roles: {Atlas.Reader: ["GET /api/v1/**"]}`))
+	require.NoError(t, err)
+	assert.Nil(t, open.ScopeFor(Caller{}))
+
+	_, err = ParsePolicy([]byte(scopedPolicyYAML + "    - roles: [Team.Empty]\n"))
+	assert.ErrorContains(t, err, "grants no environments, accounts or tags")
+	_, err = ParsePolicy([]byte(scopedPolicyYAML + "    - accounts: [\"333\"]\n"))
+	assert.ErrorContains(t, err, "applies to no roles, groups or object_ids")
+	_, err = ParsePolicy([]byte(scopedPolicyYAML + "    - object_ids: [oid]\n      accounts: [\"333\"]\n"))
+	assert.ErrorContains(t, err, "object_ids without the issuer")
+}
+
+func TestScopeAllows(t *testing.T) {
+	scope := &Scope{Environments: []string{"prod"}, Accounts: []string{"222"}, Tags: map[string][]string{"Team": {"web"}}}
+
+	vms := []models.VM{
+		{ID: "env", Env: "prod"},
+		{ID: "child", Env: "prod-us", Environment: &models.EnvironmentInfo{ID: "prod-us", Ancestors: []string{"prod"}}},
+		{ID: "account", Env: "dev", CloudAccountID: "222"},
+		{ID: "tag", Tags: map[string]string{"Team": "web"}},
+		{ID: "other-tag", Tags: map[string]string{"Team": "db"}},
+		{ID: "other", Env: "dev", CloudAccountID: "111"},
+	}
+	var allowed []string
+	for _, vm := range scope.FilterVMs(vms) {
+		allowed = append(allowed, vm.ID)
+	}
+	assert.Equal(t, []string{"env", "child", "account", "tag"}, allowed)
+	assert.Len(t, (*Scope)(nil).FilterVMs(vms), len(vms))
+
+	assert.True(t, scope.AllowsEnvironment(models.Environment{ID: "prod"}))
+	assert.True(t, scope.AllowsEnvironment(models.Environment{ID: "prod-us", Ancestors: []string{"prod"}}))
+	assert.True(t, scope.AllowsEnvironment(models.Environment{ID: "gcp", Criteria: models.EnvironmentCriteria{Project: "222"}}))
+	assert.False(t, scope.AllowsEnvironment(models.Environment{ID: "dev", Criteria: models.EnvironmentCriteria{Account: "111"}}))
+}
+
+func TestScopeFiltersResolutionsAndAmbiguities(t *testing.T) {
+	scope := &Scope{Environments: []string{"prod"}}
+	prod := models.Environment{ID: "prod", Priority: 1, Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "111"}}
+	shared := models.Environment{ID: "shared", Priority: 5, Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "111"}}
+	vm := models.VM{CloudType: "aws", CloudAccountID: "111"}
+
+	t.Run("A winner outside the scope is withheld", func(t *testing.T) {
+		resolution := scope.FilterResolution(ExplainResolution([]models.Environment{prod, shared}, vm))
+		assert.Nil(t, resolution.Winner)
+		require.Len(t, resolution.Candidates, 1)
+		assert.Equal(t, "prod", resolution.Candidates[0].Environment.ID)
+		assert.NotContains(t, resolution.Candidates[0].Reason, "shared")
+	})
+
+	t.Run("A winner in scope is kept", func(t *testing.T) {
+		shared.Priority = 0
+		resolution := scope.FilterResolution(ExplainResolution([]models.Environment{prod, shared}, vm))
+		require.NotNil(t, resolution.Winner)
+		assert.Equal(t, "prod", resolution.Winner.Environment.ID)
+		assert.Len(t, resolution.Candidates, 1)
+	})
+
+	t.Run("Ambiguities only name environments in scope", func(t *testing.T) {
+		ambiguities := []Ambiguity{
+			{Environments: []string{"prod", "shared"}, VMCount: 2},
+			{Environments: []string{"shared", "other"}, VMCount: 1},
+		}
+		environments := []models.Environment{prod, shared, {ID: "other"}}
+
+		filtered := scope.FilterAmbiguities(ambiguities, environments)
+		require.Len(t, filtered, 1)
+		assert.Equal(t, []string{"prod"}, filtered[0].Environments)
+		assert.Len(t, (*Scope)(nil).FilterAmbiguities(ambiguities, environments), 2)
+	})
+}
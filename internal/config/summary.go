@@ -0,0 +1,126 @@
+package config
+
+import (
+	"sort"
+	"time"
+
+	"golang-service/internal/models"
+)
+
+// maxTopTags is the number of tags listed in an environment summary
+const maxTopTags = 10
+
+// EnvironmentSummary describes the VMs resolved to one environment
+type EnvironmentSummary struct {
+	VMCount        int            `json:"vmCount"`
+	ByStatus       map[string]int `json:"byStatus"`
+	ByCloud        map[string]int `json:"byCloud"`
+	ByInstanceType map[string]int `json:"byInstanceType"`
+	// TotalVCPUs and TotalMemoryMiB only add up the VMs whose capacity is known;
+	// VCPUsKnown and MemoryKnown count those VMs
+	TotalVCPUs     int        `json:"totalVcpus"`
+	VCPUsKnown     int        `json:"vcpusKnown"`
+	TotalMemoryMiB int        `json:"totalMemoryMib"`
+	MemoryKnown    int        `json:"memoryKnown"`
+	LastSyncTime   *time.Time `json:"lastSyncTime,omitempty"`
+	TopTags        []TagCount `json:"topTags"`
+}
+
+// TagCount is the number of VMs carrying a tag with a given value
+type TagCount struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	VMCount int    `json:"vmCount"`
+}
+
+// newEnvironmentSummary returns an empty summary
+func newEnvironmentSummary() *EnvironmentSummary {
+	return &EnvironmentSummary{
+		ByStatus:       map[string]int{},
+		ByCloud:        map[string]int{},
+		ByInstanceType: map[string]int{},
+		TopTags:        []TagCount{},
+	}
+}
+
+// SummarizeEnvironments summarizes VMs by the environment they resolved to. VMs without
+// an environment are left out.
+func SummarizeEnvironments(vms []models.VM) map[string]*EnvironmentSummary {
+	summaries := make(map[string]*EnvironmentSummary)
+	tags := make(map[string]map[TagCount]int)
+
+	for _, vm := range vms {
+		if vm.Env == "" {
+			continue
+		}
+		summary, ok := summaries[vm.Env]
+		if !ok {
+			summary = newEnvironmentSummary()
+			summaries[vm.Env] = summary
+			tags[vm.Env] = make(map[TagCount]int)
+		}
+
+		summary.VMCount++
+		summary.ByStatus[vm.Status]++
+		summary.ByCloud[vm.CloudType]++
+		if vm.InstanceType != "" {
+			summary.ByInstanceType[vm.InstanceType]++
+		}
+		if vm.VCPUs > 0 {
+			summary.TotalVCPUs += vm.VCPUs
+			summary.VCPUsKnown++
+		}
+		if vm.MemoryMiB > 0 {
+			summary.TotalMemoryMiB += vm.MemoryMiB
+			summary.MemoryKnown++
+		}
+		if !vm.SyncTime.IsZero() && (summary.LastSyncTime == nil || vm.SyncTime.After(*summary.LastSyncTime)) {
+			syncTime := vm.SyncTime
+			summary.LastSyncTime = &syncTime
+		}
+		for key, value := range vm.Tags {
+			tags[vm.Env][TagCount{Key: key, Value: value}]++
+		}
+	}
+
+	for env, counts := range tags {
+		summaries[env].TopTags = topTags(counts)
+	}
+	return summaries
+}
+
+// SummarizeEnvironment summarizes the VMs resolved to one environment
+func SummarizeEnvironment(vms []models.VM, id string) *EnvironmentSummary {
+	var matching []models.VM
+	for _, vm := range vms {
+		if vm.Env == id {
+			matching = append(matching, vm)
+		}
+	}
+	if summary, ok := SummarizeEnvironments(matching)[id]; ok {
+		return summary
+	}
+	return newEnvironmentSummary()
+}
+
+// topTags returns the most common tag values, most VMs first
+func topTags(counts map[TagCount]int) []TagCount {
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tag.VMCount = count
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].VMCount != tags[j].VMCount {
+			return tags[i].VMCount > tags[j].VMCount
+		}
+		if tags[i].Key != tags[j].Key {
+			return tags[i].Key < tags[j].Key
+		}
+		return tags[i].Value < tags[j].Value
+	})
+	if len(tags) > maxTopTags {
+		tags = tags[:maxTopTags]
+	}
+	return tags
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeEnvironments(t *testing.T) {
+	synced := time.Date(2025, 7, 7, 16, 0, 0, 0, time.UTC)
+	vms := []models.VM{
+		{ID: "1", Env: "prod", CloudType: "aws", Status: "running", InstanceType: "m5.large", VCPUs: 2,
+			Tags: map[string]string{"Team": "web"}, SyncTime: synced},
+		{ID: "2", Env: "prod", CloudType: "aws", Status: "stopped", InstanceType: "m5.large", VCPUs: 2,
+			Tags: map[string]string{"Team": "web", "Owner": "alice"}, SyncTime: synced.Add(time.Hour)},
+		{ID: "3", Env: "prod", CloudType: "gcp", Status: "RUNNING", VCPUs: 4, MemoryMiB: 16384},
+		{ID: "4", Env: "dev", CloudType: "aws", Status: "running"},
+		{ID: "5", CloudType: "aws", Status: "running"},
+	}
+
+	summaries := SummarizeEnvironments(vms)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, 1, summaries["dev"].VMCount)
+
+	prod := summaries["prod"]
+	assert.Equal(t, 3, prod.VMCount)
+	assert.Equal(t, map[string]int{"running": 1, "stopped": 1, "RUNNING": 1}, prod.ByStatus)
+	assert.Equal(t, map[string]int{"aws": 2, "gcp": 1}, prod.ByCloud)
+	assert.Equal(t, map[string]int{"m5.large": 2}, prod.ByInstanceType)
+	assert.Equal(t, 8, prod.TotalVCPUs)
+	assert.Equal(t, 3, prod.VCPUsKnown)
+	assert.Equal(t, 16384, prod.TotalMemoryMiB)
+	assert.Equal(t, 1, prod.MemoryKnown)
+	require.NotNil(t, prod.LastSyncTime)
+	assert.Equal(t, synced.Add(time.Hour), *prod.LastSyncTime)
+	assert.Equal(t, []TagCount{
+		{Key: "Team", Value: "web", VMCount: 2},
+		{Key: "Owner", Value: "alice", VMCount: 1},
+	}, prod.TopTags)
+
+	empty := SummarizeEnvironment(vms, "staging")
+	assert.Equal(t, 0, empty.VMCount)
+	assert.Nil(t, empty.LastSyncTime)
+	assert.Empty(t, empty.TopTags)
+	assert.Equal(t, prod, SummarizeEnvironment(vms, "prod"))
+}
@@ -0,0 +1,430 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"golang-service/internal/models"
+)
+
+// Validation issue severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue is one problem found in an environments configuration
+type ValidationIssue struct {
+	Severity    string `json:"severity"`
+	Environment string `json:"environment,omitempty"`
+	Field       string `json:"field,omitempty"`
+	Message     string `json:"message"`
+}
+
+// ValidationReport is the result of validating an environments configuration.
+// Errors make the configuration invalid; warnings point at likely mistakes.
+type ValidationReport struct {
+	Valid        bool              `json:"valid"`
+	Environments int               `json:"environments"`
+	Errors       []ValidationIssue `json:"errors"`
+	Warnings     []ValidationIssue `json:"warnings"`
+}
+
+// Known YAML keys per level of the configuration document
+var (
+	knownRootKeys        = []string{"environments"}
+	knownEnvironmentKeys = []string{"id", "name", "description", "parent", "criteria", "tags", "metadata", "priority"}
+	knownCriteriaKeys    = []string{"cloud_type", "account", "region", "vpc", "subscription", "location", "project", "zone", "rules"}
+	knownRuleKeys        = []string{"tag", "equals", "name", "name_regex", "cidr", "vpc", "resource_group", "all", "any"}
+)
+
+// applicableCriteria lists the provider criteria that apply to each cloud type
+var applicableCriteria = map[string][]string{
+	"aws":   {"account", "region", "vpc"},
+	"azure": {"subscription", "location", "vpc"},
+	"gcp":   {"project", "zone", "vpc"},
+}
+
+// criteriaFormats describes the expected format of provider criteria
+var criteriaFormats = map[string]map[string]struct {
+	pattern *regexp.Regexp
+	example string
+}{
+	"aws": {
+		"account": {regexp.MustCompile(`^\d{12}$`), "123456789012"},
+		"region":  {regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-\d$`), "us-east-1"},
+		"vpc":     {regexp.MustCompile(`^vpc-[0-9a-f]{8}([0-9a-f]{9})?$`), "vpc-12345678"},
+	},
+	"azure": {
+		"location": {regexp.MustCompile(`^[a-z]+[a-z0-9]*$`), "eastus"},
+	},
+	"gcp": {
+		"project": {regexp.MustCompile(`^[a-z][a-z0-9-]{4,28}[a-z0-9]$`), "my-project-123"},
+		"zone":    {regexp.MustCompile(`^[a-z]+-[a-z]+\d+(-[a-z])?$`), "us-central1 or us-central1-a"},
+	},
+}
+
+// ValidateEnvironmentConfig validates an environments YAML document. Beyond what loading
+// requires, it flags unknown keys, provider criteria in the wrong format or not applicable
+// to the cloud type, and environments that overlap or shadow each other.
+func ValidateEnvironmentConfig(data []byte) ValidationReport {
+	report := ValidationReport{Errors: []ValidationIssue{}, Warnings: []ValidationIssue{}}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		report.add(ValidationIssue{Severity: SeverityError, Message: fmt.Sprintf("invalid YAML: %v", err)})
+		return report
+	}
+
+	var config models.EnvironmentConfig
+	if err := root.Decode(&config); err != nil {
+		report.add(ValidationIssue{Severity: SeverityError, Message: fmt.Sprintf("invalid environment configuration: %v", err)})
+		return report
+	}
+	report.Environments = len(config.Environments)
+
+	for _, issue := range unknownKeys(&root) {
+		report.add(issue)
+	}
+	for _, issue := range ValidateEnvironmentSet(config.Environments) {
+		report.add(issue)
+	}
+
+	report.Valid = len(report.Errors) == 0
+	return report
+}
+
+// ValidateEnvironmentSet runs every check that does not need the YAML document itself
+func ValidateEnvironmentSet(environments []models.Environment) []ValidationIssue {
+	issues := structuralIssues(environments)
+	effective := InheritEnvironments(environments)
+	for i, env := range environments {
+		issues = append(issues, criteriaIssues(env, effective[i].Criteria.CloudType)...)
+	}
+	return append(issues, overlapIssues(effective)...)
+}
+
+// add files an issue under errors or warnings
+func (r *ValidationReport) add(issue ValidationIssue) {
+	if issue.Severity == SeverityError {
+		r.Errors = append(r.Errors, issue)
+	} else {
+		r.Warnings = append(r.Warnings, issue)
+	}
+}
+
+// unknownKeys walks the document and reports keys the configuration does not define
+func unknownKeys(root *yaml.Node) []ValidationIssue {
+	var issues []ValidationIssue
+	if len(root.Content) == 0 {
+		return nil
+	}
+
+	check := func(node *yaml.Node, known []string, env, path string) {
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if contains(known, key) {
+				continue
+			}
+			message := fmt.Sprintf("unknown key %q", key)
+			if suggestion := closestKey(key, known); suggestion != "" {
+				message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+			}
+			issues = append(issues, ValidationIssue{
+				Severity:    SeverityError,
+				Environment: env,
+				Field:       strings.TrimPrefix(path+"."+key, "."),
+				Message:     message,
+			})
+		}
+	}
+
+	var checkRules func(rules *yaml.Node, env, path string)
+	checkRules = func(rules *yaml.Node, env, path string) {
+		if rules == nil || rules.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, rule := range rules.Content {
+			rulePath := fmt.Sprintf("%s[%d]", path, i)
+			check(rule, knownRuleKeys, env, rulePath)
+			checkRules(mappingValue(rule, "all"), env, rulePath+".all")
+			checkRules(mappingValue(rule, "any"), env, rulePath+".any")
+		}
+	}
+
+	document := root.Content[0]
+	check(document, knownRootKeys, "", "")
+
+	environments := mappingValue(document, "environments")
+	if environments == nil || environments.Kind != yaml.SequenceNode {
+		return issues
+	}
+	for i, envNode := range environments.Content {
+		env := ""
+		if id := mappingValue(envNode, "id"); id != nil {
+			env = id.Value
+		}
+		path := fmt.Sprintf("environments[%d]", i)
+		check(envNode, knownEnvironmentKeys, env, path)
+
+		criteria := mappingValue(envNode, "criteria")
+		if criteria == nil {
+			continue
+		}
+		check(criteria, knownCriteriaKeys, env, path+".criteria")
+		checkRules(mappingValue(criteria, "rules"), env, path+".criteria.rules")
+	}
+	return issues
+}
+
+// mappingValue returns the value node of a key in a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// closestKey suggests the known key within two edits of key, if any
+func closestKey(key string, known []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range known {
+		if d := editDistance(key, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// criteriaIssues checks the provider criteria an environment sets against its cloud type,
+// which may be inherited
+func criteriaIssues(env models.Environment, cloudType string) []ValidationIssue {
+	applicable, ok := applicableCriteria[cloudType]
+	if !ok {
+		// An unset cloud type matches any provider; an unsupported one is a structural error
+		return nil
+	}
+
+	var issues []ValidationIssue
+	keys := []string{"account", "region", "vpc", "subscription", "location", "project", "zone"}
+	for _, key := range keys {
+		value := criteriaValue(env.Criteria, key)
+		if value == "" {
+			continue
+		}
+		if !contains(applicable, key) {
+			issues = append(issues, ValidationIssue{
+				Severity:    SeverityWarning,
+				Environment: env.ID,
+				Field:       "criteria." + key,
+				Message:     fmt.Sprintf("criteria.%s does not apply to %s; use %s", key, cloudType, strings.Join(applicable[:2], "/")),
+			})
+			continue
+		}
+		if format, ok := criteriaFormats[cloudType][key]; ok && !format.pattern.MatchString(value) {
+			issues = append(issues, ValidationIssue{
+				Severity:    SeverityError,
+				Environment: env.ID,
+				Field:       "criteria." + key,
+				Message:     fmt.Sprintf("criteria.%s %q is not a valid %s %s (e.g. %s)", key, value, cloudType, key, format.example),
+			})
+		}
+	}
+	return issues
+}
+
+// constraint is one provider criterion in a form comparable across cloud types
+type constraint struct {
+	key, value string
+}
+
+// constraints normalizes an environment's provider criteria; account, subscription and
+// project all constrain the VM's account, region and location its location
+func constraints(criteria models.EnvironmentCriteria) []constraint {
+	var result []constraint
+	add := func(key, value string) {
+		if value != "" {
+			result = append(result, constraint{key, value})
+		}
+	}
+	add("cloud_type", criteria.CloudType)
+	add("account", criteria.Account)
+	add("account", criteria.Subscription)
+	add("account", criteria.Project)
+	add("location", criteria.Region)
+	add("location", criteria.Location)
+	add("zone", criteria.Zone)
+	add("vpc", strings.ToLower(criteria.VPC))
+	return result
+}
+
+// implied reports whether every VM satisfying the constraints of b also satisfies c
+func implied(c constraint, b []constraint) bool {
+	for _, other := range b {
+		switch {
+		case c.key == other.key && c.key == "zone":
+			if strings.HasPrefix(other.value, c.value) {
+				return true
+			}
+		case c.key == other.key:
+			if c.value == other.value {
+				return true
+			}
+		case c.key == "zone" && other.key == "location":
+			if strings.HasPrefix(other.value, c.value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// covers reports whether every VM matching b also matches a
+func covers(a, b models.Environment) bool {
+	bConstraints := constraints(b.Criteria)
+	for _, c := range constraints(a.Criteria) {
+		if !implied(c, bConstraints) {
+			return false
+		}
+	}
+	for _, rule := range a.Criteria.Rules {
+		found := false
+		for _, other := range b.Criteria.Rules {
+			if reflect.DeepEqual(rule, other) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// compatible reports whether some VM could match both a and b
+func compatible(a, b models.Environment) bool {
+	for _, c := range constraints(a.Criteria) {
+		for _, other := range constraints(b.Criteria) {
+			if c.key == other.key {
+				if c.key == "zone" {
+					if !strings.HasPrefix(c.value, other.value) && !strings.HasPrefix(other.value, c.value) {
+						return false
+					}
+				} else if c.value != other.value {
+					return false
+				}
+			}
+			if (c.key == "zone" && other.key == "location" && !strings.HasPrefix(other.value, c.value)) ||
+				(c.key == "location" && other.key == "zone" && !strings.HasPrefix(c.value, other.value)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// conditionCount estimates the score an environment reaches when it matches
+func conditionCount(env models.Environment) int {
+	return len(constraints(env.Criteria)) + len(env.Criteria.Rules)
+}
+
+// overlapIssues reports pairs of environments that are identical, where one can never win,
+// or that may tie for the same VMs. The environments must have inheritance applied.
+func overlapIssues(environments []models.Environment) []ValidationIssue {
+	sorted := make([]models.Environment, len(environments))
+	copy(sorted, environments)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	var issues []ValidationIssue
+	warn := func(env, format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Severity: SeverityWarning, Environment: env, Field: "criteria", Message: fmt.Sprintf(format, args...)})
+	}
+
+	for i := range sorted {
+		for j := i + 1; j < len(sorted); j++ {
+			a, b := sorted[i], sorted[j]
+			if a.ID == b.ID || !compatible(a, b) {
+				continue
+			}
+
+			// A descendant wins over its ancestor unless the ancestor has a higher priority
+			if isAncestor(a.ID, b) || isAncestor(b.ID, a) {
+				ancestor, descendant := a, b
+				if isAncestor(b.ID, a) {
+					ancestor, descendant = b, a
+				}
+				if ancestor.Priority > descendant.Priority {
+					warn(descendant.ID, "environment '%s' is shadowed by its ancestor '%s', which has a higher priority", descendant.ID, ancestor.ID)
+				}
+				continue
+			}
+
+			aCoversB, bCoversA := covers(a, b), covers(b, a)
+
+			switch {
+			case aCoversB && bCoversA:
+				if a.Priority == b.Priority {
+					warn(b.ID, "environments '%s' and '%s' have identical criteria; '%s' is never selected", a.ID, b.ID, b.ID)
+				} else {
+					winner, loser := a, b
+					if b.Priority > a.Priority {
+						winner, loser = b, a
+					}
+					warn(loser.ID, "environment '%s' has the same criteria as '%s', which has a higher priority; '%s' is never selected", loser.ID, winner.ID, loser.ID)
+				}
+			case aCoversB && a.Priority > b.Priority:
+				warn(b.ID, "environment '%s' is shadowed by '%s': every VM it matches also matches '%s', which has a higher priority", b.ID, a.ID, a.ID)
+			case bCoversA && b.Priority > a.Priority:
+				warn(a.ID, "environment '%s' is shadowed by '%s': every VM it matches also matches '%s', which has a higher priority", a.ID, b.ID, b.ID)
+			case !aCoversB && !bCoversA && a.Priority == b.Priority && conditionCount(a) == conditionCount(b):
+				warn(b.ID, "environments '%s' and '%s' can match the same VMs with equal priority and score; ties are decided by ID", a.ID, b.ID)
+			}
+		}
+	}
+	return issues
+}
+
+// contains reports whether a string slice contains a value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
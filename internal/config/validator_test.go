@@ -0,0 +1,121 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issueMessages returns the messages of a report's issues of one severity
+func issueMessages(issues []ValidationIssue) []string {
+	messages := make([]string, len(issues))
+	for i, issue := range issues {
+		messages[i] = issue.Message
+	}
+	return messages
+}
+
+func TestValidateEnvironmentConfig(t *testing.T) {
+	t.Run("Accepts a valid configuration", func(t *testing.T) {
+		report := ValidateEnvironmentConfig([]byte(testEnvironmentsYAML))
+		assert.True(t, report.Valid)
+		assert.Equal(t, 2, report.Environments)
+		assert.Empty(t, report.Errors)
+		assert.Empty(t, report.Warnings)
+	})
+
+	t.Run("Requires criteria per cloud type", func(t *testing.T) {
+		report := ValidateEnvironmentConfig([]byte(`environments:
+  - id: "azure"
+    name: "Azure"
+    criteria: {cloud_type: "azure", subscription: "sub-1"}
+  - id: "gcp"
+    name: "GCP"
+    criteria: {cloud_type: "gcp", project: "project-1", zone: "us-central1"}
+`))
+		assert.False(t, report.Valid)
+		require.Len(t, report.Errors, 1)
+		assert.Equal(t, "azure", report.Errors[0].Environment)
+		assert.Equal(t, "criteria.location", report.Errors[0].Field)
+	})
+
+	t.Run("Flags unknown keys", func(t *testing.T) {
+		report := ValidateEnvironmentConfig([]byte(`environments:
+  - id: "prod"
+    name: "Production"
+    owner: "platform"
+    criteria:
+      cloud_type: "aws"
+      account: "123456789012"
+      regoin: "us-east-1"
+      region: "us-east-1"
+      rules:
+        - any:
+            - {tga: "Environment"}
+`))
+		assert.False(t, report.Valid)
+		assert.ElementsMatch(t, []string{
+			`unknown key "owner"`,
+			`unknown key "regoin" (did you mean "region"?)`,
+			`unknown key "tga" (did you mean "tag"?)`,
+			"environment 'prod' criteria.rules[0]: rule has no conditions",
+		}, issueMessages(report.Errors))
+		assert.Equal(t, "environments[0].criteria.rules[0].any[0].tga", report.Errors[2].Field)
+	})
+
+	t.Run("Validates formats and applicable criteria", func(t *testing.T) {
+		report := ValidateEnvironmentConfig([]byte(`environments:
+  - id: "aws"
+    name: "AWS"
+    criteria: {cloud_type: "aws", account: "1234", region: "us-east", vpc: "vpc-12345678", location: "eastus"}
+  - id: "gcp"
+    name: "GCP"
+    criteria: {cloud_type: "gcp", project: "project-1", zone: "us-central1-a"}
+`))
+		assert.ElementsMatch(t, []string{
+			`criteria.account "1234" is not a valid aws account (e.g. 123456789012)`,
+			`criteria.region "us-east" is not a valid aws region (e.g. us-east-1)`,
+		}, issueMessages(report.Errors))
+		assert.Equal(t, []string{"criteria.location does not apply to aws; use account/region"}, issueMessages(report.Warnings))
+	})
+
+	t.Run("Detects overlapping and shadowed environments", func(t *testing.T) {
+		report := ValidateEnvironmentConfig([]byte(`environments:
+  - id: "account"
+    name: "Account"
+    priority: 10
+    criteria: {cloud_type: "aws", account: "123456789012", region: "us-east-1"}
+  - id: "account-vpc"
+    name: "Account VPC"
+    criteria: {cloud_type: "aws", account: "123456789012", region: "us-east-1", vpc: "vpc-12345678"}
+  - id: "copy"
+    name: "Copy"
+    priority: 10
+    criteria: {cloud_type: "aws", account: "123456789012", region: "us-east-1"}
+  - id: "gcp-a"
+    name: "GCP A"
+    criteria: {cloud_type: "gcp", project: "project-1", zone: "us-central1"}
+  - id: "gcp-b"
+    name: "GCP B"
+    criteria: {cloud_type: "gcp", project: "project-1", zone: "us-central1-a", rules: [{tag: "team"}]}
+  - id: "gcp-c"
+    name: "GCP C"
+    criteria: {cloud_type: "gcp", project: "project-1", zone: "us-central1-a", rules: [{name: "web-*"}]}
+`))
+		assert.True(t, report.Valid)
+		assert.ElementsMatch(t, []string{
+			"environment 'account-vpc' is shadowed by 'account': every VM it matches also matches 'account', which has a higher priority",
+			"environments 'account' and 'copy' have identical criteria; 'copy' is never selected",
+			"environment 'account-vpc' is shadowed by 'copy': every VM it matches also matches 'copy', which has a higher priority",
+			"environments 'gcp-b' and 'gcp-c' can match the same VMs with equal priority and score; ties are decided by ID",
+		}, issueMessages(report.Warnings))
+	})
+
+	t.Run("Reports invalid YAML", func(t *testing.T) {
+		report := ValidateEnvironmentConfig([]byte("environments: [unclosed"))
+		assert.False(t, report.Valid)
+		require.Len(t, report.Errors, 1)
+		assert.Contains(t, report.Errors[0].Message, "invalid YAML")
+	})
+}
@@ -0,0 +1,411 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+
+	"golang-service/internal/models"
+)
+
+// ErrVersionNotFound is returned for configuration versions that were never recorded
+var ErrVersionNotFound = errors.New("environment configuration version not found")
+
+// SystemAuthor is recorded as the author of configuration versions not applied by a user,
+// such as the initial load and file reloads
+const SystemAuthor = "system"
+
+// Version sources recorded with each configuration version
+const (
+	SourceLoad   = "load"
+	SourceFile   = "file"
+	SourceReload = "reload"
+	SourceCreate = "create"
+	SourceUpdate = "update"
+	SourceDelete = "delete"
+	SourceImport = "import"
+	SourceSync   = "sync" // picked up from a change another replica made
+)
+
+// marshalEnvironments returns the canonical YAML representation of environments, ordered by ID
+func marshalEnvironments(environments []models.Environment) ([]byte, error) {
+	return yaml.Marshal(models.EnvironmentConfig{Environments: sortedByID(environments)})
+}
+
+// DiffEnvironments compares two sets of environments by ID. Changes are ordered by ID and
+// list the modified fields; withValues also includes the environments before and after.
+func DiffEnvironments(before, after []models.Environment, withValues bool) []models.EnvironmentChange {
+	changes := []models.EnvironmentChange{}
+	for _, old := range sortedByID(before) {
+		if findEnvironment(after, old.ID) == nil {
+			change := models.EnvironmentChange{ID: old.ID, Change: models.EnvironmentRemoved}
+			if withValues {
+				change.Before = environmentPtr(old)
+			}
+			changes = append(changes, change)
+		}
+	}
+	for _, current := range sortedByID(after) {
+		old := findEnvironment(before, current.ID)
+		change := models.EnvironmentChange{ID: current.ID, Change: models.EnvironmentAdded}
+		if old != nil {
+			change.Fields = changedFields(*old, current)
+			if len(change.Fields) == 0 {
+				continue
+			}
+			change.Change = models.EnvironmentModified
+		}
+		if withValues {
+			if old != nil {
+				change.Before = environmentPtr(*old)
+			}
+			change.After = environmentPtr(current)
+		}
+		changes = append(changes, change)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+	return changes
+}
+
+// changedFields lists the configured fields that differ between two versions of an environment
+func changedFields(before, after models.Environment) []string {
+	var fields []string
+	for _, field := range []struct {
+		name          string
+		before, after interface{}
+	}{
+		{"name", before.Name, after.Name},
+		{"description", before.Description, after.Description},
+		{"parent", before.Parent, after.Parent},
+		{"priority", before.Priority, after.Priority},
+		{"criteria", before.Criteria, after.Criteria},
+		{"tags", before.Tags, after.Tags},
+		{"metadata", before.Metadata, after.Metadata},
+	} {
+		if !equalConfigValues(field.before, field.after) {
+			fields = append(fields, field.name)
+		}
+	}
+	return fields
+}
+
+// equalConfigValues compares configured values, treating empty and missing alike
+func equalConfigValues(a, b interface{}) bool {
+	if isEmptyValue(a) && isEmptyValue(b) {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// isEmptyValue reports whether a value is zero or an empty slice or map
+func isEmptyValue(v interface{}) bool {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+// environmentPtr returns a copy of an environment without its load-time fields
+func environmentPtr(env models.Environment) *models.Environment {
+	env.Ancestors = nil
+	env.ConfigVersion = 0
+	return &env
+}
+
+// recordVersion stores the loaded environments as a new configuration version unless they
+// match the latest one. The caller must hold s.mu. Failures are logged rather than returned
+// so that a version table problem never blocks a configuration change.
+func (s *EnvironmentService) recordVersion(environments []models.Environment, author, source string) {
+	content, err := marshalEnvironments(environments)
+	if err != nil {
+		log.Printf("Failed to record environment configuration version: %v", err)
+		return
+	}
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	latest, err := s.latestVersion()
+	if err != nil {
+		log.Printf("Failed to record environment configuration version: %v", err)
+		return
+	}
+	if latest != nil && latest.Checksum == checksum {
+		s.version = latest.Version
+		return
+	}
+	// The replica that made the change records its version, with its author, right after
+	// writing; until then this replica reports the latest recorded version
+	if source == SourceSync {
+		if latest != nil {
+			s.version = latest.Version
+		}
+		return
+	}
+
+	var previous []models.Environment
+	if latest != nil {
+		if parsed, err := ParseEnvironmentConfig([]byte(latest.Content)); err == nil {
+			previous = parsed.Environments
+		}
+	}
+
+	version := models.EnvironmentConfigVersion{
+		Author:   author,
+		Source:   source,
+		Checksum: checksum,
+		Changes:  DiffEnvironments(previous, environments, false),
+		Content:  string(content),
+	}
+	if s.db != nil {
+		if err := s.db.Create(&version).Error; err != nil {
+			log.Printf("Failed to record environment configuration version: %v", err)
+			return
+		}
+	} else {
+		version.Version = len(s.versions) + 1
+		version.CreatedAt = s.lastLoad
+		s.versions = append(s.versions, version)
+	}
+	s.version = version.Version
+}
+
+// latestVersion returns the most recent configuration version, or nil when none was recorded
+func (s *EnvironmentService) latestVersion() (*models.EnvironmentConfigVersion, error) {
+	if s.db == nil {
+		if len(s.versions) == 0 {
+			return nil, nil
+		}
+		return &s.versions[len(s.versions)-1], nil
+	}
+
+	var version models.EnvironmentConfigVersion
+	err := s.db.Order("version DESC").First(&version).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load latest configuration version: %w", err)
+	}
+	return &version, nil
+}
+
+// ConfigVersion returns the version of the active configuration, or 0 before it is loaded
+func (s *EnvironmentService) ConfigVersion() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// ListVersions returns a page of configuration versions, newest first, without their
+// content, along with the total number of versions. With a scope, each version only lists
+// the changes made to environments in it.
+func (s *EnvironmentService) ListVersions(page, pageSize int, scope *Scope) ([]models.EnvironmentConfigVersion, int64, error) {
+	versions, total, err := s.listVersions(page, pageSize)
+	if err != nil || scope == nil {
+		return versions, total, err
+	}
+
+	// Consecutive versions share their environments, so each is parsed once
+	loaded := map[int][]models.Environment{}
+	load := func(number int) ([]models.Environment, error) {
+		if environments, ok := loaded[number]; ok || number < 1 {
+			return environments, nil
+		}
+		environments, err := s.versionEnvironments(number)
+		loaded[number] = environments
+		return environments, err
+	}
+	for i := range versions {
+		before, err := load(versions[i].Version - 1)
+		if err != nil {
+			return nil, 0, err
+		}
+		after, err := load(versions[i].Version)
+		if err != nil {
+			return nil, 0, err
+		}
+		versions[i].Changes = scopeChanges(versions[i].Changes, before, after, scope)
+	}
+	return versions, total, nil
+}
+
+// listVersions returns a page of configuration versions without their content
+func (s *EnvironmentService) listVersions(page, pageSize int) ([]models.EnvironmentConfigVersion, int64, error) {
+	offset := (page - 1) * pageSize
+
+	if s.db == nil {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		total := int64(len(s.versions))
+		versions := []models.EnvironmentConfigVersion{}
+		for i := len(s.versions) - 1 - offset; i >= 0 && len(versions) < pageSize; i-- {
+			version := s.versions[i]
+			version.Content = ""
+			versions = append(versions, version)
+		}
+		return versions, total, nil
+	}
+
+	var total int64
+	if err := s.db.Model(&models.EnvironmentConfigVersion{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count configuration versions: %w", err)
+	}
+	versions := []models.EnvironmentConfigVersion{}
+	err := s.db.Omit("content").Order("version DESC").Offset(offset).Limit(pageSize).Find(&versions).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list configuration versions: %w", err)
+	}
+	return versions, total, nil
+}
+
+// GetVersion returns a configuration version including its content. With a scope, the
+// content and changes are limited to the environments in it.
+func (s *EnvironmentService) GetVersion(number int, scope *Scope) (*models.EnvironmentConfigVersion, error) {
+	version, err := s.getVersion(number)
+	if err != nil || scope == nil {
+		return version, err
+	}
+
+	after, err := s.versionEnvironments(number)
+	if err != nil {
+		return nil, err
+	}
+	var before []models.Environment
+	if number > 1 {
+		if before, err = s.versionEnvironments(number - 1); err != nil {
+			return nil, err
+		}
+	}
+
+	content, err := marshalEnvironments(scopedEnvironments(after, scope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render configuration version %d: %w", number, err)
+	}
+	version.Content = string(content)
+	version.Changes = scopeChanges(version.Changes, before, after, scope)
+	return version, nil
+}
+
+// getVersion returns a configuration version as recorded
+func (s *EnvironmentService) getVersion(number int) (*models.EnvironmentConfigVersion, error) {
+	if s.db == nil {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		if number < 1 || number > len(s.versions) {
+			return nil, fmt.Errorf("%w: %d", ErrVersionNotFound, number)
+		}
+		version := s.versions[number-1]
+		return &version, nil
+	}
+
+	var version models.EnvironmentConfigVersion
+	err := s.db.First(&version, "version = ?", number).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %d", ErrVersionNotFound, number)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration version: %w", err)
+	}
+	return &version, nil
+}
+
+// DiffVersions compares two configuration versions, including the environments before and
+// after. Version 0 is the empty configuration before the first, so diffing from it lists
+// every environment as added. With a scope, only changes to environments in it are included.
+func (s *EnvironmentService) DiffVersions(from, to int, scope *Scope) (*models.EnvironmentVersionDiff, error) {
+	var before []models.Environment
+	if from > 0 {
+		var err error
+		if before, err = s.versionEnvironments(from); err != nil {
+			return nil, err
+		}
+	}
+	after, err := s.versionEnvironments(to)
+	if err != nil {
+		return nil, err
+	}
+	changes := DiffEnvironments(before, after, true)
+	if scope != nil {
+		changes = scopeChanges(changes, before, after, scope)
+	}
+	return &models.EnvironmentVersionDiff{From: from, To: to, Changes: changes}, nil
+}
+
+// scopedEnvironments returns the environments in scope, judging each with its place in
+// the hierarchy resolved
+func scopedEnvironments(environments []models.Environment, scope *Scope) []models.Environment {
+	allowed := make([]models.Environment, 0, len(environments))
+	for i, env := range InheritEnvironments(environments) {
+		if scope.AllowsEnvironment(env) {
+			allowed = append(allowed, environments[i])
+		}
+	}
+	return allowed
+}
+
+// scopeChanges keeps the changes to environments in scope. Removals are judged by the
+// environments before the change, everything else by the environments after it.
+func scopeChanges(changes []models.EnvironmentChange, before, after []models.Environment, scope *Scope) []models.EnvironmentChange {
+	visibleBefore := map[string]bool{}
+	for _, env := range scopedEnvironments(before, scope) {
+		visibleBefore[env.ID] = true
+	}
+	visibleAfter := map[string]bool{}
+	for _, env := range scopedEnvironments(after, scope) {
+		visibleAfter[env.ID] = true
+	}
+
+	kept := []models.EnvironmentChange{}
+	for _, change := range changes {
+		visible := visibleAfter
+		if change.Change == models.EnvironmentRemoved {
+			visible = visibleBefore
+		}
+		if visible[change.ID] {
+			kept = append(kept, change)
+		}
+	}
+	return kept
+}
+
+// versionEnvironments returns the environments of a configuration version
+func (s *EnvironmentService) versionEnvironments(number int) ([]models.Environment, error) {
+	version, err := s.getVersion(number)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseEnvironmentConfig([]byte(version.Content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse configuration version %d: %w", number, err)
+	}
+	return config.Environments, nil
+}
+
+// RollbackToVersion makes a previous configuration version active again. The rollback is
+// recorded as a new version; versions are never modified. It returns the active version.
+func (s *EnvironmentService) RollbackToVersion(number int, author string) (int, error) {
+	if s.db == nil {
+		return 0, ErrReadOnly
+	}
+	version, err := s.getVersion(number)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.importEnvironments([]byte(version.Content), ImportModeReplace, author, fmt.Sprintf("rollback:%d", number)); err != nil {
+		return 0, err
+	}
+	return s.ConfigVersion(), nil
+}
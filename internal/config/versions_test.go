@@ -0,0 +1,161 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffEnvironments(t *testing.T) {
+	before := []models.Environment{
+		{ID: "a", Name: "A", Tags: []string{"x"}},
+		{ID: "b", Name: "B"},
+		{ID: "c", Name: "C", Metadata: map[string]interface{}{}},
+	}
+	after := []models.Environment{
+		{ID: "a", Name: "A2", Tags: []string{"x"}, Priority: 5},
+		{ID: "c", Name: "C"},
+		{ID: "d", Name: "D"},
+	}
+
+	changes := DiffEnvironments(before, after, false)
+	require.Len(t, changes, 3)
+	assert.Equal(t, models.EnvironmentChange{ID: "a", Change: models.EnvironmentModified, Fields: []string{"name", "priority"}}, changes[0])
+	assert.Equal(t, models.EnvironmentChange{ID: "b", Change: models.EnvironmentRemoved}, changes[1])
+	assert.Equal(t, models.EnvironmentChange{ID: "d", Change: models.EnvironmentAdded}, changes[2])
+
+	changes = DiffEnvironments(before, after, true)
+	assert.Equal(t, "A", changes[0].Before.Name)
+	assert.Equal(t, "A2", changes[0].After.Name)
+	assert.Nil(t, changes[1].After)
+	assert.Nil(t, changes[2].Before)
+}
+
+func TestEnvironmentVersionsDatabase(t *testing.T) {
+	service := newDatabaseEnvironmentService(t, testEnvironmentsYAML)
+	assert.Equal(t, 1, service.ConfigVersion())
+
+	// Reloading an unchanged configuration records no version
+	require.NoError(t, service.ReloadConfig("alice"))
+	assert.Equal(t, 1, service.ConfigVersion())
+
+	changed := models.Environment{
+		Name:     "AWS Production (east)",
+		Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "123456789012", Region: "us-east-1"},
+	}
+	_, err := service.UpdateEnvironment("prod", changed, "alice")
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteEnvironment("azure-dev", "bob"))
+	assert.Equal(t, 3, service.ConfigVersion())
+
+	versions, total, err := service.ListVersions(1, 2, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 3, versions[0].Version)
+	assert.Equal(t, "bob", versions[0].Author)
+	assert.Equal(t, SourceDelete, versions[0].Source)
+	assert.Equal(t, []models.EnvironmentChange{{ID: "azure-dev", Change: models.EnvironmentRemoved}}, versions[0].Changes)
+	assert.Empty(t, versions[0].Content)
+	assert.Equal(t, "alice", versions[1].Author)
+	assert.Equal(t, []string{"name", "tags", "metadata"}, versions[1].Changes[0].Fields)
+
+	first, err := service.GetVersion(1, nil)
+	require.NoError(t, err)
+	assert.Equal(t, SystemAuthor, first.Author)
+	assert.Equal(t, SourceLoad, first.Source)
+	assert.Len(t, first.Changes, 2)
+	assert.Contains(t, first.Content, "azure-dev")
+
+	diff, err := service.DiffVersions(1, 3, nil)
+	require.NoError(t, err)
+	require.Len(t, diff.Changes, 2)
+	assert.Equal(t, "azure-dev", diff.Changes[0].ID)
+	assert.Equal(t, "AWS Production", diff.Changes[1].Before.Name)
+
+	// Version 0 is the empty configuration before the first
+	diff, err = service.DiffVersions(0, 1, nil)
+	require.NoError(t, err)
+	require.Len(t, diff.Changes, 2)
+	for _, change := range diff.Changes {
+		assert.Equal(t, models.EnvironmentAdded, change.Change)
+	}
+
+	_, err = service.GetVersion(9, nil)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+
+	// Rolling back applies the old content as a new version
+	active, err := service.RollbackToVersion(1, "carol")
+	require.NoError(t, err)
+	assert.Equal(t, 4, active)
+	env, err := service.GetEnvironmentByID("azure-dev")
+	require.NoError(t, err)
+	assert.Equal(t, "Azure Development", env.Name)
+
+	latest, err := service.GetVersion(4, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "carol", latest.Author)
+	assert.Equal(t, "rollback:1", latest.Source)
+	assert.Equal(t, first.Checksum, latest.Checksum)
+
+	resolved, err := service.ResolveEnvironmentForVM(models.VM{CloudType: "aws", CloudAccountID: "123456789012", Location: "us-east-1"})
+	require.NoError(t, err)
+	assert.Equal(t, 4, resolved.ConfigVersion)
+}
+
+func TestEnvironmentVersionsScoped(t *testing.T) {
+	service := newDatabaseEnvironmentService(t, testEnvironmentsYAML)
+	changed := models.Environment{
+		Name:     "AWS Production (east)",
+		Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "123456789012", Region: "us-east-1"},
+	}
+	_, err := service.UpdateEnvironment("prod", changed, "alice")
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteEnvironment("azure-dev", "bob"))
+
+	scope := &Scope{Environments: []string{"prod"}}
+
+	versions, _, err := service.ListVersions(1, 10, scope)
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	assert.Empty(t, versions[0].Changes)
+	assert.Equal(t, "prod", versions[1].Changes[0].ID)
+	assert.Equal(t, []models.EnvironmentChange{{ID: "prod", Change: models.EnvironmentAdded}}, versions[2].Changes)
+
+	first, err := service.GetVersion(1, scope)
+	require.NoError(t, err)
+	assert.Len(t, first.Changes, 1)
+	assert.Contains(t, first.Content, "prod")
+	assert.NotContains(t, first.Content, "azure-dev")
+
+	diff, err := service.DiffVersions(1, 3, scope)
+	require.NoError(t, err)
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, "prod", diff.Changes[0].ID)
+}
+
+func TestEnvironmentVersionsFile(t *testing.T) {
+	path := writeEnvironmentsFile(t, testEnvironmentsYAML)
+	service := NewEnvironmentService(path)
+	require.NoError(t, service.LoadConfig())
+	assert.Equal(t, 1, service.ConfigVersion())
+
+	require.NoError(t, os.WriteFile(path, []byte(testEnvironmentsYAML+`  - id: "gcp-dev"
+    name: "GCP Development"
+    criteria: {cloud_type: "gcp", project: "dev-project", zone: "us-central1"}
+`), 0o644))
+	require.NoError(t, service.ApplyConfigFile())
+	assert.Equal(t, 2, service.ConfigVersion())
+
+	version, err := service.GetVersion(2, nil)
+	require.NoError(t, err)
+	assert.Equal(t, SourceFile, version.Source)
+	assert.Equal(t, []models.EnvironmentChange{{ID: "gcp-dev", Change: models.EnvironmentAdded}}, version.Changes)
+
+	_, err = service.RollbackToVersion(1, "alice")
+	assert.ErrorIs(t, err, ErrReadOnly)
+}
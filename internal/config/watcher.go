@@ -0,0 +1,96 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchConfig watches the configuration file and applies it with ApplyConfigFile whenever
+// its content changes, until ctx is cancelled. With a database the file is not a source
+// to watch and ErrSeedOnly is returned.
+//
+// The parent directory is watched rather than the file itself: Kubernetes updates a
+// mounted ConfigMap by swapping a "..data" symlink, and editors often replace files by
+// renaming, neither of which is reported on the original file. Bursts of events are
+// debounced and the file is only applied when its content actually changed.
+func (s *EnvironmentService) WatchConfig(ctx context.Context, debounce time.Duration) error {
+	if s.db != nil {
+		return ErrSeedOnly
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+
+	dir := filepath.Dir(s.configPath)
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	lastSum, _ := fileChecksum(s.configPath)
+
+	go func() {
+		defer watcher.Close()
+
+		var settled <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// Restart the debounce window on every event
+				settled = time.After(debounce)
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Environment config watcher error: %v", err)
+
+			case <-settled:
+				settled = nil
+
+				sum, err := fileChecksum(s.configPath)
+				if err != nil {
+					// Mid-swap or deleted; keep the current configuration until the file reappears
+					log.Printf("Environment config file unreadable, keeping current configuration: %v", err)
+					continue
+				}
+				if sum == lastSum {
+					continue
+				}
+				lastSum = sum
+
+				if err := s.ApplyConfigFile(); err != nil {
+					log.Printf("Rejected environment config change, keeping current configuration: %v", err)
+					continue
+				}
+				log.Printf("Environment configuration reloaded from %s", s.configPath)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// fileChecksum returns the SHA-256 of a file's content, following symlinks
+func fileChecksum(path string) ([sha256.Size]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
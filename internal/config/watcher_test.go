@@ -0,0 +1,116 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const watchDebounce = 20 * time.Millisecond
+
+// startWatching loads the service, starts the watcher and returns a channel signalled on every reload
+func startWatching(t *testing.T, service *EnvironmentService) <-chan struct{} {
+	t.Helper()
+	require.NoError(t, service.LoadConfig())
+
+	reloaded := make(chan struct{}, 10)
+	service.OnReload(func() { reloaded <- struct{}{} })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	require.NoError(t, service.WatchConfig(ctx, watchDebounce))
+	return reloaded
+}
+
+func waitForReload(t *testing.T, reloaded <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-reloaded:
+	case <-time.After(2 * time.Second):
+		t.Fatal("configuration was not reloaded")
+	}
+}
+
+func assertNoReload(t *testing.T, reloaded <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-reloaded:
+		t.Fatal("configuration was reloaded unexpectedly")
+	case <-time.After(10 * watchDebounce):
+	}
+}
+
+func environmentName(t *testing.T, service *EnvironmentService, id string) string {
+	t.Helper()
+	env, err := service.GetEnvironmentByID(id)
+	require.NoError(t, err)
+	return env.Name
+}
+
+func TestWatchConfig(t *testing.T) {
+	renamed := `environments:
+  - id: "prod"
+    name: "AWS Production (renamed)"
+    criteria: {cloud_type: "aws", account: "123456789012", region: "us-east-1"}
+`
+
+	t.Run("Reloads when the file changes and keeps the old config when invalid", func(t *testing.T) {
+		path := writeEnvironmentsFile(t, testEnvironmentsYAML)
+		service := NewEnvironmentService(path)
+		reloaded := startWatching(t, service)
+
+		require.NoError(t, os.WriteFile(path, []byte(renamed), 0o644))
+		waitForReload(t, reloaded)
+		assert.Equal(t, "AWS Production (renamed)", environmentName(t, service, "prod"))
+
+		require.NoError(t, os.WriteFile(path, []byte("environments:\n  - id: \"prod\"\n"), 0o644))
+		assertNoReload(t, reloaded)
+		assert.Equal(t, "AWS Production (renamed)", environmentName(t, service, "prod"))
+
+		require.NoError(t, os.WriteFile(path, []byte("environments: [unclosed"), 0o644))
+		assertNoReload(t, reloaded)
+		assert.Equal(t, "AWS Production (renamed)", environmentName(t, service, "prod"))
+	})
+
+	t.Run("Follows ConfigMap style symlink swaps", func(t *testing.T) {
+		// Kubernetes layout: environments.yaml -> ..data/environments.yaml, ..data -> ..<timestamp>
+		dir := t.TempDir()
+		writeVersion := func(version, content string) {
+			require.NoError(t, os.Mkdir(filepath.Join(dir, version), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, version, "environments.yaml"), []byte(content), 0o644))
+		}
+		writeVersion("..v1", testEnvironmentsYAML)
+		require.NoError(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+		require.NoError(t, os.Symlink(filepath.Join("..data", "environments.yaml"), filepath.Join(dir, "environments.yaml")))
+
+		service := NewEnvironmentService(filepath.Join(dir, "environments.yaml"))
+		reloaded := startWatching(t, service)
+		assert.Equal(t, "AWS Production", environmentName(t, service, "prod"))
+
+		// Atomically repoint ..data the way the kubelet does
+		writeVersion("..v2", renamed)
+		require.NoError(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+		require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+		require.NoError(t, os.RemoveAll(filepath.Join(dir, "..v1")))
+
+		waitForReload(t, reloaded)
+		assert.Equal(t, "AWS Production (renamed)", environmentName(t, service, "prod"))
+	})
+
+	t.Run("Is refused with a database, where the file only seeds", func(t *testing.T) {
+		service := newDatabaseEnvironmentService(t, testEnvironmentsYAML)
+
+		assert.ErrorIs(t, service.WatchConfig(context.Background(), watchDebounce), ErrSeedOnly)
+		require.NoError(t, os.WriteFile(service.GetConfigPath(), []byte(renamed), 0o644))
+		assert.ErrorIs(t, service.ApplyConfigFile(), ErrSeedOnly)
+
+		environments, err := service.GetEnvironments()
+		require.NoError(t, err)
+		assert.Len(t, environments, 2)
+	})
+}
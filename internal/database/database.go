@@ -1,6 +1,8 @@
 package database
 
 import (
+	"golang-service/internal/models"
+
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -12,11 +14,46 @@ func Initialize(databaseURL string) (*gorm.DB, error) {
 		return nil, err
 	}
 
-	// For now, skip AutoMigrate since the tables are created by init.sql
-	// The tables should already exist from the init.sql script
+	// The CloudQuery inventory tables are created by init.sql and the sync itself;
+	// only the tables owned by this service are migrated
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
+// Migrate creates or updates the tables owned by this service
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.Environment{}, &models.EnvironmentConfigVersion{}, &models.APIKey{}, &models.AuditEvent{}, &models.User{}, &models.SavedView{}); err != nil {
+		return err
+	}
+	// Object IDs are only unique within an issuer, which idx_users_identity covers
+	if db.Migrator().HasIndex(&models.User{}, "idx_users_azure_id") {
+		if err := db.Migrator().DropIndex(&models.User{}, "idx_users_azure_id"); err != nil {
+			return err
+		}
+	}
+	if db.Dialector.Name() == "postgres" {
+		return db.Exec(auditAppendOnlySQL).Error
+	}
+	return nil
+}
+
+// auditAppendOnlySQL makes PostgreSQL refuse to change or remove audit events, whoever asks
+const auditAppendOnlySQL = `
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only
+	BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_events
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
+`
+
 // Health checks database connectivity
 func Health(db *gorm.DB) error {
 	sqlDB, err := db.DB()
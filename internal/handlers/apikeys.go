@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"golang-service/internal/config"
+	"golang-service/internal/models"
+	"golang-service/internal/services"
+	"golang-service/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeysHandler handles API key administration requests
+type APIKeysHandler struct {
+	apiKeys    *services.APIKeyService
+	policy     *config.Policy
+	envService *config.EnvironmentService
+}
+
+// NewAPIKeysHandler creates a new API keys handler. Roles are checked against the policy
+// and environments against envService, when it is available.
+func NewAPIKeysHandler(apiKeys *services.APIKeyService, policy *config.Policy, envService *config.EnvironmentService) *APIKeysHandler {
+	return &APIKeysHandler{apiKeys: apiKeys, policy: policy, envService: envService}
+}
+
+// CreateAPIKey handles POST /api/v1/admin/api-keys. The response is the only place the
+// key's secret is ever shown. Callers can only grant roles they hold themselves, unless
+// they hold a bypass role, and callers with a data scope can only create keys limited to
+// environments within it.
+func (h *APIKeysHandler) CreateAPIKey(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Request body must contain 'name' and a non-empty 'roles' list")
+		return
+	}
+
+	for _, role := range req.Roles {
+		if _, ok := h.policy.Roles[role]; !ok {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Unknown role: "+role)
+			return
+		}
+	}
+	if roles := callerRoles(c); !h.policy.Bypasses(roles) {
+		for _, role := range req.Roles {
+			if !slices.Contains(roles, role) {
+				utils.SendErrorResponse(c, http.StatusForbidden, "You cannot grant a role you do not hold: "+role)
+				return
+			}
+		}
+	}
+	scope := callerScope(c)
+	if scope != nil && (len(req.Environments) == 0 || h.envService == nil) {
+		utils.SendErrorResponse(c, http.StatusForbidden, "Keys you create must be limited to environments in your data scope")
+		return
+	}
+	if h.envService != nil {
+		for _, id := range req.Environments {
+			env, err := h.envService.GetEnvironmentByID(id)
+			if err != nil {
+				utils.SendErrorResponse(c, http.StatusBadRequest, "Unknown environment: "+id)
+				return
+			}
+			if !scope.AllowsEnvironment(*env) {
+				utils.SendErrorResponse(c, http.StatusForbidden, "Environment outside your data scope: "+id)
+				return
+			}
+		}
+	}
+
+	created, err := h.apiKeys.Create(req, author(c))
+	if err != nil {
+		sendAPIKeyError(c, err, "Failed to create API key")
+		return
+	}
+
+	// Record the key without its secret
+	auditChange(c, "apikey.create", "apikey:"+created.ID, nil, created.APIKey)
+	c.JSON(http.StatusCreated, gin.H{"data": created})
+}
+
+// ListAPIKeys handles GET /api/v1/admin/api-keys
+// Callers with a data scope only see the keys they created and the keys limited to
+// environments within their scope.
+func (h *APIKeysHandler) ListAPIKeys(c *gin.Context) {
+	includeRevoked, _ := strconv.ParseBool(c.DefaultQuery("includeRevoked", "false"))
+	keys, err := h.apiKeys.List(includeRevoked)
+	if err != nil {
+		sendAPIKeyError(c, err, "Failed to list API keys")
+		return
+	}
+
+	visible := make([]models.APIKey, 0, len(keys))
+	for _, key := range keys {
+		if h.keyInScope(c, key) {
+			visible = append(visible, key)
+		}
+	}
+	utils.SendListResponse(c, visible)
+}
+
+// keyInScope reports whether the caller's data scope covers a key: the caller created it,
+// or every environment it is limited to is within the scope. Keys without environments
+// are only covered for unrestricted callers.
+func (h *APIKeysHandler) keyInScope(c *gin.Context, key models.APIKey) bool {
+	scope := callerScope(c)
+	if scope == nil || key.CreatedBy == author(c) {
+		return true
+	}
+	if len(key.Environments) == 0 || h.envService == nil {
+		return false
+	}
+	for _, id := range key.Environments {
+		env, err := h.envService.GetEnvironmentByID(id)
+		if err != nil || !scope.AllowsEnvironment(*env) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetAPIKey handles GET /api/v1/admin/api-keys/:id
+// Keys outside the caller's data scope are reported as not found.
+func (h *APIKeysHandler) GetAPIKey(c *gin.Context) {
+	key, err := h.apiKeys.Get(c.Param("id"))
+	if err == nil && !h.keyInScope(c, *key) {
+		err = services.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		sendAPIKeyError(c, err, "Failed to get API key")
+		return
+	}
+	utils.SendSuccessResponse(c, key)
+}
+
+// RevokeAPIKey handles DELETE /api/v1/admin/api-keys/:id. Revoked keys are kept, so
+// that who created, used and revoked them stays on record. Keys outside the caller's
+// data scope are reported as not found.
+func (h *APIKeysHandler) RevokeAPIKey(c *gin.Context) {
+	before, err := h.apiKeys.Get(c.Param("id"))
+	if err == nil && !h.keyInScope(c, *before) {
+		err = services.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		sendAPIKeyError(c, err, "Failed to revoke API key")
+		return
+	}
+	key, err := h.apiKeys.Revoke(c.Param("id"), author(c))
+	if err != nil {
+		sendAPIKeyError(c, err, "Failed to revoke API key")
+		return
+	}
+	auditChange(c, "apikey.revoke", "apikey:"+key.ID, before, key)
+	utils.SendSuccessResponse(c, key)
+}
+
+// sendAPIKeyError maps API key service errors to HTTP responses
+func sendAPIKeyError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		utils.SendErrorResponse(c, http.StatusNotFound, "API key not found")
+	case errors.Is(err, services.ErrInvalidAPIKeyRequest):
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendErrorResponse(c, http.StatusInternalServerError, fallback)
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-service/internal/config"
+	"golang-service/internal/database/dbtest"
+	"golang-service/internal/models"
+	"golang-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const apiKeysTestPolicy = `
+roles:
+  Atlas.Reader:
+    - GET /api/v1/vms
+  Atlas.KeyAdmin:
+    - "* /api/v1/admin/api-keys"
+  Atlas.Admin:
+    - "* /api/v1/**"
+data_access:
+  bypass_roles: [Atlas.Admin]
+  rules:
+    - roles: [Atlas.KeyAdmin]
+      environments: [prod]
+`
+
+// newAPIKeysRouter serves the API key endpoints to a caller whose subject, roles and
+// data scope are taken from the X-Subject, X-Roles and X-Scope headers
+func newAPIKeysRouter(t *testing.T) (*gin.Engine, *services.APIKeyService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	policy, err := config.ParsePolicy([]byte(apiKeysTestPolicy))
+	require.NoError(t, err)
+	apiKeys := services.NewAPIKeyService(dbtest.Open(t, &models.APIKey{}), 24*time.Hour)
+	handler := NewAPIKeysHandler(apiKeys, policy, nil)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Subject"))
+		c.Set("roles", c.Request.Header.Values("X-Roles"))
+		if environments := c.Request.Header.Values("X-Scope"); len(environments) > 0 {
+			c.Set("data_scope", &config.Scope{Environments: environments})
+		}
+	})
+	router.POST("/api-keys", handler.CreateAPIKey)
+	router.GET("/api-keys", handler.ListAPIKeys)
+	router.GET("/api-keys/:id", handler.GetAPIKey)
+	return router, apiKeys
+}
+
+func TestCreateAPIKeyRoles(t *testing.T) {
+	router, _ := newAPIKeysRouter(t)
+
+	create := func(roles []string, callerRoles ...string) int {
+		body, _ := json.Marshal(models.CreateAPIKeyRequest{Name: "automation", Roles: roles})
+		req, _ := http.NewRequest("POST", "/api-keys", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Subject", "alice")
+		for _, role := range callerRoles {
+			req.Header.Add("X-Roles", role)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Callers cannot hand out roles they do not hold themselves
+	assert.Equal(t, http.StatusForbidden, create([]string{"Atlas.Admin"}, "Atlas.Reader", "Atlas.KeyAdmin"))
+	assert.Equal(t, http.StatusForbidden, create([]string{"Atlas.Reader", "Atlas.Admin"}, "Atlas.Reader"))
+	assert.Equal(t, http.StatusCreated, create([]string{"Atlas.Reader"}, "Atlas.Reader"))
+
+	// Bypass roles may grant any role
+	assert.Equal(t, http.StatusCreated, create([]string{"Atlas.KeyAdmin"}, "Atlas.Admin"))
+	assert.Equal(t, http.StatusBadRequest, create([]string{"Atlas.Unknown"}, "Atlas.Admin"))
+}
+
+func TestListAPIKeysScoped(t *testing.T) {
+	router, apiKeys := newAPIKeysRouter(t)
+	own, err := apiKeys.Create(models.CreateAPIKeyRequest{Name: "own", Roles: []string{"Atlas.Reader"}, Environments: []string{"prod"}}, "alice")
+	require.NoError(t, err)
+	other, err := apiKeys.Create(models.CreateAPIKeyRequest{Name: "other", Roles: []string{"Atlas.Admin"}}, "bob")
+	require.NoError(t, err)
+
+	list := func(scope ...string) []string {
+		req, _ := http.NewRequest("GET", "/api-keys", nil)
+		req.Header.Set("X-Subject", "alice")
+		for _, env := range scope {
+			req.Header.Add("X-Scope", env)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data []models.APIKey `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		var ids []string
+		for _, key := range response.Data {
+			ids = append(ids, key.ID)
+		}
+		return ids
+	}
+
+	assert.ElementsMatch(t, []string{own.ID, other.ID}, list())
+	assert.Equal(t, []string{own.ID}, list("prod"))
+
+	// Keys outside the scope are not found either
+	req, _ := http.NewRequest("GET", "/api-keys/"+other.ID, nil)
+	req.Header.Set("X-Subject", "alice")
+	req.Header.Set("X-Scope", "prod")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
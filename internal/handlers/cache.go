@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"golang-service/internal/cache"
+	"golang-service/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheHandler handles cache administration requests
+type CacheHandler struct {
+	cache cache.Store
+}
+
+// InvalidateCacheRequest represents the request payload for invalidating cache tags
+type InvalidateCacheRequest struct {
+	Tags []string `json:"tags" binding:"required,min=1"`
+}
+
+// NewCacheHandler creates a new cache handler
+func NewCacheHandler(cache cache.Store) *CacheHandler {
+	return &CacheHandler{cache: cache}
+}
+
+// GetStats handles GET /api/v1/admin/cache/stats
+func (h *CacheHandler) GetStats(c *gin.Context) {
+	utils.SendSuccessResponse(c, h.cache.Stats())
+}
+
+// InvalidateTags handles POST /api/v1/admin/cache/invalidate
+func (h *CacheHandler) InvalidateTags(c *gin.Context) {
+	var req InvalidateCacheRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Request body must contain a non-empty 'tags' list")
+		return
+	}
+
+	removed, err := h.cache.InvalidateTags(c.Request.Context(), req.Tags...)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to invalidate cache")
+		return
+	}
+
+	auditChange(c, "cache.invalidate", "cache", nil, gin.H{"tags": req.Tags, "removed": removed})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cache invalidated successfully",
+		"tags":    req.Tags,
+		"removed": removed,
+	})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"golang-service/internal/middleware"
+	"golang-service/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// maxDevTokenTTL is the longest lifetime a dev token may be minted with
+const maxDevTokenTTL = 24 * time.Hour
+
+// reservedDevClaims are set by the signer and cannot be chosen
+var reservedDevClaims = map[string]bool{"iss": true, "aud": true, "exp": true, "iat": true, "nbf": true}
+
+// DevAuthHandler mints tokens in dev auth mode
+type DevAuthHandler struct {
+	signer       *middleware.DevSigner
+	defaultRoles []string
+}
+
+// DevTokenRequest represents the request payload for minting a dev token. Roles default
+// to DEV_ROLES when omitted; an empty list mints a token without roles.
+type DevTokenRequest struct {
+	Subject   string                 `json:"sub"`
+	Roles     []string               `json:"roles"`
+	Groups    []string               `json:"groups"`
+	ObjectID  string                 `json:"oid"`
+	Name      string                 `json:"name"`
+	Email     string                 `json:"email"`
+	Claims    map[string]interface{} `json:"claims"`
+	ExpiresIn string                 `json:"expiresIn"`
+}
+
+// NewDevAuthHandler creates a new dev auth handler
+func NewDevAuthHandler(signer *middleware.DevSigner, defaultRoles []string) *DevAuthHandler {
+	return &DevAuthHandler{signer: signer, defaultRoles: defaultRoles}
+}
+
+// IssueToken handles POST /dev/token
+func (h *DevAuthHandler) IssueToken(c *gin.Context) {
+	var req DevTokenRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	ttl := time.Hour
+	if req.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || parsed <= 0 || parsed > maxDevTokenTTL {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "expiresIn must be a positive duration of at most 24h")
+			return
+		}
+		ttl = parsed
+	}
+
+	claims := jwt.MapClaims{}
+	for name, value := range req.Claims {
+		if reservedDevClaims[name] {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Claim '"+name+"' is set by the server")
+			return
+		}
+		claims[name] = value
+	}
+
+	if req.Subject == "" {
+		req.Subject = "dev-user"
+	}
+	if req.Roles == nil {
+		req.Roles = h.defaultRoles
+	}
+	claims["sub"] = req.Subject
+	claims["roles"] = req.Roles
+	claims["tid"] = "dev-tenant"
+	claims["azp"] = "dev-client"
+	for name, value := range map[string]string{"oid": req.ObjectID, "name": req.Name, "email": req.Email} {
+		if value != "" {
+			claims[name] = value
+		}
+	}
+	if len(req.Groups) > 0 {
+		claims["groups"] = req.Groups
+	}
+
+	token, expiresAt, err := h.signer.Sign(claims, ttl)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to sign token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(ttl.Seconds()),
+		"expires_at":   expiresAt.UTC(),
+	})
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang-service/internal/config"
 	"golang-service/internal/models"
@@ -13,18 +17,32 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// VMInventory provides the VM inventory that environment reports are computed over
+type VMInventory interface {
+	Inventory(ctx context.Context) ([]models.VM, error)
+}
+
 // EnvironmentHandler handles environment-related requests
 type EnvironmentHandler struct {
 	envService *config.EnvironmentService
+	inventory  VMInventory
+	policy     *config.Policy
 }
 
-// NewEnvironmentHandler creates a new environment handler
-func NewEnvironmentHandler(envService *config.EnvironmentService) *EnvironmentHandler {
+// NewEnvironmentHandler creates a new environment handler. Requests that evaluate a
+// proposed configuration are checked against the policy.
+func NewEnvironmentHandler(envService *config.EnvironmentService, inventory VMInventory, policy *config.Policy) *EnvironmentHandler {
 	return &EnvironmentHandler{
 		envService: envService,
+		inventory:  inventory,
+		policy:     policy,
 	}
 }
 
+// importRoute is the permission a caller needs to evaluate proposed configurations:
+// they compile caller-supplied patterns, so they are limited to those who may apply them
+const importRoute = "/api/v1/environments/import"
+
 // ListEnvironments handles GET /api/v1/environments
 func (h *EnvironmentHandler) ListEnvironments(c *gin.Context) {
 	// Check if environment service is available
@@ -47,6 +65,42 @@ func (h *EnvironmentHandler) ListEnvironments(c *gin.Context) {
 		pageSize = 20
 	}
 
+	// Sorting by VM count needs the inventory, whose sync time then also versions the list
+	scope := callerScope(c)
+	var vmCounts map[string]int
+	var syncTime time.Time
+	if sortBy == "vmCount" {
+		vms, ok := h.loadInventory(c)
+		if !ok {
+			return
+		}
+		vmCounts = make(map[string]int)
+		for _, vm := range vms {
+			if vm.Env != "" {
+				vmCounts[vm.Env]++
+			}
+		}
+		syncTime = latestSyncTime(vms)
+	}
+
+	// Otherwise the list only changes when the configuration is reloaded
+	lastLoad := h.envService.GetLastLoadTime()
+	lastModified := lastLoad
+	if syncTime.After(lastModified) {
+		lastModified = syncTime
+	}
+	etag := utils.ETag(
+		"environments",
+		strconv.FormatInt(lastLoad.UnixNano(), 10),
+		strconv.FormatInt(syncTime.UnixNano(), 10),
+		scope.Key(),
+		c.Request.URL.Query().Encode(),
+		getBaseURL(c),
+	)
+	if utils.CheckNotModified(c, etag, lastModified) {
+		return
+	}
+
 	// Get all environments
 	environments, err := h.envService.GetEnvironments()
 	if err != nil {
@@ -54,8 +108,8 @@ func (h *EnvironmentHandler) ListEnvironments(c *gin.Context) {
 		return
 	}
 
-	// Apply filters if provided
-	filteredEnvs := h.applyFilters(environments, c)
+	// Apply filters if provided, within the caller's scope
+	filteredEnvs := h.applyFilters(scope.FilterEnvironments(environments), c)
 
 	// Apply sorting
 	sort.Slice(filteredEnvs, func(i, j int) bool {
@@ -65,6 +119,13 @@ func (h *EnvironmentHandler) ListEnvironments(c *gin.Context) {
 			less = strings.ToLower(filteredEnvs[i].Name) < strings.ToLower(filteredEnvs[j].Name)
 		case "description":
 			less = strings.ToLower(filteredEnvs[i].Description) < strings.ToLower(filteredEnvs[j].Description)
+		case "vmCount":
+			countI, countJ := vmCounts[filteredEnvs[i].ID], vmCounts[filteredEnvs[j].ID]
+			if countI == countJ {
+				// Keep equal counts in ID order whichever the direction
+				return strings.ToLower(filteredEnvs[i].ID) < strings.ToLower(filteredEnvs[j].ID)
+			}
+			less = countI < countJ
 		default:
 			less = strings.ToLower(filteredEnvs[i].ID) < strings.ToLower(filteredEnvs[j].ID)
 		}
@@ -105,10 +166,12 @@ func (h *EnvironmentHandler) ListEnvironments(c *gin.Context) {
 		Links: links,
 	}
 
+	c.Set(utils.ResultCountKey, totalItems)
 	c.JSON(http.StatusOK, response)
 }
 
 // GetEnvironment handles GET /api/v1/environments/:id
+// With include=summary the response also summarizes the VMs resolved to the environment.
 func (h *EnvironmentHandler) GetEnvironment(c *gin.Context) {
 	// Check if environment service is available
 	if h.envService == nil {
@@ -118,14 +181,54 @@ func (h *EnvironmentHandler) GetEnvironment(c *gin.Context) {
 
 	envID := c.Param("id")
 
+	// Environments outside the caller's scope are indistinguishable from missing ones
+	scope := callerScope(c)
 	environment, err := h.envService.GetEnvironmentByID(envID)
-	if err != nil {
+	if err != nil || !scope.AllowsEnvironment(*environment) {
 		utils.SendErrorResponse(c, http.StatusNotFound, "Environment not found")
 		return
 	}
 
-	// Build HATEOAS links for single environment
+	include := c.Query("include")
+	if include != "" && include != "summary" {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid include. Must be 'summary'")
+		return
+	}
+
+	// A summary changes with the inventory and with any configuration change affecting resolution
+	var summary *config.EnvironmentSummary
+	var syncTime time.Time
+	var configVersion int
+	lastModified := environment.UpdatedAt
+	if include == "summary" {
+		vms, ok := h.loadInventory(c)
+		if !ok {
+			return
+		}
+		summary = config.SummarizeEnvironment(vms, envID)
+		syncTime = latestSyncTime(vms)
+		configVersion = h.envService.ConfigVersion()
+		if syncTime.After(lastModified) {
+			lastModified = syncTime
+		}
+	}
+
 	baseURL := getBaseURL(c)
+	etag := utils.ETag(
+		"environment",
+		envID,
+		strconv.FormatInt(environment.UpdatedAt.UnixNano(), 10),
+		include,
+		strconv.FormatInt(syncTime.UnixNano(), 10),
+		strconv.Itoa(configVersion),
+		scope.Key(),
+		baseURL,
+	)
+	if utils.CheckNotModified(c, etag, lastModified) {
+		return
+	}
+
+	// Build HATEOAS links for single environment
 	links := models.HATEOASLinks{
 		Self: baseURL + "/api/v1/environments/" + envID,
 		VMs:  baseURL + "/api/v1/vms?env=" + envID,
@@ -136,10 +239,318 @@ func (h *EnvironmentHandler) GetEnvironment(c *gin.Context) {
 		"data":  environment,
 		"_links": links,
 	}
+	if summary != nil {
+		response["summary"] = summary
+	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// CreateEnvironment handles POST /api/v1/environments
+func (h *EnvironmentHandler) CreateEnvironment(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	var env models.Environment
+	if err := c.ShouldBindJSON(&env); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if !h.allowsWrite(c, env) {
+		return
+	}
+
+	created, err := h.envService.CreateEnvironment(env, author(c))
+	if err != nil {
+		sendEnvironmentError(c, err, "Failed to create environment")
+		return
+	}
+
+	auditChange(c, "environment.create", "environment:"+created.ID, nil, created)
+	c.JSON(http.StatusCreated, gin.H{"data": created})
+}
+
+// UpdateEnvironment handles PUT /api/v1/environments/:id
+func (h *EnvironmentHandler) UpdateEnvironment(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	var env models.Environment
+	if err := c.ShouldBindJSON(&env); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	env.ID = c.Param("id")
+	before, ok := h.scopedEnvironment(c, env.ID)
+	if !ok || !h.allowsWrite(c, env) {
+		return
+	}
+
+	updated, err := h.envService.UpdateEnvironment(c.Param("id"), env, author(c))
+	if err != nil {
+		sendEnvironmentError(c, err, "Failed to update environment")
+		return
+	}
+
+	auditChange(c, "environment.update", "environment:"+c.Param("id"), before, updated)
+	utils.SendSuccessResponse(c, updated)
+}
+
+// PatchEnvironment handles PATCH /api/v1/environments/:id
+// Fields present in the body replace the stored ones; criteria and metadata are merged key by key.
+func (h *EnvironmentHandler) PatchEnvironment(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	envID := c.Param("id")
+	current, ok := h.scopedEnvironment(c, envID)
+	if !ok {
+		return
+	}
+	if current == nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, "Environment not found")
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Decode into a deep copy so the patch never touches the shared configuration
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to update environment")
+		return
+	}
+	var patched models.Environment
+	if err := json.Unmarshal(currentJSON, &patched); err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to update environment")
+		return
+	}
+	if err := json.Unmarshal(body, &patched); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	patched.ID = envID
+	if !h.allowsWrite(c, patched) {
+		return
+	}
+
+	updated, err := h.envService.UpdateEnvironment(envID, patched, author(c))
+	if err != nil {
+		sendEnvironmentError(c, err, "Failed to update environment")
+		return
+	}
+
+	auditChange(c, "environment.update", "environment:"+envID, current, updated)
+	utils.SendSuccessResponse(c, updated)
+}
+
+// DeleteEnvironment handles DELETE /api/v1/environments/:id
+func (h *EnvironmentHandler) DeleteEnvironment(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	before, ok := h.scopedEnvironment(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if err := h.envService.DeleteEnvironment(c.Param("id"), author(c)); err != nil {
+		sendEnvironmentError(c, err, "Failed to delete environment")
+		return
+	}
+
+	auditChange(c, "environment.delete", "environment:"+c.Param("id"), before, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// ImportEnvironments handles POST /api/v1/environments/import
+// The body is an environments YAML document; mode=merge (default) or mode=replace.
+func (h *EnvironmentHandler) ImportEnvironments(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	mode := c.DefaultQuery("mode", config.ImportModeMerge)
+	if mode != config.ImportModeMerge && mode != config.ImportModeReplace {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid mode. Must be 'merge' or 'replace'")
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil || len(body) == 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Request body must contain an environments YAML document")
+		return
+	}
+
+	version := h.envService.ConfigVersion()
+	imported, err := h.envService.ImportEnvironments(body, mode, author(c))
+	if err != nil {
+		sendEnvironmentError(c, err, "Failed to import environments")
+		return
+	}
+
+	h.auditConfigChange(c, "environments.import", version)
+	environments, _ := h.envService.GetEnvironments()
+	c.JSON(http.StatusOK, models.EnvironmentImportResponse{
+		Message:  "Environments imported successfully",
+		Mode:     mode,
+		Imported: imported,
+		Total:    len(environments),
+	})
+}
+
+// GetEnvironmentChildren handles GET /api/v1/environments/:id/children
+func (h *EnvironmentHandler) GetEnvironmentChildren(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	children, err := h.envService.GetChildren(c.Param("id"), callerScope(c))
+	if err != nil {
+		sendEnvironmentError(c, err, "Failed to get environment children")
+		return
+	}
+
+	utils.SendListResponse(c, children)
+}
+
+// GetEnvironmentTree handles GET /api/v1/environments/tree. With ?root=<id> only the
+// subtree below that environment is returned.
+func (h *EnvironmentHandler) GetEnvironmentTree(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	tree, err := h.envService.GetEnvironmentTree(c.Query("root"), callerScope(c))
+	if err != nil {
+		sendEnvironmentError(c, err, "Failed to build environment tree")
+		return
+	}
+
+	utils.SendSuccessResponse(c, tree)
+}
+
+// ValidateEnvironments handles POST /api/v1/environments/validate. The body is an environments
+// YAML document; without a body the active configuration is validated.
+func (h *EnvironmentHandler) ValidateEnvironments(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+
+	if len(body) == 0 {
+		if h.envService == nil {
+			utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+			return
+		}
+		body, err = h.envService.ExportEnvironments(callerScope(c))
+		if err != nil {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to export environments")
+			return
+		}
+	}
+
+	utils.SendSuccessResponse(c, config.ValidateEnvironmentConfig(body))
+}
+
+// ExportEnvironments handles GET /api/v1/environments/export
+func (h *EnvironmentHandler) ExportEnvironments(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	data, err := h.envService.ExportEnvironments(callerScope(c))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to export environments")
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="environments.yaml"`)
+	c.Data(http.StatusOK, "application/yaml", data)
+}
+
+// sendEnvironmentError maps environment store errors to HTTP responses
+func sendEnvironmentError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, config.ErrEnvironmentNotFound):
+		utils.SendErrorResponse(c, http.StatusNotFound, "Environment not found")
+	case errors.Is(err, config.ErrEnvironmentExists):
+		utils.SendErrorResponse(c, http.StatusConflict, "Environment already exists")
+	case errors.Is(err, config.ErrInvalidEnvironment):
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, config.ErrReadOnly):
+		utils.SendErrorResponse(c, http.StatusConflict, "Environment configuration is read-only")
+	case errors.Is(err, config.ErrVersionNotFound):
+		utils.SendErrorResponse(c, http.StatusNotFound, "Configuration version not found")
+	default:
+		utils.SendErrorResponse(c, http.StatusInternalServerError, fallback)
+	}
+}
+
+// scopedEnvironment returns the environment a change targets, or nil when it does not
+// exist. It sends a 404 when the environment is outside the caller's scope, which is
+// indistinguishable from missing, or when a scoped caller targets a missing one.
+func (h *EnvironmentHandler) scopedEnvironment(c *gin.Context, id string) (*models.Environment, bool) {
+	scope := callerScope(c)
+	environment, err := h.envService.GetEnvironmentByID(id)
+	if err != nil {
+		environment = nil
+	}
+	if scope != nil && (environment == nil || !scope.AllowsEnvironment(*environment)) {
+		utils.SendErrorResponse(c, http.StatusNotFound, "Environment not found")
+		return nil, false
+	}
+	return environment, true
+}
+
+// allowsWrite checks that an environment as written stays in the caller's scope, judging
+// it with its ancestors under its new parent, and sends a 403 when it would not
+func (h *EnvironmentHandler) allowsWrite(c *gin.Context, env models.Environment) bool {
+	scope := callerScope(c)
+	if scope == nil {
+		return true
+	}
+
+	env.Ancestors = nil
+	if env.Parent != "" {
+		if parent, err := h.envService.GetEnvironmentByID(env.Parent); err == nil {
+			env.Ancestors = append(append([]string{}, parent.Ancestors...), parent.ID)
+		}
+	}
+	if !scope.AllowsEnvironment(env) {
+		utils.SendErrorResponse(c, http.StatusForbidden, "Environment would be outside your data scope: "+env.ID)
+		return false
+	}
+	return true
+}
+
+// author returns the authenticated user making a configuration change
+func author(c *gin.Context) string {
+	if userID := c.GetString("user_id"); userID != "" {
+		return userID
+	}
+	return "anonymous"
+}
+
 // applyFilters applies query parameter filters to environments
 func (h *EnvironmentHandler) applyFilters(environments []models.Environment, c *gin.Context) []models.Environment {
 	filtered := environments
@@ -245,16 +656,18 @@ func (h *EnvironmentHandler) ReloadConfig(c *gin.Context) {
 		return
 	}
 
-	if err := h.envService.ReloadConfig(); err != nil {
+	// The configuration is validated before it is applied: an invalid one is rejected
+	// and the current one stays active, unaudited
+	version := h.envService.ConfigVersion()
+	if err := h.envService.ReloadConfig(author(c)); err != nil {
+		if errors.Is(err, config.ErrInvalidEnvironment) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to reload configuration")
 		return
 	}
-
-	// Validate the reloaded configuration
-	if err := h.envService.ValidateConfig(); err != nil {
-		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid configuration")
-		return
-	}
+	h.auditConfigChange(c, "environments.reload", version)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":   "Configuration reloaded successfully",
@@ -275,6 +688,7 @@ func (h *EnvironmentHandler) GetConfigInfo(c *gin.Context) {
 		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to get configuration info")
 		return
 	}
+	environments = callerScope(c).FilterEnvironments(environments)
 
 	// Count environments by various criteria
 	accountCount := make(map[string]int)
@@ -292,7 +706,9 @@ func (h *EnvironmentHandler) GetConfigInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"totalEnvironments": len(environments),
 		"configPath":        h.envService.GetConfigPath(),
+		"writable":          h.envService.IsWritable(),
 		"lastLoaded":        h.envService.GetLastLoadTime(),
+		"configVersion":     h.envService.ConfigVersion(),
 		"accounts":          accountCount,
 		"regions":           regionCount,
 		"tags":              tagCount,
@@ -307,4 +723,272 @@ func getBaseURL(c *gin.Context) string {
 	}
 	host := c.Request.Host
 	return scheme + "://" + host
-} 
\ No newline at end of file
+} 
+
+// ResolveEnvironment handles POST /api/v1/environments/resolve
+func (h *EnvironmentHandler) ResolveEnvironment(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	var req models.EnvironmentResolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if (req.VMID == "") == !req.HasAttributes() {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Specify either vmId or VM attributes")
+		return
+	}
+
+	vm := req.VM()
+	if req.VMID != "" {
+		vms, ok := h.loadInventory(c)
+		if !ok {
+			return
+		}
+		found := false
+		for _, candidate := range vms {
+			if candidate.ID == req.VMID {
+				vm, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			utils.SendErrorResponse(c, http.StatusNotFound, "VM not found")
+			return
+		}
+	}
+
+	source := "active"
+	var resolution config.Resolution
+	if req.ProposedConfig != "" {
+		if h.policy == nil || !h.policy.Allows(callerRoles(c), http.MethodPost, importRoute) {
+			utils.SendErrorResponse(c, http.StatusForbidden, "Evaluating a proposed configuration requires permission to import environments")
+			return
+		}
+		proposed, err := config.ParseEnvironmentConfig([]byte(req.ProposedConfig))
+		if err == nil {
+			err = config.ValidateEnvironments(proposed.Environments)
+		}
+		if err != nil {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid proposed configuration: "+err.Error())
+			return
+		}
+		source = "proposed"
+		resolution = config.ExplainResolution(proposed.Environments, vm)
+	} else {
+		var err error
+		resolution, err = h.envService.ExplainResolution(vm)
+		if err != nil {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to evaluate environments")
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   callerScope(c).FilterResolution(resolution),
+		"source": source,
+	})
+}
+
+// GetAmbiguities handles GET /api/v1/environments/ambiguities
+func (h *EnvironmentHandler) GetAmbiguities(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+	vms, ok := h.loadInventory(c)
+	if !ok {
+		return
+	}
+
+	ambiguities, err := h.envService.DetectAmbiguities(vms)
+	if err == nil {
+		var environments []models.Environment
+		if environments, err = h.envService.GetEnvironments(); err == nil {
+			ambiguities = callerScope(c).FilterAmbiguities(ambiguities, environments)
+		}
+	}
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to evaluate environments")
+		return
+	}
+
+	ambiguousVMs := 0
+	for _, ambiguity := range ambiguities {
+		ambiguousVMs += ambiguity.VMCount
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":         ambiguities,
+		"totalVms":     len(vms),
+		"ambiguousVms": ambiguousVMs,
+	})
+}
+
+// GetCoverage handles GET /api/v1/environments/coverage
+func (h *EnvironmentHandler) GetCoverage(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	vms, ok := h.loadInventory(c)
+	if !ok {
+		return
+	}
+
+	coverage, err := h.envService.ComputeCoverage(vms)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to evaluate environments")
+		return
+	}
+
+	// Only report unused environments the caller may see
+	if scope := callerScope(c); scope != nil {
+		unused := []config.UnusedEnvironment{}
+		for _, env := range coverage.UnusedEnvironments {
+			if environment, err := h.envService.GetEnvironmentByID(env.ID); err == nil && scope.AllowsEnvironment(*environment) {
+				unused = append(unused, env)
+			}
+		}
+		coverage.UnusedEnvironments = unused
+	}
+
+	utils.SendSuccessResponse(c, coverage)
+}
+
+// loadInventory fetches the VM inventory within the caller's scope, sending an error response
+// when it is unavailable
+func (h *EnvironmentHandler) loadInventory(c *gin.Context) ([]models.VM, bool) {
+	if h.inventory == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "VM inventory is not available")
+		return nil, false
+	}
+
+	vms, err := h.inventory.Inventory(c.Request.Context())
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to fetch VMs")
+		return nil, false
+	}
+	return callerScope(c).FilterVMs(vms), true
+}
+
+// ListVersions handles GET /api/v1/environments/versions
+// Versions are listed newest first, without their content.
+func (h *EnvironmentHandler) ListVersions(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 1000 {
+		pageSize = 20
+	}
+
+	versions, total, err := h.envService.ListVersions(page, pageSize, callerScope(c))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to list configuration versions")
+		return
+	}
+
+	utils.SendPaginatedResponse(c, versions, page, pageSize, int(total))
+}
+
+// GetVersion handles GET /api/v1/environments/versions/:version
+func (h *EnvironmentHandler) GetVersion(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	number, ok := versionParam(c, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	version, err := h.envService.GetVersion(number, callerScope(c))
+	if err != nil {
+		sendEnvironmentError(c, err, "Failed to get configuration version")
+		return
+	}
+
+	utils.SendSuccessResponse(c, version)
+}
+
+// DiffVersions handles GET /api/v1/environments/versions/diff?from=&to=
+// to defaults to the active version and from to the one before it.
+func (h *EnvironmentHandler) DiffVersions(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	to := h.envService.ConfigVersion()
+	if value := c.Query("to"); value != "" {
+		var ok bool
+		if to, ok = versionParam(c, value); !ok {
+			return
+		}
+	}
+	from := to - 1
+	if value := c.Query("from"); value != "" {
+		var ok bool
+		if from, ok = versionParam(c, value); !ok {
+			return
+		}
+	}
+
+	diff, err := h.envService.DiffVersions(from, to, callerScope(c))
+	if err != nil {
+		sendEnvironmentError(c, err, "Failed to diff configuration versions")
+		return
+	}
+
+	utils.SendSuccessResponse(c, diff)
+}
+
+// RollbackToVersion handles POST /api/v1/environments/rollback/:version
+// The rollback applies the old configuration as a new version.
+func (h *EnvironmentHandler) RollbackToVersion(c *gin.Context) {
+	if h.envService == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Environment configuration service is not available")
+		return
+	}
+
+	number, ok := versionParam(c, c.Param("version"))
+	if !ok {
+		return
+	}
+
+	version := h.envService.ConfigVersion()
+	active, err := h.envService.RollbackToVersion(number, author(c))
+	if err != nil {
+		sendEnvironmentError(c, err, "Failed to roll back configuration")
+		return
+	}
+	h.auditConfigChange(c, "environments.rollback", version)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Configuration rolled back successfully",
+		"rolledBackTo":  number,
+		"activeVersion": active,
+	})
+}
+
+// versionParam parses a configuration version number, sending a 400 when it is invalid
+func versionParam(c *gin.Context, value string) (int, bool) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid configuration version: "+value)
+		return 0, false
+	}
+	return number, true
+}
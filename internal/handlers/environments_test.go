@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang-service/internal/config"
+	"golang-service/internal/database/dbtest"
+	"golang-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const environmentsTestYAML = `environments:
+  - id: "prod"
+    name: "AWS Production"
+    criteria:
+      cloud_type: "aws"
+      account: "123456789012"
+      region: "eu-west-1"
+  - id: "prod-eu"
+    name: "AWS Production EU"
+    parent: "prod"
+    criteria:
+      vpc: "vpc-12345678"
+  - id: "dev"
+    name: "AWS Development"
+    criteria:
+      cloud_type: "aws"
+      account: "210987654321"
+      region: "eu-west-1"
+`
+
+// newEnvironmentsRouter serves the environment write endpoints to a caller whose data
+// scope is taken from the X-Scope header
+func newEnvironmentsRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "environments.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(environmentsTestYAML), 0o644))
+	envService := config.NewDatabaseEnvironmentService(dbtest.Open(t, &models.Environment{}, &models.EnvironmentConfigVersion{}), path)
+	require.NoError(t, envService.LoadConfig())
+	handler := NewEnvironmentHandler(envService, nil, nil)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "alice")
+		if environments := c.Request.Header.Values("X-Scope"); len(environments) > 0 {
+			c.Set("data_scope", &config.Scope{Environments: environments})
+		}
+	})
+	router.POST("/environments", handler.CreateEnvironment)
+	router.PUT("/environments/:id", handler.UpdateEnvironment)
+	router.PATCH("/environments/:id", handler.PatchEnvironment)
+	router.DELETE("/environments/:id", handler.DeleteEnvironment)
+	return router
+}
+
+func TestEnvironmentWritesScoped(t *testing.T) {
+	router := newEnvironmentsRouter(t)
+
+	send := func(method, path string, body interface{}) int {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Scope", "prod")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Environments outside the scope cannot be changed and look missing
+	assert.Equal(t, http.StatusNotFound, send("PUT", "/environments/dev", models.Environment{Name: "Dev", Criteria: models.EnvironmentCriteria{CloudType: "aws", Account: "210987654321", Region: "eu-west-1"}}))
+	assert.Equal(t, http.StatusNotFound, send("PATCH", "/environments/dev", map[string]string{"name": "Dev"}))
+	assert.Equal(t, http.StatusNotFound, send("DELETE", "/environments/dev", nil))
+
+	// Nor can environments be moved or created outside it
+	assert.Equal(t, http.StatusForbidden, send("PATCH", "/environments/prod-eu", map[string]string{"parent": "dev"}))
+	assert.Equal(t, http.StatusForbidden, send("POST", "/environments", models.Environment{ID: "dev-eu", Name: "Dev EU", Parent: "dev"}))
+
+	assert.Equal(t, http.StatusOK, send("PATCH", "/environments/prod-eu", map[string]string{"name": "Production EU"}))
+	assert.Equal(t, http.StatusCreated, send("POST", "/environments", models.Environment{ID: "prod-us", Name: "Production US", Parent: "prod", Criteria: models.EnvironmentCriteria{VPC: "vpc-87654321"}}))
+	assert.Equal(t, http.StatusNoContent, send("DELETE", "/environments/prod-us", nil))
+}
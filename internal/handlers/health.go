@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"time"
 
+	"golang-service/internal/cache"
+	"golang-service/internal/middleware"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -16,6 +20,8 @@ type HealthResponse struct {
 	Uptime    string            `json:"uptime"`
 	Database  DatabaseStatus    `json:"database"`
 	Services  map[string]string `json:"services"`
+	// JWKS reports the signing key sets tokens are verified against
+	JWKS []middleware.KeySetStatus `json:"jwks,omitempty"`
 }
 
 // DatabaseStatus represents the database connectivity status
@@ -52,6 +58,23 @@ func HealthCheck(c *gin.Context) {
 		},
 	}
 
+	// Report Redis state if the cache is configured. The service keeps serving from
+	// the in-memory cache without Redis, so this does not affect the overall status.
+	if value, exists := c.Get("redis"); exists {
+		if redisCache, ok := value.(*cache.RedisCache); ok {
+			response.Services["redis"] = redisStatus(c.Request.Context(), redisCache)
+		}
+	}
+
+	// Report whether signing keys could be fetched. Requests authenticate with the last
+	// keys fetched, so this does not affect the overall status either.
+	if value, exists := c.Get("jwks"); exists {
+		if verifier, ok := value.(*middleware.TokenVerifier); ok && verifier != nil {
+			response.JWKS = verifier.Status()
+			response.Services["jwks"] = jwksStatus(response.JWKS)
+		}
+	}
+
 	// Check database connectivity if available
 	if db, exists := c.Get("db"); exists {
 		if gormDB, ok := db.(*gorm.DB); ok {
@@ -88,4 +111,51 @@ func DatabaseHealthMiddleware(db *gorm.DB) gin.HandlerFunc {
 		c.Set("db", db)
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// RedisHealthMiddleware adds the Redis cache to context for health checks.
+// A nil cache means Redis is disabled by configuration.
+func RedisHealthMiddleware(redisCache *cache.RedisCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("redis", redisCache)
+		c.Next()
+	}
+}
+
+// JWKSHealthMiddleware adds the token verifier to context for health checks
+func JWKSHealthMiddleware(verifier *middleware.TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("jwks", verifier)
+		c.Next()
+	}
+}
+
+// jwksStatus summarizes key sets for the services map: unhealthy when a key set has no
+// keys, degraded when one has keys but its last refresh failed
+func jwksStatus(statuses []middleware.KeySetStatus) string {
+	status := "healthy"
+	for _, keySet := range statuses {
+		if keySet.Keys == 0 {
+			return "unhealthy"
+		}
+		if !keySet.Healthy {
+			status = "degraded"
+		}
+	}
+	return status
+}
+
+// redisStatus pings Redis and returns its state for the services map
+func redisStatus(ctx context.Context, redisCache *cache.RedisCache) string {
+	if redisCache == nil {
+		return "disabled"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if err := redisCache.Ping(ctx); err != nil {
+		return "unhealthy"
+	}
+	return "healthy"
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+
+	"golang-service/internal/config"
+	"golang-service/internal/models"
+	"golang-service/internal/services"
+	"golang-service/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MeHandler handles requests about the caller themselves
+type MeHandler struct {
+	users      *services.UserService
+	envService *config.EnvironmentService
+}
+
+// NewMeHandler creates a new handler for the caller's profile
+func NewMeHandler(users *services.UserService, envService *config.EnvironmentService) *MeHandler {
+	return &MeHandler{users: users, envService: envService}
+}
+
+// GetMe handles GET /api/v1/me
+// It returns the caller's user profile, effective roles, accessible environments and preferences.
+func (h *MeHandler) GetMe(c *gin.Context) {
+	groups, _ := c.Get("groups")
+	groupList, _ := groups.([]string)
+	me := models.MeResponse{
+		User:         callerUser(c),
+		Subject:      c.GetString("user_id"),
+		ObjectID:     c.GetString("object_id"),
+		TenantID:     c.GetString("tenant_id"),
+		ClientID:     c.GetString("client_id"),
+		Issuer:       c.GetString("issuer"),
+		AuthMethod:   c.GetString("auth_method"),
+		Roles:        callerRoles(c),
+		Groups:       groupList,
+		Environments: []string{},
+		Preferences:  map[string]interface{}{},
+	}
+	if me.Roles == nil {
+		me.Roles = []string{}
+	}
+	if me.Groups == nil {
+		me.Groups = []string{}
+	}
+	if me.User != nil && me.User.Preferences != nil {
+		me.Preferences = me.User.Preferences
+	}
+
+	scope := callerScope(c)
+	me.AllEnvironments = scope == nil
+	if h.envService != nil {
+		environments, err := h.envService.GetEnvironments()
+		if err != nil {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to load environments")
+			return
+		}
+		for _, env := range scope.FilterEnvironments(environments) {
+			me.Environments = append(me.Environments, env.ID)
+		}
+	}
+
+	utils.SendSuccessResponse(c, me)
+}
+
+// UpdatePreferences handles PATCH /api/v1/me/preferences
+// Keys in the body replace the stored ones; keys set to null are removed.
+func (h *MeHandler) UpdatePreferences(c *gin.Context) {
+	user := callerUser(c)
+	if user == nil {
+		utils.SendErrorResponse(c, http.StatusNotFound, "No user is linked to this caller")
+		return
+	}
+
+	var preferences map[string]interface{}
+	if err := c.ShouldBindJSON(&preferences); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Request body must be a JSON object")
+		return
+	}
+
+	updated, err := h.users.UpdatePreferences(user.ID, preferences)
+	if err != nil {
+		sendUserError(c, err, "Failed to update preferences")
+		return
+	}
+
+	utils.SendSuccessResponse(c, updated.Preferences)
+}
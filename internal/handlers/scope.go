@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"golang-service/internal/config"
+	"golang-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// callerScope returns the data scope set by the DataScope middleware, or nil when the
+// caller may see everything
+func callerScope(c *gin.Context) *config.Scope {
+	value, _ := c.Get("data_scope")
+	scope, _ := value.(*config.Scope)
+	return scope
+}
+
+// callerUser returns the user stored by the ProvisionUser middleware, or nil when the
+// caller is not a user
+func callerUser(c *gin.Context) *models.User {
+	value, _ := c.Get("user")
+	user, _ := value.(*models.User)
+	return user
+}
+
+// callerRoles returns the app roles stored in the context by Authenticate
+func callerRoles(c *gin.Context) []string {
+	roles, _ := c.Get("roles")
+	list, _ := roles.([]string)
+	return list
+}
@@ -1,29 +1,61 @@
 package handlers
 
 import (
-	"golang-service/internal/models"
-	"golang-service/internal/utils"
-	"fmt"
+	"errors"
 	"net/http"
-	"reflect"
-	"sort"
+	"strconv"
 	"strings"
 
+	"golang-service/internal/models"
+	"golang-service/internal/services"
+	"golang-service/internal/utils"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// userColumns maps the fields users can be filtered and sorted on to their columns
+var userColumns = map[string]string{
+	"id":         "id",
+	"email":      "email",
+	"name":       "name",
+	"azure_id":   "azure_id",
+	"azureId":    "azure_id",
+	"issuer":     "issuer",
+	"active":     "is_active",
+	"isActive":   "is_active",
+	"created_at": "created_at",
+	"createdAt":  "created_at",
+	"updated_at": "updated_at",
+	"updatedAt":  "updated_at",
+}
+
+// defaultUsersPerPage is the page size of user listings without per_page
+const defaultUsersPerPage = 10
+
+// PaginatedResponse is one page of a listing with its position among all pages
+type PaginatedResponse struct {
+	Page       int         `json:"page"`
+	PerPage    int         `json:"per_page"`
+	Total      int64       `json:"total"`
+	TotalPages int         `json:"total_pages"`
+	Data       interface{} `json:"data"`
+}
+
 // UsersHandler handles user-related HTTP requests
 type UsersHandler struct {
-	db *gorm.DB
+	users *services.UserService
 }
 
 // NewUsersHandler creates a new users handler
-func NewUsersHandler(db *gorm.DB) *UsersHandler {
-	return &UsersHandler{db: db}
+func NewUsersHandler(users *services.UserService) *UsersHandler {
+	return &UsersHandler{users: users}
 }
 
 // GetUsers handles GET /api/v1/users
+// Filters, sorting and pagination are applied by the database. search matches name and
+// email; deleted=true lists deleted users instead; per_page, or pageSize as elsewhere in
+// the API, sets the page size.
 func (h *UsersHandler) GetUsers(c *gin.Context) {
 	// Parse query parameters using the reusable utility
 	params, err := utils.ParseQueryParams(c)
@@ -31,6 +63,38 @@ func (h *UsersHandler) GetUsers(c *gin.Context) {
 		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	// ParseQueryParams has already read and checked pageSize
+	if c.Query("pageSize") == "" {
+		params.PageSize = defaultUsersPerPage
+	}
+	if perPage := c.Query("per_page"); perPage != "" {
+		if params.PageSize, err = strconv.Atoi(perPage); err != nil || params.PageSize < 1 || params.PageSize > 1000 {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "per_page must be between 1 and 1000: "+perPage)
+			return
+		}
+	}
+
+	// search, deleted, per_page and pageSize are not column filters
+	search := strings.TrimSpace(c.Query("search"))
+	deleted, _ := strconv.ParseBool(c.DefaultQuery("deleted", "false"))
+	filters := make([]utils.QueryFilter, 0, len(params.Filters))
+	for _, filter := range params.Filters {
+		if filter.Field == "search" || filter.Field == "deleted" || filter.Field == "per_page" || filter.Field == "pageSize" {
+			continue
+		}
+		// Boolean columns compare as booleans, whatever the database stores them as
+		if userColumns[filter.Field] == "is_active" && filter.Operator == "eq" {
+			value, _ := filter.Value.(string)
+			active, err := strconv.ParseBool(value)
+			if err != nil {
+				utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid boolean for "+filter.Field)
+				return
+			}
+			filter.Value = active
+		}
+		filters = append(filters, filter)
+	}
+	params.Filters = filters
 
 	// Validate query parameters
 	if err := utils.ValidateQueryParams(params); err != nil {
@@ -38,87 +102,165 @@ func (h *UsersHandler) GetUsers(c *gin.Context) {
 		return
 	}
 
-	// Get users from database
-	var users []models.User
-	if err := h.db.Find(&users).Error; err != nil {
+	filter, err := utils.SQLFilters(params.Filters, userColumns)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	sort, err := utils.SQLSort(params.SortBy, params.SortOrder, userColumns)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, total, err := h.users.List(func(tx *gorm.DB) *gorm.DB {
+		if search != "" {
+			pattern := "%" + strings.ToLower(search) + "%"
+			tx = tx.Where("LOWER(name) LIKE ? OR LOWER(email) LIKE ?", pattern, pattern)
+		}
+		return tx.Scopes(filter, sort)
+	}, deleted, params.Page, params.PageSize)
+	if err != nil {
 		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to fetch users")
 		return
 	}
 
-	// Apply filters using the reusable utility
-	filteredUsers := utils.ApplyInMemoryFilters(users, params.Filters, h.extractUserField)
+	totalPages := int((total + int64(params.PageSize) - 1) / int64(params.PageSize))
+	c.Set(utils.ResultCountKey, int(total))
+	c.JSON(http.StatusOK, PaginatedResponse{
+		Page:       params.Page,
+		PerPage:    params.PageSize,
+		Total:      total,
+		TotalPages: totalPages,
+		Data:       users,
+	})
+}
 
-	// Apply sorting
-	sortedUsers := h.applySorting(filteredUsers, params.SortBy, params.SortOrder)
+// GetUser handles GET /api/v1/users/:id
+func (h *UsersHandler) GetUser(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
 
-	// Calculate pagination
-	totalItems := len(sortedUsers)
-	paginatedUsers := utils.ApplyPagination(sortedUsers, params.Page, params.PageSize)
+	user, err := h.users.Get(id)
+	if err != nil {
+		sendUserError(c, err, "Failed to fetch user")
+		return
+	}
 
-	// Send response using the reusable utility
-	utils.SendPaginatedResponse(c, paginatedUsers, params.Page, params.PageSize, totalItems)
+	c.JSON(http.StatusOK, user)
 }
 
-// extractUserField extracts field value from User struct for filtering
-func (h *UsersHandler) extractUserField(user models.User, field string) interface{} {
-	v := reflect.ValueOf(user)
-	t := v.Type()
-
-	for i := 0; i < v.NumField(); i++ {
-		fieldType := t.Field(i)
-		fieldValue := v.Field(i)
-
-		// Check JSON tag first, then field name
-		jsonTag := fieldType.Tag.Get("json")
-		if jsonTag != "" {
-			jsonField := strings.Split(jsonTag, ",")[0]
-			if jsonField == field {
-				return fieldValue.Interface()
-			}
-		}
+// CreateUser handles POST /api/v1/users
+func (h *UsersHandler) CreateUser(c *gin.Context) {
+	var req models.CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Request body must contain 'name' and a valid 'email'")
+		return
+	}
 
-		// Check field name
-		if strings.EqualFold(fieldType.Name, field) {
-			return fieldValue.Interface()
+	// An object ID given without its issuer is one from the caller's own issuer
+	if req.AzureID != "" && req.Issuer == "" {
+		req.Issuer = c.GetString("issuer_namespace")
+	}
+
+	user, err := h.users.Create(req)
+	if err != nil {
+		sendUserError(c, err, "Failed to create user")
+		return
+	}
+
+	auditChange(c, "user.create", "user:"+strconv.FormatUint(uint64(user.ID), 10), nil, user)
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUser handles PUT and PATCH /api/v1/users/:id
+// Fields present in the body are changed; the others keep their value.
+func (h *UsersHandler) UpdateUser(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	// A new object ID given without its issuer is one from the caller's own issuer
+	if req.AzureID != nil && *req.AzureID != "" && req.Issuer == nil {
+		if issuer := c.GetString("issuer_namespace"); issuer != "" {
+			req.Issuer = &issuer
 		}
 	}
 
-	return nil
+	before, _ := h.users.Get(id)
+	user, err := h.users.Update(id, req)
+	if err != nil {
+		sendUserError(c, err, "Failed to update user")
+		return
+	}
+
+	auditChange(c, "user.update", "user:"+c.Param("id"), before, user)
+	c.JSON(http.StatusOK, user)
 }
 
-// applySorting applies sorting to users
-func (h *UsersHandler) applySorting(users []models.User, sortBy, sortOrder string) []models.User {
-	if sortBy == "" {
-		return users
+// DeleteUser handles DELETE /api/v1/users/:id
+// The user is soft deleted and can be restored.
+func (h *UsersHandler) DeleteUser(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
 	}
 
-	sortedUsers := make([]models.User, len(users))
-	copy(sortedUsers, users)
+	user, err := h.users.Delete(id)
+	if err != nil {
+		sendUserError(c, err, "Failed to delete user")
+		return
+	}
 
-	sort.Slice(sortedUsers, func(i, j int) bool {
-		valI := h.extractUserField(sortedUsers[i], sortBy)
-		valJ := h.extractUserField(sortedUsers[j], sortBy)
+	auditChange(c, "user.delete", "user:"+c.Param("id"), user, nil)
+	c.Status(http.StatusNoContent)
+}
 
-		// Handle nil values
-		if valI == nil && valJ == nil {
-			return false
-		}
-		if valI == nil {
-			return sortOrder == "asc"
-		}
-		if valJ == nil {
-			return sortOrder == "desc"
-		}
+// RestoreUser handles POST /api/v1/users/:id/restore
+func (h *UsersHandler) RestoreUser(c *gin.Context) {
+	id, ok := userID(c)
+	if !ok {
+		return
+	}
 
-		// Convert to string for comparison
-		strI := strings.ToLower(fmt.Sprintf("%v", valI))
-		strJ := strings.ToLower(fmt.Sprintf("%v", valJ))
+	user, err := h.users.Restore(id)
+	if err != nil {
+		sendUserError(c, err, "Failed to restore user")
+		return
+	}
 
-		if sortOrder == "desc" {
-			return strI > strJ
-		}
-		return strI < strJ
-	})
+	auditChange(c, "user.restore", "user:"+c.Param("id"), nil, user)
+	c.JSON(http.StatusOK, user)
+}
 
-	return sortedUsers
-}
\ No newline at end of file
+// userID parses the user ID path parameter, sending a 400 when it is invalid
+func userID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil || id == 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid user ID: "+c.Param("id"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// sendUserError maps user service errors to HTTP responses
+func sendUserError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		utils.SendErrorResponse(c, http.StatusNotFound, "User not found")
+	case errors.Is(err, services.ErrUserConflict):
+		utils.SendErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidUserRequest):
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendErrorResponse(c, http.StatusInternalServerError, fallback)
+	}
+}
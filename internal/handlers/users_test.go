@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"golang-service/internal/models"
+	"golang-service/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -43,7 +44,7 @@ func TestGetUsers(t *testing.T) {
 	
 	t.Run("Get all users with default pagination", func(t *testing.T) {
 		db := setupTestDB()
-		handler := New(db)
+		handler := NewUsersHandler(services.NewUserService(db))
 		
 		// Create test users
 		for _, user := range testUsers {
@@ -81,7 +82,7 @@ func TestGetUsers(t *testing.T) {
 	
 	t.Run("Get users with custom pagination", func(t *testing.T) {
 		db := setupTestDB()
-		handler := New(db)
+		handler := NewUsersHandler(services.NewUserService(db))
 		
 		// Create test users
 		for _, user := range testUsers {
@@ -118,7 +119,7 @@ func TestGetUsers(t *testing.T) {
 	
 	t.Run("Get users with search filter", func(t *testing.T) {
 		db := setupTestDB()
-		handler := New(db)
+		handler := NewUsersHandler(services.NewUserService(db))
 		
 		// Create test users
 		for _, user := range testUsers {
@@ -152,7 +153,7 @@ func TestGetUsers(t *testing.T) {
 	
 	t.Run("Get users with active filter", func(t *testing.T) {
 		db := setupTestDB()
-		handler := New(db)
+		handler := NewUsersHandler(services.NewUserService(db))
 		
 		// Create test users
 		for _, user := range testUsers {
@@ -193,7 +194,7 @@ func TestGetUser(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	db := setupTestDB()
-	handler := New(db)
+	handler := NewUsersHandler(services.NewUserService(db))
 	
 	// Create test user
 	user := models.User{
@@ -249,7 +250,7 @@ func TestCreateUser(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	db := setupTestDB()
-	handler := New(db)
+	handler := NewUsersHandler(services.NewUserService(db))
 	
 	t.Run("Create valid user", func(t *testing.T) {
 		router := gin.New()
@@ -342,7 +343,7 @@ func TestUpdateUser(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	db := setupTestDB()
-	handler := New(db)
+	handler := NewUsersHandler(services.NewUserService(db))
 	
 	// Create test user
 	user := models.User{
@@ -410,7 +411,7 @@ func TestDeleteUser(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	db := setupTestDB()
-	handler := New(db)
+	handler := NewUsersHandler(services.NewUserService(db))
 	
 	// Create test user
 	user := models.User{
@@ -457,4 +458,34 @@ func TestDeleteUser(t *testing.T) {
 		
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
-}
\ No newline at end of file
+}
+func TestGetUsersPageSizeAlias(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB()
+	handler := NewUsersHandler(services.NewUserService(db))
+	for i := 1; i <= 3; i++ {
+		db.Create(&models.User{Email: fmt.Sprintf("user%d@example.com", i), Name: fmt.Sprintf("User %d", i)})
+	}
+
+	router := gin.New()
+	router.GET("/users", handler.GetUsers)
+
+	for query, perPage := range map[string]int{"pageSize=2": 2, "per_page=1&pageSize=2": 1} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/users?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, query)
+
+		var response PaginatedResponse
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, perPage, response.PerPage, query)
+		assert.Equal(t, perPage, len(response.Data.([]interface{})), query)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users?pageSize=0", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
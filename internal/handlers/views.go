@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"golang-service/internal/models"
+	"golang-service/internal/services"
+	"golang-service/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ViewsHandler handles saved view requests
+type ViewsHandler struct {
+	views *services.ViewService
+}
+
+// NewViewsHandler creates a new saved views handler
+func NewViewsHandler(views *services.ViewService) *ViewsHandler {
+	return &ViewsHandler{views: views}
+}
+
+// ListViews handles GET /api/v1/views
+// It lists the caller's own views and shared ones, optionally for one resourceType.
+func (h *ViewsHandler) ListViews(c *gin.Context) {
+	owner, _ := viewOwner(c)
+	views, err := h.views.List(owner, c.Query("resourceType"))
+	if err != nil {
+		sendViewError(c, err, "Failed to list views")
+		return
+	}
+	utils.SendListResponse(c, views)
+}
+
+// GetView handles GET /api/v1/views/:id
+func (h *ViewsHandler) GetView(c *gin.Context) {
+	id, ok := viewID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	owner, _ := viewOwner(c)
+	view, err := h.views.Get(id, owner)
+	if err != nil {
+		sendViewError(c, err, "Failed to get view")
+		return
+	}
+	utils.SendSuccessResponse(c, view)
+}
+
+// CreateView handles POST /api/v1/views
+func (h *ViewsHandler) CreateView(c *gin.Context) {
+	owner, ok := viewWriter(c)
+	if !ok {
+		return
+	}
+
+	var req models.SavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Request body must contain 'name' and 'resourceType'")
+		return
+	}
+
+	view, err := h.views.Create(req, owner)
+	if err != nil {
+		sendViewError(c, err, "Failed to create view")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": view})
+}
+
+// UpdateView handles PUT /api/v1/views/:id
+func (h *ViewsHandler) UpdateView(c *gin.Context) {
+	owner, ok := viewWriter(c)
+	if !ok {
+		return
+	}
+	id, ok := viewID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req models.SavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Request body must contain 'name' and 'resourceType'")
+		return
+	}
+
+	view, err := h.views.Update(id, req, owner)
+	if err != nil {
+		sendViewError(c, err, "Failed to update view")
+		return
+	}
+	utils.SendSuccessResponse(c, view)
+}
+
+// DeleteView handles DELETE /api/v1/views/:id
+func (h *ViewsHandler) DeleteView(c *gin.Context) {
+	owner, ok := viewWriter(c)
+	if !ok {
+		return
+	}
+	id, ok := viewID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := h.views.Delete(id, owner); err != nil {
+		sendViewError(c, err, "Failed to delete view")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// viewOwner returns who owns the caller's saved views: their subject qualified by the
+// identity namespace of their token's issuer, as subjects are only unique within one.
+// ok is false when the caller has no subject and so can only see shared views.
+func viewOwner(c *gin.Context) (string, bool) {
+	subject := c.GetString("user_id")
+	if subject == "" {
+		return "", false
+	}
+	if namespace := c.GetString("issuer_namespace"); namespace != "" {
+		return namespace + "#" + subject, true
+	}
+	return subject, true
+}
+
+// viewWriter returns the caller's view owner, sending a 403 when they have none
+func viewWriter(c *gin.Context) (string, bool) {
+	owner, ok := viewOwner(c)
+	if !ok {
+		utils.SendErrorResponse(c, http.StatusForbidden, "Saving views requires a caller with a subject")
+	}
+	return owner, ok
+}
+
+// viewID parses a saved view ID, sending a 400 when it is invalid
+func viewID(c *gin.Context, value string) (uint, bool) {
+	id, err := strconv.ParseUint(value, 10, 0)
+	if err != nil || id == 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid view ID: "+value)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// sendViewError maps saved view service errors to HTTP responses
+func sendViewError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrViewNotFound):
+		utils.SendErrorResponse(c, http.StatusNotFound, "View not found")
+	case errors.Is(err, services.ErrViewNotOwner):
+		utils.SendErrorResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrViewConflict):
+		utils.SendErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidView):
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrBrokenView):
+		utils.SendErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+	default:
+		utils.SendErrorResponse(c, http.StatusInternalServerError, fallback)
+	}
+}
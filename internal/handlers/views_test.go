@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang-service/internal/database/dbtest"
+	"golang-service/internal/models"
+	"golang-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newViewsRouter serves the saved view endpoints to a caller whose subject and issuer
+// namespace are taken from the X-Subject and X-Namespace headers
+func newViewsRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	handler := NewViewsHandler(services.NewViewService(dbtest.Open(t, &models.SavedView{})))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Subject"))
+		c.Set("issuer_namespace", c.GetHeader("X-Namespace"))
+	})
+	router.GET("/views", handler.ListViews)
+	router.POST("/views", handler.CreateView)
+	router.DELETE("/views/:id", handler.DeleteView)
+	return router
+}
+
+func TestViewOwnership(t *testing.T) {
+	router := newViewsRouter(t)
+
+	send := func(method, path, namespace, subject string, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Namespace", namespace)
+		req.Header.Set("X-Subject", subject)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	list := func(namespace, subject string) []models.SavedView {
+		w := send("GET", "/views", namespace, subject, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []models.SavedView `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data
+	}
+
+	w := send("POST", "/views", "https://idp-a.example.com", "alice", models.SavedViewRequest{Name: "Mine", ResourceType: "vms"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.SavedView `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "https://idp-a.example.com#alice", created.Data.Owner)
+
+	// The same subject from another issuer is someone else
+	assert.Len(t, list("https://idp-a.example.com", "alice"), 1)
+	assert.Empty(t, list("https://idp-b.example.com", "alice"))
+	w = send("DELETE", "/views/1", "https://idp-b.example.com", "alice", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Callers without a subject cannot save views
+	w = send("POST", "/views", "", "", models.SavedViewRequest{Name: "Anonymous", ResourceType: "vms"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, list("", ""))
+}
@@ -6,6 +6,7 @@ import (
 	"golang-service/internal/cache"
 	"golang-service/internal/config"
 	"golang-service/internal/models"
+	"golang-service/internal/services"
 	"golang-service/internal/utils"
 	"fmt"
 	"log"
@@ -14,6 +15,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -22,33 +24,49 @@ import (
 // VMsHandler handles VM-related HTTP requests
 type VMsHandler struct {
 	db         *gorm.DB
-	cache      *cache.RedisCache
+	cache      cache.Store
 	envService *config.EnvironmentService
 	config     *config.Config
+	views      *services.ViewService
 }
 
 // NewVMsHandler creates a new VMs handler
-func NewVMsHandler(db *gorm.DB, cache *cache.RedisCache, envService *config.EnvironmentService, config *config.Config) *VMsHandler {
-	return &VMsHandler{db: db, cache: cache, envService: envService, config: config}
+func NewVMsHandler(db *gorm.DB, cache cache.Store, envService *config.EnvironmentService, config *config.Config, views *services.ViewService) *VMsHandler {
+	return &VMsHandler{db: db, cache: cache, envService: envService, config: config, views: views}
 }
 
 // GetVMs handles GET /api/v1/vms
+// view=<id> applies a saved view's filters and sort; the request's own parameters add to them.
 func (h *VMsHandler) GetVMs(c *gin.Context) {
 	// Get filter configuration for VMs endpoint
 	filterConfig := config.VMsFilterConfig()
 
+	query := c.Request.URL.Query()
+	view, viewFilters, ok := h.savedView(c, query.Get("view"))
+	if !ok {
+		return
+	}
+	query.Del("view")
+
 	// Parse and validate filters from query parameters
-	filters, err := filterConfig.ParseQueryParams(c.Request.URL.Query())
+	filters, err := filterConfig.ParseQueryParams(query)
 	if err != nil {
 		utils.SendErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Filter validation error: %s", err.Error()))
 		return
 	}
+	filters = append(viewFilters, filters...)
 
 	// Parse pagination and sorting parameters
 	page := 1
 	pageSize := 10
 	sortBy := ""
 	sortOrder := "asc"
+	if view != nil {
+		sortBy = view.SortBy
+		if view.SortOrder != "" {
+			sortOrder = view.SortOrder
+		}
+	}
 
 	if pageStr := c.Query("page"); pageStr != "" {
 		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
@@ -67,44 +85,49 @@ func (h *VMsHandler) GetVMs(c *gin.Context) {
 	}
 
 	if sortOrderParam := c.Query("sortOrder"); sortOrderParam != "" {
+		sortOrder = "asc"
 		if sortOrderParam == "desc" {
 			sortOrder = "desc"
 		}
 	}
 
-	// Try to get VMs from cache first (if Redis is available)
-	var cachedVMs []models.VM
-	if h.cache != nil {
-		ctx := context.Background()
-		cachedVMs, err = h.cache.GetVMs(ctx)
-		if err != nil {
-			log.Printf("Cache error: %v", err)
-		}
+	// Serve the page straight from the query cache when this exact query was answered before.
+	// Callers restricted to part of the inventory share cached pages only with callers
+	// seeing the same part.
+	scope := callerScope(c)
+	queryKey := cache.QueryKey(filters, sortBy, sortOrder, page, pageSize)
+	if scope != nil {
+		queryKey += ":scope:" + scope.Key()
 	}
-
-	// If cache miss or Redis unavailable, fetch from database and cache the result
-	if cachedVMs == nil {
-		log.Println("Cache miss or Redis unavailable - fetching VMs from database")
-		cachedVMs, err = h.fetchVMsFromDatabase()
+	if h.cache != nil {
+		result, err := h.cache.GetQuery(c.Request.Context(), queryKey)
 		if err != nil {
-			utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to fetch VMs")
+			log.Printf("Query cache error: %v", err)
+		} else if result != nil {
+			etag, lastModified := h.vmsValidators(queryKey, result.SyncTime, result.TotalItems)
+			if utils.CheckNotModified(c, etag, lastModified) {
+				return
+			}
+			utils.SendPaginatedResponse(c, result.VMs, page, pageSize, result.TotalItems)
 			return
 		}
+	}
 
-		// Cache the result (async) if Redis is available
-		if h.cache != nil {
-			go func() {
-				if err := h.cache.SetVMs(context.Background(), cachedVMs); err != nil {
-					log.Printf("Failed to cache VMs: %v", err)
-				}
-			}()
-		}
-	} else {
-		log.Println("Cache hit - using cached VMs")
+	var generation uint64
+	if h.cache != nil {
+		generation = h.cache.Generation()
+	}
+	cachedVMs, err := h.Inventory(c.Request.Context())
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Failed to fetch VMs")
+		return
 	}
 
+	// Restrict to the caller's scope first so filtering and counts only reflect what they may see
+	scopedVMs := scope.FilterVMs(cachedVMs)
+
 	// Apply filters using the configurable system (including environment filters)
-	filteredVMs := utils.ApplyFilters(cachedVMs, filters)
+	filteredVMs := utils.ApplyFilters(scopedVMs, filters)
 
 	// Apply sorting
 	sortedVMs := h.applySorting(filteredVMs, sortBy, sortOrder)
@@ -112,12 +135,225 @@ func (h *VMsHandler) GetVMs(c *gin.Context) {
 	// Calculate pagination
 	totalItems := len(sortedVMs)
 	paginatedVMs := utils.ApplyPagination(sortedVMs, page, pageSize)
+	syncTime := latestSyncTime(cachedVMs)
+
+	// Cache this page (async), tagged so provider re-syncs and environment changes can invalidate it
+	if h.cache != nil {
+		result := &cache.QueryResult{
+			VMs:        paginatedVMs,
+			TotalItems: totalItems,
+			Tags:       cache.QueryTags(queryProviders(filters), sortedVMs),
+			SyncTime:   syncTime,
+		}
+		h.cacheInBackground(generation, "query result", func(ctx context.Context) error {
+			return h.cache.SetQuery(ctx, queryKey, result)
+		})
+	}
+
+	etag, lastModified := h.vmsValidators(queryKey, syncTime, totalItems)
+	if utils.CheckNotModified(c, etag, lastModified) {
+		return
+	}
 
 	// Send response using the reusable utility
 	utils.SendPaginatedResponse(c, paginatedVMs, page, pageSize, totalItems)
 }
 
+// Inventory returns the full VM inventory with resolved environments,
+// from the cache when possible and otherwise from the database
+func (h *VMsHandler) Inventory(ctx context.Context) ([]models.VM, error) {
+	// Try to get VMs from cache first
+	var cachedVMs []models.VM
+	var err error
+	if h.cache != nil {
+		cachedVMs, err = h.cache.GetVMs(ctx)
+		if err != nil {
+			log.Printf("Cache error: %v", err)
+		}
+	}
+
+	if cachedVMs != nil {
+		log.Println("Cache hit - using cached VMs")
+		return cachedVMs, nil
+	}
+
+	// If cache miss or cache unavailable, fetch from database and cache the result
+	log.Println("Cache miss - fetching VMs from database")
+	var generation uint64
+	if h.cache != nil {
+		generation = h.cache.Generation()
+	}
+	cachedVMs, err = h.fetchVMsFromDatabase()
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache the result (async) if a cache is configured
+	if h.cache != nil {
+		h.cacheInBackground(generation, "VMs", func(ctx context.Context) error {
+			return h.cache.SetVMs(ctx, cachedVMs)
+		})
+	}
+
+	return cachedVMs, nil
+}
+
+// cacheInBackground runs a cache write without delaying the response. The write is
+// skipped when the cache was invalidated after generation was read: the value was
+// built from data loaded before the invalidation and would bring stale results back.
+func (h *VMsHandler) cacheInBackground(generation uint64, what string, write func(context.Context) error) {
+	go func() {
+		if h.cache.Generation() != generation {
+			log.Printf("Not caching %s: the cache was invalidated while it was built", what)
+			return
+		}
+		if err := write(context.Background()); err != nil {
+			log.Printf("Failed to cache %s: %v", what, err)
+		}
+	}()
+}
+
+// providerTables are the tables CloudQuery syncs each provider's VMs into
+var providerTables = map[string]string{
+	"aws":   models.AWSEC2Instance{}.TableName(),
+	"azure": models.AzureVMInstance{}.TableName(),
+	"gcp":   models.GCPComputeInstance{}.TableName(),
+}
+
+// WatchProviderSyncs checks every interval whether CloudQuery has re-synced a provider
+// and, if so, invalidates the cached entries built from that provider's VMs. Every
+// replica watches, so each drops its own local entries even without a shared cache.
+func (h *VMsHandler) WatchProviderSyncs(ctx context.Context, interval time.Duration) {
+	synced := make(map[string]time.Time)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.invalidateResyncedProviders(ctx, synced)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// invalidateResyncedProviders invalidates the providers whose latest sync time moved past
+// the one recorded in synced, and records the new ones. A provider seen for the first
+// time is only recorded.
+func (h *VMsHandler) invalidateResyncedProviders(ctx context.Context, synced map[string]time.Time) {
+	for cloudType, table := range providerTables {
+		var latest []time.Time
+		err := h.db.WithContext(ctx).Table(table).
+			Order("_cq_sync_time DESC").Limit(1).
+			Pluck("_cq_sync_time", &latest).Error
+		if err != nil {
+			log.Printf("Failed to read the latest %s sync time: %v", cloudType, err)
+			continue
+		}
+		if len(latest) == 0 {
+			continue
+		}
+
+		previous, seen := synced[cloudType]
+		synced[cloudType] = latest[0]
+		if !seen || !latest[0].After(previous) {
+			continue
+		}
+		if _, err := h.cache.InvalidateTags(ctx, cache.ProviderTag(cloudType)); err != nil {
+			log.Printf("Failed to invalidate cache after %s re-sync: %v", cloudType, err)
+			continue
+		}
+		log.Printf("Invalidated cached VMs after %s re-sync at %s", cloudType, latest[0].Format(time.RFC3339))
+	}
+}
+
+// vmsValidators returns the ETag and Last-Modified time of a VM query page.
+// The version combines the inventory's latest sync time with the environment
+// configuration's load time, since environment resolution is part of every VM.
+func (h *VMsHandler) vmsValidators(queryKey string, syncTime time.Time, totalItems int) (string, time.Time) {
+	lastModified := syncTime
+	var envVersion time.Time
+	if h.envService != nil {
+		envVersion = h.envService.GetLastLoadTime()
+		if envVersion.After(lastModified) {
+			lastModified = envVersion
+		}
+	}
+
+	etag := utils.ETag(
+		queryKey,
+		strconv.FormatInt(syncTime.UnixNano(), 10),
+		strconv.FormatInt(envVersion.UnixNano(), 10),
+		strconv.Itoa(totalItems),
+	)
+	return etag, lastModified
+}
+
+// latestSyncTime returns the most recent sync time across the inventory
+func latestSyncTime(vms []models.VM) time.Time {
+	var latest time.Time
+	for _, vm := range vms {
+		if vm.SyncTime.After(latest) {
+			latest = vm.SyncTime
+		}
+	}
+	return latest
+}
+
+// queryProviders returns the cloud providers a query can draw VMs from, narrowed by any cloudType filter
+func queryProviders(filters []config.FilterParam) []string {
+	for _, filter := range filters {
+		if filter.Field != "cloudType" {
+			continue
+		}
+		switch filter.Operator {
+		case config.OperatorEquals:
+			return []string{strings.ToLower(filter.Value)}
+		case config.OperatorIn:
+			var providers []string
+			for _, value := range strings.Split(filter.Value, ",") {
+				providers = append(providers, strings.ToLower(strings.TrimSpace(value)))
+			}
+			return providers
+		}
+	}
+	return cache.CloudTypes
+}
+
+// savedView loads the saved view named by the view parameter, if any, with its filters.
+// It sends an error when the view cannot be applied: it is not visible to the caller, is
+// for another resource, or its filters no longer fit the VM filter configuration.
+func (h *VMsHandler) savedView(c *gin.Context, value string) (*models.SavedView, []config.FilterParam, bool) {
+	if value == "" {
+		return nil, nil, true
+	}
+	if h.views == nil {
+		utils.SendErrorResponse(c, http.StatusServiceUnavailable, "Saved views are not available")
+		return nil, nil, false
+	}
+
+	id, ok := viewID(c, value)
+	if !ok {
+		return nil, nil, false
+	}
+	owner, _ := viewOwner(c)
+	view, err := h.views.Get(id, owner)
+	if err != nil {
+		sendViewError(c, err, "Failed to load view")
+		return nil, nil, false
+	}
+	if view.ResourceType != "vms" {
+		utils.SendErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("View %d is a view of %s, not VMs", id, view.ResourceType))
+		return nil, nil, false
+	}
 
+	filters, err := h.views.Filters(view)
+	if err != nil {
+		sendViewError(c, err, "Failed to load view")
+		return nil, nil, false
+	}
+	return view, filters, true
+}
 
 // applySorting applies sorting to VMs
 func (h *VMsHandler) applySorting(vms []models.VM, sortBy, sortOrder string) []models.VM {
@@ -209,16 +445,23 @@ func (h *VMsHandler) fetchVMsFromDatabase() ([]models.VM, error) {
 				Location:             awsVM.Region,
 				InstanceType:         awsVM.InstanceType,
 				CloudSpecificDetails: awsVM.Tags, // Store tags as cloud-specific details
+				Tags:                 parseTags(awsVM.Tags),
+				PrivateIP:            awsVM.PrivateIPAddress,
+				VpcID:                awsVM.VpcID,
+				VCPUs:                awsVCPUs(awsVM.CpuOptions),
+				SyncTime:             awsVM.CqSyncTime,
 			}
 			
 			// Resolve environment for this VM if environment service is available
 			if h.envService != nil {
 				if environment, err := h.envService.ResolveEnvironmentForVM(vm); err == nil {
 					vm.Environment = &models.EnvironmentInfo{
-						ID:          environment.ID,
-						Name:        environment.Name,
-						Description: environment.Description,
-						Tags:        environment.Tags,
+						ID:            environment.ID,
+						Name:          environment.Name,
+						Description:   environment.Description,
+						Tags:          environment.Tags,
+						Ancestors:     environment.Ancestors,
+						ConfigVersion: environment.ConfigVersion,
 					}
 					vm.Env = environment.ID
 				}
@@ -242,6 +485,8 @@ func (h *VMsHandler) fetchVMsFromDatabase() ([]models.VM, error) {
 			return
 		}
 
+		nics := h.fetchAzureNetworkInterfaces()
+
 		// Convert Azure VMs to unified VM format
 		for _, azureVM := range azureVMs {
 			// Extract status from properties JSON
@@ -264,16 +509,25 @@ func (h *VMsHandler) fetchVMsFromDatabase() ([]models.VM, error) {
 				Location:             azureVM.Location,
 				InstanceType:         "", // Will extract from properties if needed
 				CloudSpecificDetails: azureVM.Properties, // Store properties as cloud-specific details
+				Tags:                 parseTags(azureVM.Tags),
+				ResourceGroup:        azureResourceGroup(azureVM.ID),
+				SyncTime:             azureVM.CqSyncTime,
+			}
+			if nic, ok := nics[strings.ToLower(azurePrimaryInterfaceID(azureVM.Properties))]; ok {
+				vm.PrivateIP = nic.PrivateIP
+				vm.VpcID = nic.VNet
 			}
 			
 			// Resolve environment for this VM if environment service is available
 			if h.envService != nil {
 				if environment, err := h.envService.ResolveEnvironmentForVM(vm); err == nil {
 					vm.Environment = &models.EnvironmentInfo{
-						ID:          environment.ID,
-						Name:        environment.Name,
-						Description: environment.Description,
-						Tags:        environment.Tags,
+						ID:            environment.ID,
+						Name:          environment.Name,
+						Description:   environment.Description,
+						Tags:          environment.Tags,
+						Ancestors:     environment.Ancestors,
+						ConfigVersion: environment.ConfigVersion,
 					}
 					vm.Env = environment.ID
 				}
@@ -308,16 +562,24 @@ func (h *VMsHandler) fetchVMsFromDatabase() ([]models.VM, error) {
 				Location:             gcpVM.Zone, // Using zone as location
 				InstanceType:         gcpVM.MachineType,
 				CloudSpecificDetails: gcpVM.Labels, // Store labels as cloud-specific details
+				Tags:                 parseTags(gcpVM.Labels),
+				SyncTime:             gcpVM.CqSyncTime,
 			}
+			vm.VCPUs, vm.MemoryMiB = gcpMachineCapacity(gcpVM.MachineType)
+			primary := gcpPrimaryInterface(gcpVM.NetworkInterfaces)
+			vm.PrivateIP = primary.NetworkIP
+			vm.VpcID = lastPathSegment(primary.Network)
 			
 			// Resolve environment for this VM if environment service is available
 			if h.envService != nil {
 				if environment, err := h.envService.ResolveEnvironmentForVM(vm); err == nil {
 					vm.Environment = &models.EnvironmentInfo{
-						ID:          environment.ID,
-						Name:        environment.Name,
-						Description: environment.Description,
-						Tags:        environment.Tags,
+						ID:            environment.ID,
+						Name:          environment.Name,
+						Description:   environment.Description,
+						Tags:          environment.Tags,
+						Ancestors:     environment.Ancestors,
+						ConfigVersion: environment.ConfigVersion,
 					}
 					vm.Env = environment.ID
 				}
@@ -342,3 +604,188 @@ func (h *VMsHandler) fetchVMsFromDatabase() ([]models.VM, error) {
 	return allVMs, nil
 }
 
+// parseTags flattens a JSON object of tags or labels into string values
+func parseTags(raw json.RawMessage) map[string]string {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(raw, &values); err != nil || len(values) == 0 {
+		return nil
+	}
+
+	tags := make(map[string]string, len(values))
+	for key, value := range values {
+		if str, ok := value.(string); ok {
+			tags[key] = str
+		} else if value != nil {
+			tags[key] = fmt.Sprintf("%v", value)
+		} else {
+			tags[key] = ""
+		}
+	}
+	return tags
+}
+
+// azureResourceGroup extracts the resource group from an Azure resource ID
+// (/subscriptions/<id>/resourceGroups/<group>/providers/...)
+func azureResourceGroup(resourceID string) string {
+	parts := strings.Split(resourceID, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if strings.EqualFold(parts[i], "resourceGroups") {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// gcpNetworkInterface holds the fields used from a GCP instance's network interface
+type gcpNetworkInterface struct {
+	NetworkIP string `json:"networkIP"`
+	Network   string `json:"network"`
+}
+
+// gcpPrimaryInterface returns the first network interface of a GCP instance
+func gcpPrimaryInterface(raw json.RawMessage) gcpNetworkInterface {
+	var interfaces []gcpNetworkInterface
+	if err := json.Unmarshal(raw, &interfaces); err != nil || len(interfaces) == 0 {
+		return gcpNetworkInterface{}
+	}
+	return interfaces[0]
+}
+
+// lastPathSegment returns the last segment of a resource path or URL, e.g. the network
+// name of https://www.googleapis.com/compute/v1/projects/p/global/networks/default
+func lastPathSegment(resource string) string {
+	return resource[strings.LastIndex(resource, "/")+1:]
+}
+
+// awsVCPUs returns the vCPU count from an EC2 instance's CPU options, or 0 when unknown
+func awsVCPUs(raw json.RawMessage) int {
+	var options struct {
+		CoreCount      int `json:"CoreCount"`
+		ThreadsPerCore int `json:"ThreadsPerCore"`
+	}
+	if err := json.Unmarshal(raw, &options); err != nil {
+		return 0
+	}
+	if options.ThreadsPerCore == 0 {
+		options.ThreadsPerCore = 1
+	}
+	return options.CoreCount * options.ThreadsPerCore
+}
+
+// gcpMachineCapacity returns the vCPUs and memory encoded in a GCP machine type. Custom
+// types ("n2-custom-4-16384") carry both; predefined types ("e2-standard-4") only the
+// vCPUs, and shared-core types neither.
+func gcpMachineCapacity(machineType string) (int, int) {
+	parts := strings.Split(lastPathSegment(machineType), "-")
+	for i, part := range parts {
+		if part == "custom" && i+2 < len(parts) {
+			vcpus, err1 := strconv.Atoi(parts[i+1])
+			memory, err2 := strconv.Atoi(parts[i+2])
+			if err1 != nil || err2 != nil {
+				return 0, 0
+			}
+			return vcpus, memory
+		}
+	}
+	if len(parts) < 3 {
+		return 0, 0
+	}
+	vcpus, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return 0, 0
+	}
+	return vcpus, 0
+}
+
+// azureNIC holds what is used from an Azure network interface
+type azureNIC struct {
+	PrivateIP string
+	VNet      string
+}
+
+// fetchAzureNetworkInterfaces maps lower-cased network interface IDs to their primary private IP
+// and virtual network. The table is optional, so an absent or unreadable table yields no entries.
+func (h *VMsHandler) fetchAzureNetworkInterfaces() map[string]azureNIC {
+	if !h.db.Migrator().HasTable(&models.AzureNetworkInterface{}) {
+		return nil
+	}
+
+	var interfaces []models.AzureNetworkInterface
+	if err := h.db.Select("id", "properties").Find(&interfaces).Error; err != nil {
+		log.Printf("Failed to fetch Azure network interfaces: %v", err)
+		return nil
+	}
+
+	nics := make(map[string]azureNIC, len(interfaces))
+	for _, iface := range interfaces {
+		var properties struct {
+			IPConfigurations []struct {
+				Properties struct {
+					Primary          bool   `json:"primary"`
+					PrivateIPAddress string `json:"privateIPAddress"`
+					Subnet           struct {
+						ID string `json:"id"`
+					} `json:"subnet"`
+				} `json:"properties"`
+			} `json:"ipConfigurations"`
+		}
+		if err := json.Unmarshal(iface.Properties, &properties); err != nil || len(properties.IPConfigurations) == 0 {
+			continue
+		}
+
+		primary := properties.IPConfigurations[0].Properties
+		for _, ipConfig := range properties.IPConfigurations {
+			if ipConfig.Properties.Primary {
+				primary = ipConfig.Properties
+				break
+			}
+		}
+		nics[strings.ToLower(iface.ID)] = azureNIC{
+			PrivateIP: primary.PrivateIPAddress,
+			VNet:      azureVirtualNetwork(primary.Subnet.ID),
+		}
+	}
+	return nics
+}
+
+// azurePrimaryInterfaceID returns the ID of an Azure VM's primary network interface
+// from its properties.networkProfile
+func azurePrimaryInterfaceID(raw json.RawMessage) string {
+	var properties struct {
+		NetworkProfile struct {
+			NetworkInterfaces []struct {
+				ID         string `json:"id"`
+				Properties struct {
+					Primary bool `json:"primary"`
+				} `json:"properties"`
+			} `json:"networkInterfaces"`
+		} `json:"networkProfile"`
+	}
+	if err := json.Unmarshal(raw, &properties); err != nil || len(properties.NetworkProfile.NetworkInterfaces) == 0 {
+		return ""
+	}
+
+	interfaces := properties.NetworkProfile.NetworkInterfaces
+	for _, iface := range interfaces {
+		if iface.Properties.Primary {
+			return iface.ID
+		}
+	}
+	return interfaces[0].ID
+}
+
+// azureVirtualNetwork extracts the virtual network name from a subnet ID
+// (.../providers/Microsoft.Network/virtualNetworks/<vnet>/subnets/<subnet>)
+func azureVirtualNetwork(subnetID string) string {
+	parts := strings.Split(subnetID, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if strings.EqualFold(parts[i], "virtualNetworks") {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
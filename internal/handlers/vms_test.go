@@ -20,7 +20,7 @@ type VMHandlerTestSuite struct {
 	suite.Suite
 	db     *gorm.DB
 	router *gin.Engine
-	handler *Handler
+	handler *VMsHandler
 }
 
 func (suite *VMHandlerTestSuite) SetupSuite() {
@@ -37,7 +37,7 @@ func (suite *VMHandlerTestSuite) SetupSuite() {
 	suite.Require().NoError(err)
 
 	suite.db = db
-	suite.handler = New(db)
+	suite.handler = NewVMsHandler(db, nil, nil, nil, nil)
 
 	// Set up Gin router
 	gin.SetMode(gin.TestMode)
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"golang-service/internal/models"
+	"golang-service/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditRecorder stores audit events
+type AuditRecorder interface {
+	Record(ctx context.Context, event *models.AuditEvent) error
+}
+
+// Audit records every request that passed authentication: the caller, route, query
+// filters, status, latency and, for list responses, the number of results. Handlers
+// describe admin mutations by setting "audit_action", "audit_resource", "audit_before"
+// and "audit_after". It must run after Authenticate.
+func Audit(recorder AuditRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		event := &models.AuditEvent{
+			UserID:     c.GetString("user_id"),
+			ClientID:   c.GetString("client_id"),
+			ObjectID:   c.GetString("object_id"),
+			Issuer:     c.GetString("issuer_namespace"),
+			AuthMethod: c.GetString("auth_method"),
+			ClientIP:   c.ClientIP(),
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			Path:       c.Request.URL.Path,
+			Status:     c.Writer.Status(),
+			LatencyMs:  float64(time.Since(start).Microseconds()) / 1000,
+			Action:     c.GetString("audit_action"),
+			Resource:   c.GetString("audit_resource"),
+		}
+		if query := c.Request.URL.Query(); len(query) > 0 {
+			event.Filters = make(map[string]string, len(query))
+			for name, values := range query {
+				event.Filters[name] = strings.Join(values, ",")
+			}
+		}
+		if count, ok := c.Get(utils.ResultCountKey); ok {
+			if n, ok := count.(int); ok {
+				event.ResultCount = &n
+			}
+		}
+		if event.Action != "" {
+			event.Before, _ = c.Get("audit_before")
+			event.After, _ = c.Get("audit_after")
+		}
+
+		// The response is already written; a failure to record must not change it
+		if err := recorder.Record(context.WithoutCancel(c.Request.Context()), event); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang-service/internal/config"
+	"golang-service/internal/models"
+	"golang-service/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuditLog keeps recorded events in memory
+type fakeAuditLog []*models.AuditEvent
+
+func (f *fakeAuditLog) Record(ctx context.Context, event *models.AuditEvent) error {
+	*f = append(*f, event)
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy, err := config.ParsePolicy([]byte(`roles:
+  Atlas.Reader: ["GET /api/v1/vms"]
+`))
+	require.NoError(t, err)
+
+	apiKeys := fakeAPIKeys{"ak_1.secret": {ID: "ak_1", Roles: []string{"Atlas.Reader"}}}
+	var events fakeAuditLog
+	router := gin.New()
+	api := router.Group("/api/v1")
+	api.Use(Authenticate(NewTokenVerifier(nil, KeySetOptions{}), apiKeys), Audit(&events), RequirePermission(policy))
+	api.GET("/vms", func(c *gin.Context) {
+		utils.SendListResponse(c, []string{"vm-1", "vm-2"})
+	})
+	api.POST("/environments/reload", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(method, target, header string) {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", header)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	request(http.MethodGet, "/api/v1/vms?cloud=aws&region=a&region=b", "ApiKey ak_1.secret")
+	request(http.MethodPost, "/api/v1/environments/reload", "ApiKey ak_1.secret")
+	request(http.MethodGet, "/api/v1/vms", "ApiKey wrong")
+
+	// Unauthenticated requests never reach the audit log
+	require.Len(t, events, 2)
+
+	listed := events[0]
+	assert.Equal(t, "apikey:ak_1", listed.UserID)
+	assert.Equal(t, "ak_1", listed.ClientID)
+	assert.Equal(t, "api_key", listed.AuthMethod)
+	assert.Equal(t, "/api/v1/vms", listed.Route)
+	assert.Equal(t, map[string]string{"cloud": "aws", "region": "a,b"}, listed.Filters)
+	assert.Equal(t, http.StatusOK, listed.Status)
+	require.NotNil(t, listed.ResultCount)
+	assert.Equal(t, 2, *listed.ResultCount)
+
+	// Refused requests are recorded too
+	assert.Equal(t, "/api/v1/environments/reload", events[1].Route)
+	assert.Equal(t, http.StatusForbidden, events[1].Status)
+	assert.Nil(t, events[1].ResultCount)
+}
@@ -2,79 +2,26 @@ package middleware
 
 import (
 	"context"
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/base64"
-	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"strings"
-	"time"
 
-	"golang-service/internal/config"
+	"golang-service/internal/models"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-// AzureJWKS represents Azure's JSON Web Key Set
-type AzureJWKS struct {
-	Keys []AzureJWK `json:"keys"`
+// APIKeyAuthenticator resolves API keys sent as "Authorization: ApiKey <key>"
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
 }
 
-// AzureJWK represents a single JSON Web Key
-type AzureJWK struct {
-	Kty string   `json:"kty"`
-	Kid string   `json:"kid"`
-	Use string   `json:"use"`
-	N   string   `json:"n"`
-	E   string   `json:"e"`
-	X5c []string `json:"x5c"`
-	X5t string   `json:"x5t"`
-}
-
-// AzureTokenClaims represents Azure token claims
-type AzureTokenClaims struct {
-	Aud           string `json:"aud"`
-	Iss           string `json:"iss"`
-	Iat           int64  `json:"iat"`
-	Nbf           int64  `json:"nbf"`
-	Exp           int64  `json:"exp"`
-	Aio           string `json:"aio"`
-	Appid         string `json:"appid"`
-	Appidacr      string `json:"appidacr"`
-	Idp           string `json:"idp"`
-	ObjID         string `json:"oid"`
-	Rh            string `json:"rh"`
-	Sub           string `json:"sub"`
-	TenantID      string `json:"tid"`
-	UTI           string `json:"uti"`
-	Ver           string `json:"ver"`
-	Roles         []string `json:"roles"`
-	jwt.RegisteredClaims
-}
-
-var (
-	// Cache for Azure public keys
-	azurePublicKeys map[string]*rsa.PublicKey
-	keysLastFetch   time.Time
-	keysCacheTTL    = 24 * time.Hour
-)
-
-// AzureEntraAuth validates Azure Entra ID tokens with client credential flow
-func AzureEntraAuth(cfg *config.Config) gin.HandlerFunc {
+// Authenticate validates the Authorization header and stores the caller's identity in the
+// context. Bearer tokens must come from an issuer the verifier trusts (in dev auth mode
+// including tokens from POST /dev/token); API keys are checked by apiKeys, which may be
+// nil to refuse them.
+func Authenticate(verifier *TokenVerifier, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Bypass authentication for local development
-		if cfg.BypassAuth {
-			log.Println("Auth bypassed for local development")
-			c.Set("user_id", "dev-user")
-			c.Set("tenant_id", "dev-tenant")
-			c.Set("client_id", "dev-client")
-			c.Next()
-			return
-		}
-
 		// Extract Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -83,19 +30,20 @@ func AzureEntraAuth(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		if key, ok := strings.CutPrefix(authHeader, "ApiKey "); ok {
+			authenticateAPIKey(c, apiKeys, key)
+			return
+		}
+
 		// Extract token from "Bearer <token>"
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format. Expected 'Bearer <token>'"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format. Expected 'Bearer <token>' or 'ApiKey <key>'"})
 			c.Abort()
 			return
 		}
 
-		// Parse and validate the token
-		token, err := jwt.ParseWithClaims(tokenString, &AzureTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return getAzurePublicKey(token, cfg)
-		})
-
+		identity, err := verifier.Verify(c.Request.Context(), tokenString)
 		if err != nil {
 			log.Printf("Token validation error: %v", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -103,160 +51,48 @@ func AzureEntraAuth(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		// Extract and validate claims
-		if claims, ok := token.Claims.(*AzureTokenClaims); ok && token.Valid {
-			// Validate issuer
-			expectedIssuer := fmt.Sprintf("https://sts.windows.net/%s/", cfg.AzureTenantID)
-			if claims.Iss != expectedIssuer {
-				log.Printf("Invalid issuer: expected %s, got %s", expectedIssuer, claims.Iss)
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token issuer"})
-				c.Abort()
-				return
-			}
-
-			// Validate audience (should be the client ID)
-			if claims.Aud != cfg.AzureClientID {
-				log.Printf("Invalid audience: expected %s, got %s", cfg.AzureClientID, claims.Aud)
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token audience"})
-				c.Abort()
-				return
-			}
-
-			// Validate tenant ID
-			if claims.TenantID != cfg.AzureTenantID {
-				log.Printf("Invalid tenant ID: expected %s, got %s", cfg.AzureTenantID, claims.TenantID)
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid tenant ID"})
-				c.Abort()
-				return
-			}
-
-			// Store token information in context
-			c.Set("user_id", claims.Sub)
-			c.Set("tenant_id", claims.TenantID)
-			c.Set("client_id", claims.Appid)
-			c.Set("roles", claims.Roles)
-			c.Set("object_id", claims.ObjID)
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
-		}
+		// Store token information in context
+		c.Set("auth_method", "bearer")
+		c.Set("issuer", identity.Issuer)
+		c.Set("issuer_namespace", identity.Namespace)
+		c.Set("user_id", identity.Subject)
+		c.Set("tenant_id", identity.TenantID)
+		c.Set("client_id", identity.ClientID)
+		c.Set("roles", identity.Roles)
+		c.Set("groups", identity.Groups)
+		c.Set("object_id", identity.ObjectID)
+		c.Set("name", identity.Name)
+		c.Set("email", identity.Email)
 
 		c.Next()
 	}
 }
 
-// getAzurePublicKey retrieves the public key for token validation
-func getAzurePublicKey(token *jwt.Token, cfg *config.Config) (interface{}, error) {
-	// Check if we need to refresh the keys
-	if azurePublicKeys == nil || time.Since(keysLastFetch) > keysCacheTTL {
-		if err := refreshAzurePublicKeys(cfg); err != nil {
-			return nil, fmt.Errorf("failed to refresh Azure public keys: %w", err)
-		}
-	}
-
-	// Get the key ID from the token header
-	kidInterface, ok := token.Header["kid"]
-	if !ok {
-		return nil, fmt.Errorf("token header missing 'kid' claim")
-	}
-
-	kid, ok := kidInterface.(string)
-	if !ok {
-		return nil, fmt.Errorf("'kid' claim is not a string")
-	}
-
-	// Look up the public key
-	publicKey, exists := azurePublicKeys[kid]
-	if !exists {
-		// Try to refresh keys once more
-		if err := refreshAzurePublicKeys(cfg); err != nil {
-			return nil, fmt.Errorf("failed to refresh Azure public keys: %w", err)
-		}
-		
-		publicKey, exists = azurePublicKeys[kid]
-		if !exists {
-			return nil, fmt.Errorf("unable to find public key for kid: %s", kid)
-		}
-	}
-
-	return publicKey, nil
-}
-
-// refreshAzurePublicKeys fetches the latest public keys from Azure
-func refreshAzurePublicKeys(cfg *config.Config) error {
-	jwksURL := fmt.Sprintf("https://login.microsoftonline.com/%s/discovery/v2.0/keys", cfg.AzureTenantID)
-	
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, "GET", jwksURL, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// authenticateAPIKey stores the identity of an API key's holder in the context. The key
+// acts as its own client; its environments, if any, become the caller's data scope.
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, presented string) {
+	if apiKeys == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API keys are not accepted"})
+		c.Abort()
+		return
 	}
 
-	resp, err := client.Do(req)
+	key, err := apiKeys.Authenticate(c.Request.Context(), strings.TrimSpace(presented))
 	if err != nil {
-		return fmt.Errorf("failed to fetch JWKS: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to fetch JWKS, status: %d", resp.StatusCode)
-	}
-
-	var jwks AzureJWKS
-	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
-		return fmt.Errorf("failed to decode JWKS: %w", err)
+		log.Printf("API key validation error: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return
 	}
 
-	// Convert JWK to RSA public keys
-	publicKeys := make(map[string]*rsa.PublicKey)
-	for _, key := range jwks.Keys {
-		if key.Kty == "RSA" && key.Use == "sig" {
-			publicKey, err := jwkToRSAPublicKey(key)
-			if err != nil {
-				log.Printf("Failed to convert JWK to RSA public key for kid %s: %v", key.Kid, err)
-				continue
-			}
-			publicKeys[key.Kid] = publicKey
-		}
+	c.Set("auth_method", "api_key")
+	c.Set("api_key_id", key.ID)
+	c.Set("user_id", "apikey:"+key.ID)
+	c.Set("client_id", key.ID)
+	c.Set("roles", key.Roles)
+	if len(key.Environments) > 0 {
+		c.Set("api_key_environments", key.Environments)
 	}
 
-	azurePublicKeys = publicKeys
-	keysLastFetch = time.Now()
-	
-	log.Printf("Successfully loaded %d Azure public keys", len(publicKeys))
-	return nil
+	c.Next()
 }
-
-// jwkToRSAPublicKey converts a JWK to an RSA public key
-func jwkToRSAPublicKey(jwk AzureJWK) (*rsa.PublicKey, error) {
-	if len(jwk.X5c) == 0 {
-		return nil, fmt.Errorf("no x5c certificate found in JWK")
-	}
-
-	// Decode the x5c certificate
-	certData, err := base64.StdEncoding.DecodeString(jwk.X5c[0])
-	if err != nil {
-		return nil, fmt.Errorf("failed to decode x5c certificate: %w", err)
-	}
-
-	// Parse the certificate
-	cert, err := x509.ParseCertificate(certData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse certificate: %w", err)
-	}
-
-	// Extract the public key
-	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("certificate does not contain an RSA public key")
-	}
-
-	return publicKey, nil
-}
\ No newline at end of file
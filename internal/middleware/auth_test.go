@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang-service/internal/config"
+	"golang-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPIKeys accepts the keys it holds
+type fakeAPIKeys map[string]models.APIKey
+
+func (f fakeAPIKeys) Authenticate(ctx context.Context, presented string) (*models.APIKey, error) {
+	key, ok := f[presented]
+	if !ok {
+		return nil, errors.New("unknown key")
+	}
+	return &key, nil
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy, err := config.ParsePolicy([]byte(`roles:
+  Atlas.Reader: ["GET /api/v1/**"]
+`))
+	require.NoError(t, err)
+
+	apiKeys := fakeAPIKeys{
+		"ak_1.secret": {ID: "ak_1", Roles: []string{"Atlas.Reader"}, Environments: []string{"prod"}},
+		"ak_2.secret": {ID: "ak_2", Roles: []string{"Atlas.Reader"}},
+	}
+	var gotUser string
+	var gotScope *config.Scope
+	router := gin.New()
+	api := router.Group("/api/v1")
+	api.Use(Authenticate(NewTokenVerifier(nil, KeySetOptions{}), apiKeys), RequirePermission(policy), DataScope(policy))
+	handler := func(c *gin.Context) {
+		gotUser = c.GetString("user_id")
+		value, _ := c.Get("data_scope")
+		gotScope, _ = value.(*config.Scope)
+		c.Status(http.StatusOK)
+	}
+	api.GET("/vms", handler)
+	api.POST("/environments/reload", handler)
+
+	request := func(method, path, header string) int {
+		gotUser, gotScope = "", nil
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", header)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/v1/vms", "ApiKey ak_1.secret"))
+	assert.Equal(t, "apikey:ak_1", gotUser)
+	require.NotNil(t, gotScope)
+	assert.Equal(t, []string{"prod"}, gotScope.Environments)
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/api/v1/vms", "ApiKey ak_2.secret"))
+	assert.Nil(t, gotScope)
+
+	// Keys are authorized by their roles like any other caller
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "/api/v1/environments/reload", "ApiKey ak_1.secret"))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/v1/vms", "ApiKey ak_1.wrong"))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/api/v1/vms", "Basic dXNlcjpwYXNz"))
+
+	// Without an authenticator API keys are refused
+	router = gin.New()
+	router.Use(Authenticate(NewTokenVerifier(nil, KeySetOptions{}), nil))
+	router.GET("/api/v1/vms", handler)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/vms", nil)
+	req.Header.Set("Authorization", "ApiKey ak_1.secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"golang-service/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole allows requests from callers holding any of the given app roles.
+// It must run after Authenticate, which stores the token's roles in the context.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, role := range callerRoles(c) {
+			for _, required := range roles {
+				if role == required {
+					c.Next()
+					return
+				}
+			}
+		}
+		forbidden(c, "requires one of the roles: "+strings.Join(roles, ", "))
+	}
+}
+
+// RequirePermission allows requests that the policy grants to one of the caller's roles.
+// Requests matching no route pass through so they still answer 404.
+func RequirePermission(policy *config.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" || policy.Allows(callerRoles(c), c.Request.Method, route) {
+			c.Next()
+			return
+		}
+
+		message := "no role grants " + c.Request.Method + " " + route
+		if roles := policy.RolesFor(c.Request.Method, route); len(roles) > 0 {
+			message = "requires one of the roles: " + strings.Join(roles, ", ")
+		}
+		forbidden(c, message)
+	}
+}
+
+// DataScope stores the caller's data scope under "data_scope" for handlers to restrict
+// results to. Unrestricted callers (bypass roles, or no data access rules) get none.
+// API keys limited to environments are scoped to exactly those.
+func DataScope(policy *config.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if value, ok := c.Get("api_key_environments"); ok {
+			environments, _ := value.([]string)
+			c.Set("data_scope", &config.Scope{Environments: environments, Accounts: []string{}, Tags: map[string][]string{}})
+			c.Next()
+			return
+		}
+
+		groups, _ := c.Get("groups")
+		groupList, _ := groups.([]string)
+		caller := config.Caller{
+			Roles:     callerRoles(c),
+			Groups:    groupList,
+			ObjectID:  c.GetString("object_id"),
+			Namespace: c.GetString("issuer_namespace"),
+		}
+		if scope := policy.ScopeFor(caller); scope != nil {
+			c.Set("data_scope", scope)
+		}
+		c.Next()
+	}
+}
+
+// callerRoles returns the app roles stored in the context by Authenticate
+func callerRoles(c *gin.Context) []string {
+	roles, _ := c.Get("roles")
+	list, _ := roles.([]string)
+	return list
+}
+
+// forbidden aborts the request with 403 and the standard error body plus the reason
+func forbidden(c *gin.Context, reason string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error":  "Insufficient permissions",
+		"reason": reason,
+	})
+}
@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang-service/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTenantID = "test-tenant"
+	testClientID = "test-client"
+	testKeyID    = "test-key"
+)
+
+// useTestSigningKey returns a verifier trusting the test tenant's Entra issuers, whose
+// keys are served by a local stub holding a freshly generated signing key
+func useTestSigningKey(t *testing.T) (*rsa.PrivateKey, *TokenVerifier) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	stub := newStubJWKS(t, map[string]crypto.PublicKey{testKeyID: &key.PublicKey})
+	issuers := config.EntraIssuers(testTenantID, testClientID)
+	for i := range issuers {
+		issuers[i].JWKSURL = stub.URL + "/keys"
+	}
+	return key, NewTokenVerifier(issuers, KeySetOptions{})
+}
+
+// signTestToken signs an Entra-style access token carrying the given app roles
+func signTestToken(t *testing.T, key *rsa.PrivateKey, roles ...string) string {
+	t.Helper()
+	return signTestTokenWithClaims(t, key, jwt.MapClaims{"roles": roles})
+}
+
+// signTestTokenWithClaims signs an Entra-style access token with extra claims
+func signTestTokenWithClaims(t *testing.T, key *rsa.PrivateKey, extra jwt.MapClaims) string {
+	t.Helper()
+	claims := jwt.MapClaims{
+		"iss": "https://sts.windows.net/" + testTenantID + "/",
+		"aud": testClientID,
+		"tid": testTenantID,
+		"sub": "user-1",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func newAuthzRouter(t *testing.T, verifier *TokenVerifier) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	policy, err := config.ParsePolicy([]byte(`roles:
+  Atlas.Reader:
+    - GET /api/v1/environments/**
+  Atlas.Admin:
+    - "* /api/v1/**"
+`))
+	require.NoError(t, err)
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	router := gin.New()
+	api := router.Group("/api/v1")
+	api.Use(Authenticate(verifier, nil), RequirePermission(policy))
+	api.GET("/environments/:id", ok)
+	api.POST("/environments/reload", ok)
+	api.GET("/admin/cache/stats", RequireRole("Atlas.Admin"), ok)
+	return router
+}
+
+func TestRequirePermission(t *testing.T) {
+	key, verifier := useTestSigningKey(t)
+	router := newAuthzRouter(t, verifier)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		roles  []string
+		status int
+	}{
+		{"reader reads", http.MethodGet, "/api/v1/environments/prod", []string{"Atlas.Reader"}, http.StatusOK},
+		{"reader cannot reload", http.MethodPost, "/api/v1/environments/reload", []string{"Atlas.Reader"}, http.StatusForbidden},
+		{"admin reloads", http.MethodPost, "/api/v1/environments/reload", []string{"Atlas.Admin"}, http.StatusOK},
+		{"any granting role suffices", http.MethodPost, "/api/v1/environments/reload", []string{"Atlas.Reader", "Atlas.Admin"}, http.StatusOK},
+		{"no roles", http.MethodGet, "/api/v1/environments/prod", nil, http.StatusForbidden},
+		{"unknown routes stay 404", http.MethodGet, "/api/v1/missing", nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+signTestToken(t, key, tt.roles...))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/environments/reload", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestToken(t, key, "Atlas.Reader"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Insufficient permissions", body["error"])
+	assert.Equal(t, "requires one of the roles: Atlas.Admin", body["reason"])
+
+	// Tokens signed by another key never reach authorization
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/environments/prod", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestToken(t, other, "Atlas.Admin"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRequireRole(t *testing.T) {
+	key, verifier := useTestSigningKey(t)
+	router := newAuthzRouter(t, verifier)
+
+	for roles, status := range map[string]int{"Atlas.Admin": http.StatusOK, "Atlas.Reader": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/cache/stats", nil)
+		req.Header.Set("Authorization", "Bearer "+signTestToken(t, key, roles))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code, roles)
+	}
+}
+
+func TestDataScope(t *testing.T) {
+	key, verifier := useTestSigningKey(t)
+	gin.SetMode(gin.TestMode)
+
+	policy, err := config.ParsePolicy([]byte(`roles:
+  Atlas.Reader: ["GET /api/v1/**"]
+data_access:
+  bypass_roles: [Atlas.Admin]
+  rules:
+    - groups: [group-web]
+      environments: [web]
+    - object_ids: [oid-1]
+      issuer: entra:` + testTenantID + `
+      accounts: ["111"]
+`))
+	require.NoError(t, err)
+
+	var scope *config.Scope
+	router := gin.New()
+	router.Use(Authenticate(verifier, nil), DataScope(policy))
+	router.GET("/api/v1/vms", func(c *gin.Context) {
+		value, _ := c.Get("data_scope")
+		scope, _ = value.(*config.Scope)
+		c.Status(http.StatusOK)
+	})
+
+	request := func(claims jwt.MapClaims) {
+		scope = nil
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/vms", nil)
+		req.Header.Set("Authorization", "Bearer "+signTestTokenWithClaims(t, key, claims))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	request(jwt.MapClaims{"roles": []string{"Atlas.Reader"}, "groups": []string{"group-web"}, "oid": "oid-1"})
+	require.NotNil(t, scope)
+	assert.Equal(t, []string{"web"}, scope.Environments)
+	assert.Equal(t, []string{"111"}, scope.Accounts)
+
+	request(jwt.MapClaims{"roles": []string{"Atlas.Admin"}, "groups": []string{"group-web"}})
+	assert.Nil(t, scope)
+
+	request(jwt.MapClaims{"roles": []string{"Atlas.Reader"}})
+	require.NotNil(t, scope)
+	assert.Empty(t, scope.Environments)
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang-service/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Dev tokens are issued and accepted under these names
+const (
+	DevIssuer   = "atlas-dev-auth"
+	DevAudience = "atlas-api"
+)
+
+// DevSigner signs tokens for local development with a key that only this process trusts
+type DevSigner struct {
+	key *ecdsa.PrivateKey
+	kid string
+}
+
+// NewDevSigner returns a signer using the P-256 key in the PEM file at keyPath, which is
+// created when missing. Without a path a new key is generated, so tokens do not survive
+// a restart.
+func NewDevSigner(keyPath string) (*DevSigner, error) {
+	key, err := loadDevKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode dev auth key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return &DevSigner{key: key, kid: "dev-" + hex.EncodeToString(sum[:4])}, nil
+}
+
+// loadDevKey reads, or generates and (with a path) saves, the signing key
+func loadDevKey(keyPath string) (*ecdsa.PrivateKey, error) {
+	if keyPath != "" {
+		data, err := os.ReadFile(keyPath)
+		if err == nil {
+			block, _ := pem.Decode(data)
+			if block == nil {
+				return nil, fmt.Errorf("dev auth key %s is not PEM encoded", keyPath)
+			}
+			key, err := x509.ParseECPrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse dev auth key %s: %w", keyPath, err)
+			}
+			return key, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read dev auth key: %w", err)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate dev auth key: %w", err)
+	}
+	if keyPath != "" {
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode dev auth key: %w", err)
+		}
+		data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(keyPath, data, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to save dev auth key: %w", err)
+		}
+	}
+	return key, nil
+}
+
+// Issuer describes dev tokens for the verifier: ES256 tokens from DevIssuer for
+// DevAudience, with the standard claim names
+func (s *DevSigner) Issuer() config.IssuerConfig {
+	return config.IssuerConfig{
+		Name:       "dev",
+		Issuer:     DevIssuer,
+		Audiences:  []string{DevAudience},
+		Algorithms: []string{"ES256"},
+		Claims:     config.ClaimMapping{Subject: "sub", Roles: "roles", Groups: "groups", ObjectID: "oid", TenantID: "tid", ClientID: "azp"},
+	}
+}
+
+// PublicKey returns the key dev tokens are verified with
+func (s *DevSigner) PublicKey() (string, crypto.PublicKey) {
+	return s.kid, &s.key.PublicKey
+}
+
+// Sign returns a token carrying claims, valid for ttl. The issuer, audience and
+// lifetime claims are always set by the signer.
+func (s *DevSigner) Sign(claims jwt.MapClaims, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	token := jwt.MapClaims{}
+	for name, value := range claims {
+		token[name] = value
+	}
+	token["iss"] = DevIssuer
+	token["aud"] = DevAudience
+	token["iat"] = now.Unix()
+	token["nbf"] = now.Unix()
+	token["exp"] = expiresAt.Unix()
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodES256, token)
+	unsigned.Header["kid"] = s.kid
+	signed, err := unsigned.SignedString(s.key)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign dev token: %w", err)
+	}
+	return signed, expiresAt, nil
+}
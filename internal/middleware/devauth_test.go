@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevSignerTokens(t *testing.T) {
+	signer, err := NewDevSigner("")
+	require.NoError(t, err)
+	verifier := NewTokenVerifier(nil, KeySetOptions{})
+	verifier.TrustDevSigner(signer)
+
+	token, expiresAt, err := signer.Sign(jwt.MapClaims{
+		"sub":    "alice",
+		"roles":  []string{"Atlas.Reader"},
+		"groups": []string{"group-web"},
+		"iss":    "https://evil.example.com",
+	}, time.Hour)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+
+	identity, err := verifier.Verify(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, DevIssuer, identity.Issuer)
+	assert.Equal(t, "alice", identity.Subject)
+	assert.Equal(t, []string{"Atlas.Reader"}, identity.Roles)
+	assert.Equal(t, []string{"group-web"}, identity.Groups)
+
+	// Tokens from another process's key are rejected, as are dev tokens where dev auth is off
+	other, err := NewDevSigner("")
+	require.NoError(t, err)
+	foreign, _, err := other.Sign(jwt.MapClaims{"sub": "mallory"}, time.Hour)
+	require.NoError(t, err)
+	_, err = verifier.Verify(context.Background(), foreign)
+	assert.Error(t, err)
+	_, err = NewTokenVerifier(nil, KeySetOptions{}).Verify(context.Background(), token)
+	assert.Error(t, err)
+}
+
+func TestDevSignerKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dev-auth.pem")
+	first, err := NewDevSigner(path)
+	require.NoError(t, err)
+	token, _, err := first.Sign(jwt.MapClaims{"sub": "alice"}, time.Hour)
+	require.NoError(t, err)
+
+	// A restarted server reuses the saved key, so earlier tokens stay valid
+	second, err := NewDevSigner(path)
+	require.NoError(t, err)
+	verifier := NewTokenVerifier(nil, KeySetOptions{})
+	verifier.TrustDevSigner(second)
+	_, err = verifier.Verify(context.Background(), token)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,419 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// httpClient fetches discovery documents and key sets
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// JWKS represents a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK represents a single JSON Web Key
+type JWK struct {
+	Kty string   `json:"kty"`
+	Kid string   `json:"kid"`
+	Use string   `json:"use"`
+	Alg string   `json:"alg"`
+	N   string   `json:"n"`
+	E   string   `json:"e"`
+	Crv string   `json:"crv"`
+	X   string   `json:"x"`
+	Y   string   `json:"y"`
+	X5c []string `json:"x5c"`
+	X5t string   `json:"x5t"`
+	// Nbf and Exp bound when the key may be used, as published by e.g. Azure AD B2C
+	Nbf int64 `json:"nbf,omitempty"`
+	Exp int64 `json:"exp,omitempty"`
+}
+
+// discoveryDocument holds the OpenID configuration fields the service uses
+type discoveryDocument struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// maxUnknownKeyIDs bounds how many unknown key IDs a key set remembers. Key IDs come from
+// unverified tokens, so anyone can make up new ones.
+const maxUnknownKeyIDs = 1024
+
+// KeySetOptions controls how key sets are refreshed
+type KeySetOptions struct {
+	// RefreshInterval is how often Start refetches each key set; 0 disables background refresh
+	RefreshInterval time.Duration
+	// MinRefreshInterval is the minimum time between fetches forced by unknown key IDs,
+	// and the retry delay after a failed background refresh
+	MinRefreshInterval time.Duration
+	// NegativeCacheTTL is how long an unknown key ID is rejected without refetching
+	NegativeCacheTTL time.Duration
+}
+
+// KeySet holds an issuer's signing keys by key ID. The JWKS URL is either configured or
+// looked up from the issuer's discovery document on first use. Keys are refreshed in the
+// background (see Start) and when a token names an unknown key ID, as after the issuer
+// rotated its keys; such forced refreshes are rate limited and unknown IDs are remembered
+// for a while, so tokens with bogus key IDs cannot cause a flood of fetches. A failed
+// refresh keeps the previous keys.
+type KeySet struct {
+	issuers      []string
+	discoveryURL string
+	options      KeySetOptions
+	static       bool
+
+	// fetchMu serializes fetches; mu guards the fields below
+	fetchMu sync.Mutex
+	mu      sync.RWMutex
+
+	jwksURL     string
+	keys        map[string]signingKey
+	unknown     map[string]time.Time // key ID -> when to stop rejecting it outright
+	lastAttempt time.Time
+	lastRefresh time.Time
+	lastError   string
+	lastErrorAt time.Time
+	failures    int
+}
+
+// signingKey is a public key with the validity window its JWK publishes, if any
+type signingKey struct {
+	key       crypto.PublicKey
+	notBefore time.Time
+	notAfter  time.Time
+}
+
+// KeySetStatus describes a key set's fetch state for health reporting
+type KeySetStatus struct {
+	Issuers             []string   `json:"issuers"`
+	JWKSURL             string     `json:"jwksUrl,omitempty"`
+	Keys                int        `json:"keys"`
+	LastRefresh         *time.Time `json:"lastRefresh,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastErrorAt         *time.Time `json:"lastErrorAt,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	Healthy             bool       `json:"healthy"`
+}
+
+// NewKeySet returns an empty key set for a JWKS URL or discovery document
+func NewKeySet(jwksURL, discoveryURL string, options KeySetOptions) *KeySet {
+	return &KeySet{
+		discoveryURL: discoveryURL,
+		options:      options,
+		jwksURL:      jwksURL,
+		unknown:      make(map[string]time.Time),
+	}
+}
+
+// newStaticKeySet returns a key set holding fixed keys, which is never fetched
+func newStaticKeySet(keys map[string]crypto.PublicKey) *KeySet {
+	set := &KeySet{static: true, keys: make(map[string]signingKey, len(keys)), unknown: make(map[string]time.Time)}
+	for kid, key := range keys {
+		set.keys[kid] = signingKey{key: key}
+	}
+	return set
+}
+
+// Key returns the signing key with the given ID
+func (s *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if key, ok, err := s.cached(kid); ok {
+		return key, err
+	}
+
+	// The issuer may have rotated its keys since the last fetch
+	s.forceRefresh(ctx, kid)
+	if key, ok, err := s.cached(kid); ok {
+		return key, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.options.NegativeCacheTTL > 0 {
+		s.rememberUnknown(kid, time.Now())
+	}
+	if s.keys == nil && s.lastError != "" {
+		return nil, fmt.Errorf("signing keys unavailable: %s", s.lastError)
+	}
+	return nil, fmt.Errorf("unable to find public key for kid: %s", kid)
+}
+
+// rememberUnknown rejects a key ID outright for NegativeCacheTTL. When maxUnknownKeyIDs
+// are remembered, expired IDs are dropped first and then those remembered the longest.
+// The caller holds mu.
+func (s *KeySet) rememberUnknown(kid string, now time.Time) {
+	if _, ok := s.unknown[kid]; !ok && len(s.unknown) >= maxUnknownKeyIDs {
+		for id, until := range s.unknown {
+			if now.After(until) {
+				delete(s.unknown, id)
+			}
+		}
+		for len(s.unknown) >= maxUnknownKeyIDs {
+			oldest, oldestUntil := "", time.Time{}
+			for id, until := range s.unknown {
+				if oldest == "" || until.Before(oldestUntil) {
+					oldest, oldestUntil = id, until
+				}
+			}
+			delete(s.unknown, oldest)
+		}
+	}
+	s.unknown[kid] = now.Add(s.options.NegativeCacheTTL)
+}
+
+// cached looks a key ID up without fetching. It reports false when the ID is neither a
+// known key nor a remembered unknown one.
+func (s *KeySet) cached(kid string) (crypto.PublicKey, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	if key, ok := s.keys[kid]; ok {
+		if !key.notBefore.IsZero() && now.Before(key.notBefore) {
+			return nil, true, fmt.Errorf("key %s is not valid before %s", kid, key.notBefore.Format(time.RFC3339))
+		}
+		if !key.notAfter.IsZero() && now.After(key.notAfter) {
+			return nil, true, fmt.Errorf("key %s expired at %s", kid, key.notAfter.Format(time.RFC3339))
+		}
+		return key.key, true, nil
+	}
+	if until, ok := s.unknown[kid]; ok && now.Before(until) {
+		return nil, true, fmt.Errorf("unable to find public key for kid: %s", kid)
+	}
+	return nil, false, nil
+}
+
+// forceRefresh fetches the key set for an unknown key ID unless another request already
+// fetched it or the last fetch was too recent
+func (s *KeySet) forceRefresh(ctx context.Context, kid string) {
+	if s.static {
+		return
+	}
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	s.mu.RLock()
+	_, known := s.keys[kid]
+	lastAttempt := s.lastAttempt
+	s.mu.RUnlock()
+	if known || (!lastAttempt.IsZero() && time.Since(lastAttempt) < s.options.MinRefreshInterval) {
+		return
+	}
+	// A client giving up on its request should not fail the fetch for everyone else
+	_ = s.fetch(context.WithoutCancel(ctx))
+}
+
+// Refresh fetches the key set now
+func (s *KeySet) Refresh(ctx context.Context) error {
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+	return s.fetch(ctx)
+}
+
+// run refreshes the key set every RefreshInterval until ctx is done, retrying failed
+// fetches after MinRefreshInterval
+func (s *KeySet) run(ctx context.Context) {
+	for {
+		wait := s.options.RefreshInterval
+		if err := s.Refresh(ctx); err != nil {
+			log.Printf("Warning: Failed to refresh signing keys for %s: %v", strings.Join(s.issuers, ", "), err)
+			if retry := s.options.MinRefreshInterval; retry > 0 && retry < wait {
+				wait = retry
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// fetch fetches the key set and records the outcome; the caller holds fetchMu
+func (s *KeySet) fetch(ctx context.Context) error {
+	s.mu.Lock()
+	s.lastAttempt = time.Now()
+	jwksURL := s.jwksURL
+	s.mu.Unlock()
+
+	keys, jwksURL, err := s.download(ctx, jwksURL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.lastError = err.Error()
+		s.lastErrorAt = time.Now()
+		s.failures++
+		return err
+	}
+
+	s.jwksURL = jwksURL
+	s.keys = keys
+	s.lastRefresh = time.Now()
+	s.lastError = ""
+	s.failures = 0
+	for kid, until := range s.unknown {
+		if _, ok := keys[kid]; ok || time.Now().After(until) {
+			delete(s.unknown, kid)
+		}
+	}
+	log.Printf("Loaded %d signing keys from %s", len(keys), jwksURL)
+	return nil
+}
+
+// download resolves the JWKS URL through discovery when needed and fetches the keys
+func (s *KeySet) download(ctx context.Context, jwksURL string) (map[string]signingKey, string, error) {
+	if jwksURL == "" {
+		var doc discoveryDocument
+		if err := fetchJSON(ctx, s.discoveryURL, &doc); err != nil {
+			return nil, "", fmt.Errorf("failed to fetch discovery document: %w", err)
+		}
+		if doc.JWKSURI == "" {
+			return nil, "", fmt.Errorf("discovery document %s has no jwks_uri", s.discoveryURL)
+		}
+		jwksURL = doc.JWKSURI
+	}
+
+	var jwks JWKS
+	if err := fetchJSON(ctx, jwksURL, &jwks); err != nil {
+		return nil, "", fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	keys := make(map[string]signingKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			log.Printf("Skipping JWK %s from %s: %v", jwk.Kid, jwksURL, err)
+			continue
+		}
+		signing := signingKey{key: key}
+		if jwk.Nbf > 0 {
+			signing.notBefore = time.Unix(jwk.Nbf, 0)
+		}
+		if jwk.Exp > 0 {
+			signing.notAfter = time.Unix(jwk.Exp, 0)
+		}
+		keys[jwk.Kid] = signing
+	}
+	return keys, jwksURL, nil
+}
+
+// Status returns the key set's fetch state. A key set is healthy once it has keys and
+// its last fetch succeeded.
+func (s *KeySet) Status() KeySetStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := KeySetStatus{
+		Issuers:             s.issuers,
+		JWKSURL:             s.jwksURL,
+		Keys:                len(s.keys),
+		LastError:           s.lastError,
+		ConsecutiveFailures: s.failures,
+		Healthy:             s.keys != nil && s.lastError == "",
+	}
+	if !s.lastRefresh.IsZero() {
+		lastRefresh := s.lastRefresh
+		status.LastRefresh = &lastRefresh
+	}
+	if !s.lastErrorAt.IsZero() {
+		lastErrorAt := s.lastErrorAt
+		status.LastErrorAt = &lastErrorAt
+	}
+	return status
+}
+
+// fetchJSON GETs a URL and decodes its JSON body into v
+func fetchJSON(ctx context.Context, url string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", url, err)
+	}
+	return nil
+}
+
+// PublicKey converts the JWK to an RSA or ECDSA (P-256) public key, from its
+// parameters or, failing those, its first x5c certificate
+func (jwk JWK) PublicKey() (crypto.PublicKey, error) {
+	switch {
+	case jwk.Kty == "RSA" && jwk.N != "" && jwk.E != "":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+
+	case jwk.Kty == "EC" && jwk.X != "" && jwk.Y != "":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("point is not on curve %s", jwk.Crv)
+		}
+		return key, nil
+
+	case len(jwk.X5c) > 0:
+		der, err := base64.StdEncoding.DecodeString(jwk.X5c[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode x5c certificate: %w", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		switch key := cert.PublicKey.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			return key, nil
+		}
+		return nil, fmt.Errorf("certificate does not contain an RSA or ECDSA public key")
+	}
+	return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func TestKeySetNegativeCaching(t *testing.T) {
+	key := newTestKey(t)
+	stub := newStubJWKS(t, map[string]crypto.PublicKey{"a": &key.PublicKey})
+	keys := NewKeySet(stub.URL+"/keys", "", KeySetOptions{NegativeCacheTTL: time.Hour})
+
+	_, err := keys.Key(context.Background(), "a")
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = keys.Key(context.Background(), "bogus")
+		assert.Error(t, err)
+	}
+	// The first lookup of the unknown ID refetches; later ones are answered from memory
+	assert.Equal(t, 2, stub.keyRequests())
+}
+
+func TestKeySetBoundsUnknownKeyIDs(t *testing.T) {
+	keys := newStaticKeySet(nil)
+	keys.options.NegativeCacheTTL = time.Hour
+	now := time.Now()
+
+	keys.unknown["expired"] = now.Add(-time.Minute)
+	for i := 1; i < maxUnknownKeyIDs; i++ {
+		keys.rememberUnknown(fmt.Sprintf("kid-%d", i), now.Add(time.Duration(i)*time.Second))
+	}
+	require.Len(t, keys.unknown, maxUnknownKeyIDs)
+
+	// Expired IDs go first, then the ones remembered the longest
+	keys.rememberUnknown("new-1", now.Add(time.Hour))
+	assert.Len(t, keys.unknown, maxUnknownKeyIDs)
+	assert.NotContains(t, keys.unknown, "expired")
+
+	keys.rememberUnknown("new-2", now.Add(time.Hour))
+	assert.Len(t, keys.unknown, maxUnknownKeyIDs)
+	assert.NotContains(t, keys.unknown, "kid-1")
+	assert.Contains(t, keys.unknown, "new-1")
+}
+
+func TestKeySetRateLimitsForcedRefreshes(t *testing.T) {
+	key := newTestKey(t)
+	stub := newStubJWKS(t, map[string]crypto.PublicKey{"a": &key.PublicKey})
+	keys := NewKeySet(stub.URL+"/keys", "", KeySetOptions{MinRefreshInterval: time.Hour})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _ = keys.Key(context.Background(), "bogus-"+string(rune('a'+i)))
+			_, _ = keys.Key(context.Background(), "a")
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, stub.keyRequests())
+
+	_, err := keys.Key(context.Background(), "a")
+	assert.NoError(t, err)
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey, newKey := newTestKey(t), newTestKey(t)
+	stub := newStubJWKS(t, map[string]crypto.PublicKey{"old": &oldKey.PublicKey})
+	keys := NewKeySet(stub.URL+"/keys", "", KeySetOptions{})
+
+	_, err := keys.Key(context.Background(), "old")
+	require.NoError(t, err)
+
+	// A token signed with a key published after the last fetch triggers a refresh, after
+	// which the retired key is no longer trusted
+	stub.setKeys(map[string]crypto.PublicKey{"new": &newKey.PublicKey})
+	found, err := keys.Key(context.Background(), "new")
+	require.NoError(t, err)
+	assert.Equal(t, &newKey.PublicKey, found)
+	_, err = keys.Key(context.Background(), "old")
+	assert.Error(t, err)
+}
+
+func TestKeySetHonoursNotBefore(t *testing.T) {
+	current, upcoming := newTestKey(t), newTestKey(t)
+	stub := newStubJWKS(t, map[string]crypto.PublicKey{"current": &current.PublicKey, "upcoming": &upcoming.PublicKey})
+	stub.nbf = map[string]int64{
+		"current":  time.Now().Add(-time.Hour).Unix(),
+		"upcoming": time.Now().Add(time.Hour).Unix(),
+	}
+	keys := NewKeySet(stub.URL+"/keys", "", KeySetOptions{})
+
+	_, err := keys.Key(context.Background(), "current")
+	assert.NoError(t, err)
+	_, err = keys.Key(context.Background(), "upcoming")
+	assert.ErrorContains(t, err, "not valid before")
+	assert.Equal(t, 1, stub.keyRequests())
+}
+
+func TestKeySetBackgroundRefreshAndStatus(t *testing.T) {
+	first, second := newTestKey(t), newTestKey(t)
+	stub := newStubJWKS(t, map[string]crypto.PublicKey{"first": &first.PublicKey})
+	verifier := NewTokenVerifier(nil, KeySetOptions{})
+	keys := NewKeySet("", stub.URL+"/.well-known/openid-configuration", KeySetOptions{
+		RefreshInterval:    20 * time.Millisecond,
+		MinRefreshInterval: 10 * time.Millisecond,
+	})
+	keys.issuers = []string{"stub"}
+	verifier.keySets = append(verifier.keySets, keys)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	verifier.Start(ctx)
+
+	assert.Eventually(t, func() bool { return keys.Status().Healthy }, time.Second, 5*time.Millisecond)
+	status := verifier.Status()[0]
+	assert.Equal(t, []string{"stub"}, status.Issuers)
+	assert.Equal(t, stub.URL+"/keys", status.JWKSURL)
+	assert.Equal(t, 1, status.Keys)
+
+	// Failures are reported while the previous keys keep working
+	stub.failWith(http.StatusInternalServerError)
+	assert.Eventually(t, func() bool { return keys.Status().ConsecutiveFailures > 0 }, time.Second, 5*time.Millisecond)
+	status = keys.Status()
+	assert.False(t, status.Healthy)
+	assert.Contains(t, status.LastError, "status 500")
+	assert.Equal(t, 1, status.Keys)
+	_, err := keys.Key(context.Background(), "first")
+	assert.NoError(t, err)
+
+	// Rotated keys are picked up without any request asking for them
+	stub.setKeys(map[string]crypto.PublicKey{"second": &second.PublicKey})
+	stub.failWith(0)
+	assert.Eventually(t, func() bool {
+		s := keys.Status()
+		return s.Healthy && s.ConsecutiveFailures == 0 && s.Keys == 1 && keys.hasKey("second")
+	}, time.Second, 5*time.Millisecond)
+}
+
+// hasKey reports whether the key set currently holds a key ID, without fetching
+func (s *KeySet) hasKey(kid string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.keys[kid]
+	return ok
+}
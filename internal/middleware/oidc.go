@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang-service/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clockSkew is the leeway allowed when checking exp, nbf and iat
+const clockSkew = time.Minute
+
+// Identity is the caller described by a verified token, with claims read through the
+// issuer's claim mapping
+type Identity struct {
+	Issuer string
+	// Namespace is the issuer's identity namespace, which qualifies Subject and ObjectID
+	Namespace string
+	Subject   string
+	ObjectID  string
+	TenantID  string
+	ClientID  string
+	Name      string
+	Email     string
+	Roles     []string
+	Groups    []string
+	Claims    jwt.MapClaims
+}
+
+// TokenVerifier verifies access tokens from a set of configured issuers
+type TokenVerifier struct {
+	issuers map[string]*trustedIssuer
+	keySets []*KeySet
+}
+
+// trustedIssuer is a configured issuer together with its signing keys
+type trustedIssuer struct {
+	config config.IssuerConfig
+	keys   *KeySet
+}
+
+// NewTokenVerifier returns a verifier trusting the given issuers. Issuers publishing
+// their keys at the same place (such as Entra ID's v1.0 and v2.0 issuers) share a key set.
+func NewTokenVerifier(issuers []config.IssuerConfig, options KeySetOptions) *TokenVerifier {
+	verifier := &TokenVerifier{issuers: make(map[string]*trustedIssuer, len(issuers))}
+	keySets := make(map[string]*KeySet)
+	for _, issuer := range issuers {
+		source := issuer.JWKSURL
+		if source == "" {
+			source = "discovery:" + issuer.DiscoveryURL
+		}
+		keys, ok := keySets[source]
+		if !ok {
+			keys = NewKeySet(issuer.JWKSURL, issuer.DiscoveryURL, options)
+			keySets[source] = keys
+			verifier.keySets = append(verifier.keySets, keys)
+		}
+		keys.issuers = append(keys.issuers, issuer.Name)
+		verifier.issuers[issuer.Issuer] = &trustedIssuer{config: issuer, keys: keys}
+	}
+	return verifier
+}
+
+// TrustDevSigner makes the verifier accept tokens signed by a dev signer
+func (v *TokenVerifier) TrustDevSigner(signer *DevSigner) {
+	kid, key := signer.PublicKey()
+	v.issuers[DevIssuer] = &trustedIssuer{
+		config: signer.Issuer(),
+		keys:   newStaticKeySet(map[string]crypto.PublicKey{kid: key}),
+	}
+}
+
+// Start fetches every key set in the background and keeps refreshing them until ctx is
+// done. Without it keys are only fetched when tokens need them.
+func (v *TokenVerifier) Start(ctx context.Context) {
+	for _, keys := range v.keySets {
+		if keys.options.RefreshInterval > 0 {
+			go keys.run(ctx)
+		}
+	}
+}
+
+// Status returns the fetch state of every key set
+func (v *TokenVerifier) Status() []KeySetStatus {
+	statuses := make([]KeySetStatus, 0, len(v.keySets))
+	for _, keys := range v.keySets {
+		statuses = append(statuses, keys.Status())
+	}
+	return statuses
+}
+
+// Verify checks a token's signature, issuer, audience and lifetime, and returns the
+// identity it carries
+func (v *TokenVerifier) Verify(ctx context.Context, tokenString string) (*Identity, error) {
+	// The issuer decides which keys, algorithms and audiences apply, so read it first
+	unverified, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return nil, fmt.Errorf("malformed token: %w", err)
+	}
+	iss, _ := unverified.Claims.GetIssuer()
+	issuer, ok := v.issuers[iss]
+	if !ok {
+		return nil, fmt.Errorf("untrusted issuer %q", iss)
+	}
+
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(issuer.config.Algorithms),
+		jwt.WithIssuer(iss),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	_, err = parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok || kid == "" {
+			return nil, errors.New("token header missing 'kid'")
+		}
+		return issuer.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	audiences, _ := claims.GetAudience()
+	if !intersectsAudience(audiences, issuer.config.Audiences) {
+		return nil, fmt.Errorf("token audience %v is not accepted by issuer %s", audiences, issuer.config.Name)
+	}
+
+	namespace := issuer.config.Namespace
+	if namespace == "" {
+		namespace = iss
+	}
+	mapping := issuer.config.Claims
+	return &Identity{
+		Issuer:    iss,
+		Namespace: namespace,
+		Subject:   stringClaim(claims, mapping.Subject),
+		ObjectID:  stringClaim(claims, mapping.ObjectID),
+		TenantID:  stringClaim(claims, mapping.TenantID),
+		ClientID:  stringClaim(claims, mapping.ClientID),
+		Name:      stringClaim(claims, mapping.Name),
+		Email:     stringClaim(claims, mapping.Email),
+		Roles:     stringsClaim(claims, mapping.Roles),
+		Groups:    stringsClaim(claims, mapping.Groups),
+		Claims:    claims,
+	}, nil
+}
+
+// intersectsAudience reports whether the token names any accepted audience
+func intersectsAudience(audiences, accepted []string) bool {
+	for _, audience := range audiences {
+		for _, want := range accepted {
+			if audience == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookupClaim returns the claim at a dotted path, e.g. "realm_access.roles"
+func lookupClaim(claims map[string]interface{}, path string) interface{} {
+	var value interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+// stringClaim returns a string claim, or "" when it is missing or not a string
+func stringClaim(claims jwt.MapClaims, path string) string {
+	value, _ := lookupClaim(claims, path).(string)
+	return value
+}
+
+// stringsClaim returns a list claim's strings; a single string is a one-item list
+func stringsClaim(claims jwt.MapClaims, path string) []string {
+	switch value := lookupClaim(claims, path).(type) {
+	case string:
+		if value != "" {
+			return []string{value}
+		}
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+		return items
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang-service/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubJWKS serves a discovery document at /.well-known/openid-configuration and the
+// public keys it is given at /keys
+type stubJWKS struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey
+	nbf      map[string]int64
+	status   int
+	requests int
+}
+
+func newStubJWKS(t *testing.T, keys map[string]crypto.PublicKey) *stubJWKS {
+	t.Helper()
+	stub := &stubJWKS{keys: keys}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(discoveryDocument{Issuer: stub.URL, JWKSURI: stub.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		stub.requests++
+		if stub.status != 0 {
+			w.WriteHeader(stub.status)
+			return
+		}
+		var jwks JWKS
+		for kid, key := range stub.keys {
+			jwk := toJWK(kid, key)
+			jwk.Nbf = stub.nbf[kid]
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+		_ = json.NewEncoder(w).Encode(jwks)
+	})
+	stub.Server = httptest.NewServer(mux)
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+// setKeys replaces the served keys, as an issuer rotating its keys would
+func (s *stubJWKS) setKeys(keys map[string]crypto.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+// failWith makes the key endpoint answer with an error status, or recover when 0
+func (s *stubJWKS) failWith(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// keyRequests returns how often the key set was fetched
+func (s *stubJWKS) keyRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// toJWK encodes an RSA or P-256 public key as a JWK
+func toJWK(kid string, key crypto.PublicKey) JWK {
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", Kid: kid, Use: "sig", N: encode(key.N), E: encode(big.NewInt(int64(key.E)))}
+	case *ecdsa.PublicKey:
+		return JWK{Kty: "EC", Kid: kid, Use: "sig", Crv: "P-256", X: encode(key.X), Y: encode(key.Y)}
+	}
+	panic("unsupported key type")
+}
+
+// signToken signs claims with the key, naming kid in the header
+func signToken(t *testing.T, method jwt.SigningMethod, key crypto.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+// validClaims returns unexpired claims for an issuer and audience
+func validClaims(iss, aud string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss": iss,
+		"aud": aud,
+		"sub": "user-1",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestTokenVerifierEntraIssuers(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	stub := newStubJWKS(t, map[string]crypto.PublicKey{"rsa": &rsaKey.PublicKey})
+
+	issuers := config.EntraIssuers(testTenantID, testClientID)
+	for i := range issuers {
+		issuers[i].DiscoveryURL = stub.URL + "/.well-known/openid-configuration"
+	}
+	verifier := NewTokenVerifier(issuers, KeySetOptions{})
+
+	// v1.0 tokens name the client in appid, v2.0 tokens in azp
+	v1 := validClaims("https://sts.windows.net/"+testTenantID+"/", testClientID)
+	v1["appid"] = "caller-app"
+	v1["tid"] = testTenantID
+	v1["roles"] = []string{"Atlas.Reader"}
+	identity, err := verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", v1))
+	require.NoError(t, err)
+	assert.Equal(t, "caller-app", identity.ClientID)
+	assert.Equal(t, testTenantID, identity.TenantID)
+	assert.Equal(t, []string{"Atlas.Reader"}, identity.Roles)
+
+	v2 := validClaims("https://login.microsoftonline.com/"+testTenantID+"/v2.0", "api://"+testClientID)
+	v2["azp"] = "caller-app"
+	v2["oid"] = "oid-1"
+	v2["name"] = "Alice"
+	v2["preferred_username"] = "alice@example.com"
+	identity, err = verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", v2))
+	require.NoError(t, err)
+	assert.Equal(t, "https://login.microsoftonline.com/"+testTenantID+"/v2.0", identity.Issuer)
+	assert.Equal(t, "caller-app", identity.ClientID)
+	assert.Equal(t, "oid-1", identity.ObjectID)
+	assert.Equal(t, "Alice", identity.Name)
+	// preferred_username is not verified, so it is never taken for an email
+	assert.Empty(t, identity.Email)
+
+	// Object IDs from both issuers are qualified by the tenant
+	assert.Equal(t, config.EntraNamespace(testTenantID), identity.Namespace)
+
+	// Both issuers share one key set, fetched once
+	assert.Equal(t, 1, stub.keyRequests())
+	assert.Len(t, verifier.Status(), 1)
+}
+
+func TestTokenVerifierRejects(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	stub := newStubJWKS(t, map[string]crypto.PublicKey{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey})
+
+	issuers, err := config.ParseIssuers([]byte(`issuers:
+  - name: keycloak
+    issuer: https://idp.example.com/realms/atlas
+    jwks_url: ` + stub.URL + `/keys
+    audiences: [atlas-api]
+    algorithms: [ES256]
+    claims:
+      roles: realm_access.roles
+      client_id: client_id
+`))
+	require.NoError(t, err)
+	verifier := NewTokenVerifier(issuers, KeySetOptions{})
+	iss := "https://idp.example.com/realms/atlas"
+
+	claims := validClaims(iss, "atlas-api")
+	claims["realm_access"] = map[string]interface{}{"roles": []string{"Atlas.Admin"}}
+	claims["client_id"] = "ci"
+	identity, err := verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodES256, ecKey, "ec", claims))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Atlas.Admin"}, identity.Roles)
+	assert.Equal(t, "ci", identity.ClientID)
+	assert.Equal(t, "user-1", identity.Subject)
+
+	expired := validClaims(iss, "atlas-api")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	noExpiry := validClaims(iss, "atlas-api")
+	delete(noExpiry, "exp")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"algorithm not accepted", signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", validClaims(iss, "atlas-api"))},
+		{"wrong audience", signToken(t, jwt.SigningMethodES256, ecKey, "ec", validClaims(iss, "other-api"))},
+		{"unknown issuer", signToken(t, jwt.SigningMethodES256, ecKey, "ec", validClaims("https://evil.example.com", "atlas-api"))},
+		{"expired", signToken(t, jwt.SigningMethodES256, ecKey, "ec", expired)},
+		{"no expiry", signToken(t, jwt.SigningMethodES256, ecKey, "ec", noExpiry)},
+		{"unknown kid", signToken(t, jwt.SigningMethodES256, ecKey, "missing", validClaims(iss, "atlas-api"))},
+		{"not a token", "not-a-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(context.Background(), tt.token)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"golang-service/internal/models"
+	"golang-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserProvisioner finds or creates the user an authenticated caller is
+type UserProvisioner interface {
+	Provision(ctx context.Context, claims models.UserClaims) (*models.User, error)
+}
+
+// ProvisionUser links bearer token callers to their user, creating it on their first
+// request, and stores it under "user". Users are identified by their issuer and object ID,
+// or their subject when the issuer gives no object ID. Deactivated and deleted users, and
+// callers whose email another user has, are rejected with 403. Callers that are not users
+// (API keys, or applications without an email) pass without one. It must run after
+// Authenticate.
+func ProvisionUser(users UserProvisioner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") != "bearer" {
+			c.Next()
+			return
+		}
+
+		objectID := c.GetString("object_id")
+		if objectID == "" {
+			objectID = c.GetString("user_id")
+		}
+		user, err := users.Provision(c.Request.Context(), models.UserClaims{
+			Issuer:   c.GetString("issuer_namespace"),
+			ObjectID: objectID,
+			TenantID: c.GetString("tenant_id"),
+			Name:     c.GetString("name"),
+			Email:    c.GetString("email"),
+		})
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			c.Next()
+			return
+		case errors.Is(err, services.ErrUserDeleted):
+			forbidden(c, "user account has been deleted")
+			return
+		case errors.Is(err, services.ErrUserConflict):
+			log.Printf("User provisioning conflict for %s: %v", objectID, err)
+			forbidden(c, "email belongs to another user account")
+			return
+		case err != nil:
+			log.Printf("User provisioning error: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+			return
+		}
+
+		if !user.IsActive {
+			forbidden(c, "user account is deactivated")
+			return
+		}
+		c.Set("user", user)
+		c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang-service/internal/models"
+	"golang-service/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUsers provisions the users it holds, keyed by object ID; the object ID "taken" has
+// an email another user has
+type fakeUsers map[string]*models.User
+
+func (f fakeUsers) Provision(ctx context.Context, claims models.UserClaims) (*models.User, error) {
+	if claims.ObjectID == "taken" {
+		return nil, services.ErrUserConflict
+	}
+	user, ok := f[claims.ObjectID]
+	if !ok {
+		return nil, services.ErrUserNotFound
+	}
+	if user == nil {
+		return nil, services.ErrUserDeleted
+	}
+	return user, nil
+}
+
+func TestProvisionUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	users := fakeUsers{
+		"active":   {ID: 1, IsActive: true},
+		"inactive": {ID: 2, IsActive: false},
+		"deleted":  nil,
+	}
+
+	var gotUser *models.User
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("auth_method", c.GetHeader("X-Auth-Method"))
+		c.Set("object_id", c.GetHeader("X-Object-ID"))
+		c.Set("user_id", c.GetHeader("X-Subject"))
+	}, ProvisionUser(users))
+	router.GET("/me", func(c *gin.Context) {
+		value, _ := c.Get("user")
+		gotUser, _ = value.(*models.User)
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		objectID   string
+		subject    string
+		wantStatus int
+		wantUser   uint
+	}{
+		{"active user", "bearer", "active", "sub-1", http.StatusOK, 1},
+		{"user of an issuer without object IDs", "bearer", "", "active", http.StatusOK, 1},
+		{"deactivated user", "bearer", "inactive", "sub-2", http.StatusForbidden, 0},
+		{"deleted user", "bearer", "deleted", "sub-3", http.StatusForbidden, 0},
+		{"email of another user", "bearer", "taken", "sub-4", http.StatusForbidden, 0},
+		{"application", "bearer", "app", "sub-5", http.StatusOK, 0},
+		{"API key", "api_key", "", "apikey:1", http.StatusOK, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUser = nil
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("X-Auth-Method", tt.method)
+			req.Header.Set("X-Object-ID", tt.objectID)
+			req.Header.Set("X-Subject", tt.subject)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantUser == 0 {
+				assert.Nil(t, gotUser)
+			} else if assert.NotNil(t, gotUser) {
+				assert.Equal(t, tt.wantUser, gotUser.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey is a credential for automation such as CI pipelines, sent as
+// "Authorization: ApiKey <key>". Only a hash of its secret is stored.
+type APIKey struct {
+	ID         string   `json:"id" gorm:"primaryKey"`
+	Name       string   `json:"name" gorm:"not null"`
+	SecretHash string   `json:"-" gorm:"not null"`
+	Roles      []string `json:"roles" gorm:"serializer:json"`
+	// Environments limits the key to these environments and their descendants; empty
+	// leaves the key to the policy's data access rules for its roles
+	Environments []string   `json:"environments,omitempty" gorm:"serializer:json"`
+	CreatedBy    string     `json:"createdBy"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ExpiresAt    time.Time  `json:"expiresAt" gorm:"index"`
+	LastUsedAt   *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt    *time.Time `json:"revokedAt,omitempty"`
+	RevokedBy    string     `json:"revokedBy,omitempty"`
+}
+
+// TableName returns the table name for API keys
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// CreateAPIKeyRequest represents the request payload for creating an API key. ExpiresAt
+// and ExpiresIn (a duration such as "720h") are alternatives; without either the key
+// lives for the maximum lifetime.
+type CreateAPIKeyRequest struct {
+	Name         string     `json:"name" binding:"required"`
+	Roles        []string   `json:"roles" binding:"required,min=1"`
+	Environments []string   `json:"environments"`
+	ExpiresAt    *time.Time `json:"expiresAt"`
+	ExpiresIn    string     `json:"expiresIn"`
+}
+
+// CreatedAPIKey is a newly created API key together with its secret, which is only
+// ever returned here
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
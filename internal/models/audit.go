@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditEvent records one authenticated API request: who made it, what they asked for
+// and how it went. Admin mutations also record the action with the state before and
+// after. Events are only ever appended.
+type AuditEvent struct {
+	ID        uint64    `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"time" gorm:"index"`
+	// Caller identity, from the authentication context
+	UserID     string `json:"userId,omitempty" gorm:"index"`
+	ClientID   string `json:"clientId,omitempty" gorm:"index"`
+	ObjectID   string `json:"objectId,omitempty"`
+	Issuer     string `json:"issuer,omitempty"` // the namespace ObjectID belongs to
+	AuthMethod string `json:"authMethod,omitempty"`
+	ClientIP   string `json:"clientIp,omitempty"`
+	// Request and outcome
+	Method      string            `json:"method"`
+	Route       string            `json:"route" gorm:"index"`
+	Path        string            `json:"path"`
+	Filters     map[string]string `json:"filters,omitempty" gorm:"serializer:json"`
+	Status      int               `json:"status" gorm:"index"`
+	LatencyMs   float64           `json:"latencyMs"`
+	ResultCount *int              `json:"resultCount,omitempty"`
+	// Admin mutations
+	Action   string      `json:"action,omitempty" gorm:"index"`
+	Resource string      `json:"resource,omitempty"`
+	Before   interface{} `json:"before,omitempty" gorm:"serializer:json"`
+	After    interface{} `json:"after,omitempty" gorm:"serializer:json"`
+}
+
+// TableName returns the table name for audit events
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
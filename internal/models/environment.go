@@ -6,26 +6,51 @@ import (
 
 // Environment represents an environment configuration
 type Environment struct {
-	ID          string                 `json:"id" yaml:"id"`
-	Name        string                 `json:"name" yaml:"name"`
-	Description string                 `json:"description" yaml:"description"`
-	Criteria    EnvironmentCriteria    `json:"criteria" yaml:"criteria"`
-	Tags        []string               `json:"tags" yaml:"tags"`
-	Metadata    map[string]interface{} `json:"metadata" yaml:"metadata"`
-	CreatedAt   time.Time              `json:"createdAt"`
-	UpdatedAt   time.Time              `json:"updatedAt"`
+	ID            string                 `json:"id" yaml:"id" gorm:"primaryKey"`
+	Name          string                 `json:"name" yaml:"name" gorm:"not null"`
+	Description   string                 `json:"description" yaml:"description"`
+	Criteria      EnvironmentCriteria    `json:"criteria" yaml:"criteria" gorm:"serializer:json"`
+	Tags          []string               `json:"tags" yaml:"tags" gorm:"serializer:json"`
+	Metadata      map[string]interface{} `json:"metadata" yaml:"metadata" gorm:"serializer:json"`
+	Priority      int                    `json:"priority" yaml:"priority,omitempty" gorm:"not null;default:0"` // higher wins when several environments match
+	Parent        string                 `json:"parent,omitempty" yaml:"parent,omitempty" gorm:"index"`        // inherits criteria, tags and metadata from this environment
+	Ancestors     []string               `json:"ancestors,omitempty" yaml:"-" gorm:"-"`                        // parent chain, root first; derived on load
+	ConfigVersion int                    `json:"-" yaml:"-" gorm:"-"`                                          // configuration version it was resolved from
+	CreatedAt     time.Time              `json:"createdAt" yaml:"-"`
+	UpdatedAt     time.Time              `json:"updatedAt" yaml:"-"`
 }
 
-// EnvironmentCriteria defines the criteria for environment matching
+// TableName returns the table name for environments
+func (Environment) TableName() string {
+	return "environments"
+}
+
+// EnvironmentCriteria defines the criteria for environment matching.
+// Every criterion that is set must match, as must every rule.
 type EnvironmentCriteria struct {
-	CloudType   string `json:"cloud_type" yaml:"cloud_type"`
-	Account     string `json:"account" yaml:"account"`
-	Region      string `json:"region" yaml:"region"`
-	VPC         string `json:"vpc" yaml:"vpc"`
-	Subscription string `json:"subscription" yaml:"subscription"`
-	Location    string `json:"location" yaml:"location"`
-	Project     string `json:"project" yaml:"project"`
-	Zone        string `json:"zone" yaml:"zone"`
+	CloudType    string      `json:"cloud_type" yaml:"cloud_type"`
+	Account      string      `json:"account" yaml:"account"`
+	Region       string      `json:"region" yaml:"region"`
+	VPC          string      `json:"vpc" yaml:"vpc"`
+	Subscription string      `json:"subscription" yaml:"subscription"`
+	Location     string      `json:"location" yaml:"location"`
+	Project      string      `json:"project" yaml:"project"`
+	Zone         string      `json:"zone" yaml:"zone"`
+	Rules        []MatchRule `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// MatchRule is a condition on a VM's attributes. All conditions set on a rule must hold;
+// All and Any combine nested rules with AND and OR.
+type MatchRule struct {
+	Tag           string      `json:"tag,omitempty" yaml:"tag,omitempty"`                       // tag key that must exist
+	Equals        *string     `json:"equals,omitempty" yaml:"equals,omitempty"`                 // required value of Tag
+	Name          string      `json:"name,omitempty" yaml:"name,omitempty"`                     // glob on the VM name, e.g. "prod-*"
+	NameRegex     string      `json:"name_regex,omitempty" yaml:"name_regex,omitempty"`         // regular expression on the VM name
+	CIDR          string      `json:"cidr,omitempty" yaml:"cidr,omitempty"`                     // network containing the private IP
+	VPC           string      `json:"vpc,omitempty" yaml:"vpc,omitempty"`                       // VPC or network ID
+	ResourceGroup string      `json:"resource_group,omitempty" yaml:"resource_group,omitempty"` // Azure resource group, case-insensitive
+	All           []MatchRule `json:"all,omitempty" yaml:"all,omitempty"`
+	Any           []MatchRule `json:"any,omitempty" yaml:"any,omitempty"`
 }
 
 // EnvironmentListResponse represents the response for listing environments
@@ -46,4 +71,88 @@ type HATEOASLinks struct {
 // EnvironmentConfig represents the root configuration structure
 type EnvironmentConfig struct {
 	Environments []Environment `yaml:"environments"`
-} 
\ No newline at end of file
+}
+
+// EnvironmentImportResponse represents the result of importing environments from YAML
+type EnvironmentImportResponse struct {
+	Message  string `json:"message"`
+	Mode     string `json:"mode"`
+	Imported int    `json:"imported"`
+	Total    int    `json:"total"`
+}
+
+// EnvironmentResolveRequest asks which environment a VM resolves to. Either VMID names a VM
+// from the inventory or the attributes describe one. ProposedConfig optionally holds an
+// environments YAML document to evaluate instead of the active configuration.
+type EnvironmentResolveRequest struct {
+	VMID           string            `json:"vmId"`
+	CloudType      string            `json:"cloudType"`
+	Account        string            `json:"account"`
+	Location       string            `json:"location"`
+	VPC            string            `json:"vpc"`
+	Tags           map[string]string `json:"tags"`
+	Name           string            `json:"name"`
+	PrivateIP      string            `json:"privateIp"`
+	ResourceGroup  string            `json:"resourceGroup"`
+	ProposedConfig string            `json:"proposedConfig"`
+}
+
+// HasAttributes reports whether the request describes an ad-hoc VM
+func (r EnvironmentResolveRequest) HasAttributes() bool {
+	return r.CloudType != "" || r.Account != "" || r.Location != "" || r.VPC != "" ||
+		len(r.Tags) > 0 || r.Name != "" || r.PrivateIP != "" || r.ResourceGroup != ""
+}
+
+// VM returns the ad-hoc VM described by the request attributes
+func (r EnvironmentResolveRequest) VM() VM {
+	return VM{
+		Name:           r.Name,
+		CloudType:      r.CloudType,
+		CloudAccountID: r.Account,
+		Location:       r.Location,
+		Tags:           r.Tags,
+		PrivateIP:      r.PrivateIP,
+		VpcID:          r.VPC,
+		ResourceGroup:  r.ResourceGroup,
+	}
+}
+
+// EnvironmentConfigVersion is an immutable snapshot of an applied environments configuration
+type EnvironmentConfigVersion struct {
+	Version   int                 `json:"version" gorm:"primaryKey;autoIncrement"`
+	Author    string              `json:"author"`
+	Source    string              `json:"source"` // what applied it, e.g. create, import, file, rollback:3
+	Checksum  string              `json:"checksum" gorm:"index"`
+	Changes   []EnvironmentChange `json:"changes" gorm:"serializer:json"`     // relative to the previous version
+	Content   string              `json:"content,omitempty" gorm:"type:text"` // the configuration as YAML
+	CreatedAt time.Time           `json:"createdAt"`
+}
+
+// TableName returns the table name for environment configuration versions
+func (EnvironmentConfigVersion) TableName() string {
+	return "environment_config_versions"
+}
+
+// Environment change kinds
+const (
+	EnvironmentAdded    = "added"
+	EnvironmentRemoved  = "removed"
+	EnvironmentModified = "modified"
+)
+
+// EnvironmentChange describes how one environment differs between two configurations.
+// Before and After are only filled in for diffs requested through the API.
+type EnvironmentChange struct {
+	ID     string       `json:"id"`
+	Change string       `json:"change"`
+	Fields []string     `json:"fields,omitempty"`
+	Before *Environment `json:"before,omitempty"`
+	After  *Environment `json:"after,omitempty"`
+}
+
+// EnvironmentVersionDiff is the difference between two configuration versions
+type EnvironmentVersionDiff struct {
+	From    int                 `json:"from"`
+	To      int                 `json:"to"`
+	Changes []EnvironmentChange `json:"changes"`
+}
@@ -6,16 +6,32 @@ import (
 	"gorm.io/gorm"
 )
 
-// User represents a user in the system
+// User represents a user in the system. Users are created by admins or, on their first
+// authenticated request, from their token; Preferences are free-form settings they keep
+// for themselves. A user's identity is their object ID (AzureID) within the identity
+// namespace of the issuer that vouches for it (Issuer).
 type User struct {
-	ID        uint           `json:"id" gorm:"primarykey"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Name      string         `json:"name" gorm:"not null"`
-	AzureID   string         `json:"azure_id" gorm:"uniqueIndex"`
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint                   `json:"id" gorm:"primarykey"`
+	Email       string                 `json:"email" gorm:"uniqueIndex;not null"`
+	Name        string                 `json:"name" gorm:"not null"`
+	Issuer      string                 `json:"issuer" gorm:"uniqueIndex:idx_users_identity,priority:1,where:azure_id <> ''"`
+	AzureID     string                 `json:"azure_id" gorm:"uniqueIndex:idx_users_identity,priority:2,where:azure_id <> ''"`
+	TenantID    string                 `json:"tenant_id"`
+	IsActive    bool                   `json:"is_active" gorm:"default:true"`
+	Preferences map[string]interface{} `json:"preferences,omitempty" gorm:"serializer:json"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt         `json:"-" gorm:"index"`
+}
+
+// UserClaims describes the caller of an authenticated request, as read from the token.
+// Issuer is the identity namespace of the token's issuer.
+type UserClaims struct {
+	Issuer   string
+	ObjectID string
+	TenantID string
+	Name     string
+	Email    string
 }
 
 // CreateUserRequest represents the request payload for creating a user
@@ -23,11 +39,35 @@ type CreateUserRequest struct {
 	Email   string `json:"email" binding:"required,email"`
 	Name    string `json:"name" binding:"required"`
 	AzureID string `json:"azure_id"`
+	Issuer  string `json:"issuer"` // defaults to the caller's with azure_id
 }
 
-// UpdateUserRequest represents the request payload for updating a user
+// UpdateUserRequest represents the request payload for updating a user; fields left out
+// keep their value
 type UpdateUserRequest struct {
 	Email    *string `json:"email" binding:"omitempty,email"`
 	Name     *string `json:"name"`
+	AzureID  *string `json:"azure_id"`
+	Issuer   *string `json:"issuer"`
 	IsActive *bool   `json:"is_active"`
-}
\ No newline at end of file
+}
+
+// MeResponse describes the caller of GET /api/v1/me
+type MeResponse struct {
+	// User is null for callers that are not users, such as API keys and applications
+	User       *User  `json:"user"`
+	Subject    string `json:"subject"`
+	ObjectID   string `json:"objectId,omitempty"`
+	TenantID   string `json:"tenantId,omitempty"`
+	ClientID   string `json:"clientId,omitempty"`
+	Issuer     string `json:"issuer,omitempty"`
+	AuthMethod string `json:"authMethod"`
+	// Roles are the app roles the caller's permissions come from
+	Roles  []string `json:"roles"`
+	Groups []string `json:"groups"`
+	// Environments lists the IDs of the environments the caller may see;
+	// AllEnvironments is set when their data access is unrestricted
+	Environments    []string               `json:"environments"`
+	AllEnvironments bool                   `json:"allEnvironments"`
+	Preferences     map[string]interface{} `json:"preferences"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// SavedView is a named query preset: the filters, sort and columns a user keeps for a
+// resource such as VMs. Private views are only visible to their owner; shared views to
+// everyone, though only the owner may change them. Owner is the owner's subject, which
+// for tokens is qualified by their issuer's identity namespace as "namespace#subject".
+type SavedView struct {
+	ID           uint         `json:"id" gorm:"primaryKey"`
+	Owner        string       `json:"owner" gorm:"not null;uniqueIndex:idx_saved_views_owner_name"`
+	Name         string       `json:"name" gorm:"not null;uniqueIndex:idx_saved_views_owner_name"`
+	ResourceType string       `json:"resourceType" gorm:"not null;index"`
+	Filters      []ViewFilter `json:"filters" gorm:"serializer:json"`
+	SortBy       string       `json:"sortBy,omitempty"`
+	SortOrder    string       `json:"sortOrder,omitempty"`
+	Columns      []string     `json:"columns,omitempty" gorm:"serializer:json"`
+	Shared       bool         `json:"shared" gorm:"index"`
+	CreatedAt    time.Time    `json:"createdAt"`
+	UpdatedAt    time.Time    `json:"updatedAt"`
+	// Problems lists why the view's filters no longer fit the resource's current filter
+	// configuration; a broken view cannot be applied until it is fixed
+	Problems []string `json:"problems,omitempty" gorm:"-"`
+	Broken   bool     `json:"broken" gorm:"-"`
+}
+
+// TableName returns the table name for saved views
+func (SavedView) TableName() string {
+	return "saved_views"
+}
+
+// ViewFilter is one filter of a saved view, as it would be sent as "field_operator=value"
+type ViewFilter struct {
+	Field    string `json:"field"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+// SavedViewRequest represents the request payload for creating or replacing a saved view
+type SavedViewRequest struct {
+	Name         string       `json:"name" binding:"required"`
+	ResourceType string       `json:"resourceType" binding:"required"`
+	Filters      []ViewFilter `json:"filters"`
+	SortBy       string       `json:"sortBy"`
+	SortOrder    string       `json:"sortOrder"`
+	Columns      []string     `json:"columns"`
+	Shared       bool         `json:"shared"`
+}
@@ -109,6 +109,23 @@ func (AzureVMInstance) TableName() string {
 	return "azure_compute_virtual_machines"
 }
 
+// AzureNetworkInterface represents Azure network interfaces. The table is optional; it
+// is only synced when the CloudQuery Azure source includes network resources.
+type AzureNetworkInterface struct {
+	CqSyncTime     time.Time       `json:"-" gorm:"column:_cq_sync_time"`
+	CqID           string          `json:"-" gorm:"column:_cq_id"`
+	SubscriptionID string          `json:"subscriptionId" gorm:"column:subscription_id;index"`
+	Location       string          `json:"location"`
+	Properties     json.RawMessage `json:"-" gorm:"column:properties;type:json"`
+	ID             string          `json:"id" gorm:"primarykey"`
+	Name           string          `json:"name"`
+}
+
+// TableName returns the table name for AzureNetworkInterface
+func (AzureNetworkInterface) TableName() string {
+	return "azure_network_interfaces"
+}
+
 // GCPComputeInstance represents GCP Compute Engine instances
 type GCPComputeInstance struct {
 	CqSyncTime                              time.Time       `json:"-" gorm:"column:_cq_sync_time"`
@@ -180,14 +197,23 @@ type VM struct {
 	CloudSpecificDetails json.RawMessage        `json:"cloudSpecificDetails"`
 	Environment          *EnvironmentInfo       `json:"environment,omitempty"`
 	Env                  string                 `json:"env,omitempty"`
+	Tags                 map[string]string      `json:"tags,omitempty"`
+	PrivateIP            string                 `json:"privateIp,omitempty"`
+	VpcID                string                 `json:"vpcId,omitempty"` // VPC ID (AWS), network name (GCP) or virtual network name (Azure)
+	ResourceGroup        string                 `json:"resourceGroup,omitempty"`
+	VCPUs                int                    `json:"vcpus,omitempty"`     // when known from the instance's CPU options or machine type
+	MemoryMiB            int                    `json:"memoryMib,omitempty"` // when known from the machine type
+	SyncTime             time.Time              `json:"-"` // when CloudQuery last synced the source row
 }
 
 // EnvironmentInfo represents environment information
 type EnvironmentInfo struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	Tags        []string `json:"tags,omitempty"`
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	Ancestors     []string `json:"ancestors,omitempty"`     // parent environments, root first
+	ConfigVersion int      `json:"configVersion,omitempty"` // environment configuration version used to resolve it
 }
 
 // VMListResponse represents the response structure for VM list endpoints
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"golang-service/internal/models"
+)
+
+// API key errors
+var (
+	ErrAPIKeyNotFound       = errors.New("API key not found")
+	ErrInvalidAPIKey        = errors.New("invalid API key")
+	ErrInvalidAPIKeyRequest = errors.New("invalid API key request")
+)
+
+// apiKeyPrefix starts every API key ID, so keys are recognizable in logs and scanners
+const apiKeyPrefix = "ak_"
+
+// lastUsedResolution is how stale an API key's last-used time may get before a request
+// records it again, so busy keys do not write on every request
+const lastUsedResolution = time.Minute
+
+// APIKeyService manages API keys stored in the database. A key reads
+// "<id>.<secret>"; the ID is public and the secret is only stored as a hash.
+type APIKeyService struct {
+	db     *gorm.DB
+	maxTTL time.Duration
+}
+
+// NewAPIKeyService returns a service for the API keys in db. Keys may live at most maxTTL.
+func NewAPIKeyService(db *gorm.DB, maxTTL time.Duration) *APIKeyService {
+	return &APIKeyService{db: db, maxTTL: maxTTL}
+}
+
+// Create stores a new API key on behalf of author and returns it with its secret
+func (s *APIKeyService) Create(req models.CreateAPIKeyRequest, author string) (*models.CreatedAPIKey, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidAPIKeyRequest)
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.maxTTL)
+	switch {
+	case req.ExpiresAt != nil && req.ExpiresIn != "":
+		return nil, fmt.Errorf("%w: set expiresAt or expiresIn, not both", ErrInvalidAPIKeyRequest)
+	case req.ExpiresAt != nil:
+		expiresAt = req.ExpiresAt.UTC()
+	case req.ExpiresIn != "":
+		ttl, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("%w: expiresIn must be a positive duration such as 720h", ErrInvalidAPIKeyRequest)
+		}
+		expiresAt = now.Add(ttl)
+	}
+	if !expiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expiry must be in the future", ErrInvalidAPIKeyRequest)
+	}
+	if expiresAt.After(now.Add(s.maxTTL)) {
+		return nil, fmt.Errorf("%w: keys may live at most %s", ErrInvalidAPIKeyRequest, s.maxTTL)
+	}
+
+	id, err := randomString(6, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+
+	key := models.APIKey{
+		ID:           apiKeyPrefix + id,
+		Name:         req.Name,
+		SecretHash:   hashSecret(secret),
+		Roles:        req.Roles,
+		Environments: req.Environments,
+		CreatedBy:    author,
+		CreatedAt:    now,
+		ExpiresAt:    expiresAt,
+	}
+	if err := s.db.Create(&key).Error; err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return &models.CreatedAPIKey{APIKey: key, Key: key.ID + "." + secret}, nil
+}
+
+// List returns the API keys, newest first. Revoked keys are left out unless requested.
+func (s *APIKeyService) List(includeRevoked bool) ([]models.APIKey, error) {
+	query := s.db.Order("created_at DESC, id")
+	if !includeRevoked {
+		query = query.Where("revoked_at IS NULL")
+	}
+	keys := []models.APIKey{}
+	if err := query.Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Get returns the API key with the given ID
+func (s *APIKeyService) Get(id string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := s.db.Where("id = ?", id).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrAPIKeyNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return &key, nil
+}
+
+// Revoke disables an API key on behalf of author. Revoking a revoked key changes nothing.
+func (s *APIKeyService) Revoke(id, author string) (*models.APIKey, error) {
+	key, err := s.Get(id)
+	if err != nil || key.RevokedAt != nil {
+		return key, err
+	}
+
+	now := time.Now().UTC()
+	if err := s.db.Model(key).Updates(map[string]interface{}{"revoked_at": now, "revoked_by": author}).Error; err != nil {
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	key.RevokedAt = &now
+	key.RevokedBy = author
+	return key, nil
+}
+
+// Authenticate returns the API key a presented "<id>.<secret>" value belongs to, and
+// records its use. Unknown, revoked and expired keys all fail with ErrInvalidAPIKey.
+func (s *APIKeyService) Authenticate(ctx context.Context, presented string) (*models.APIKey, error) {
+	id, secret, ok := strings.Cut(presented, ".")
+	if !ok || !strings.HasPrefix(id, apiKeyPrefix) || secret == "" {
+		return nil, fmt.Errorf("%w: malformed key", ErrInvalidAPIKey)
+	}
+
+	var key models.APIKey
+	err := s.db.WithContext(ctx).Where("id = ?", id).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: unknown key %s", ErrInvalidAPIKey, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, fmt.Errorf("%w: wrong secret for key %s", ErrInvalidAPIKey, id)
+	}
+	now := time.Now().UTC()
+	if key.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: key %s was revoked", ErrInvalidAPIKey, id)
+	}
+	if !now.Before(key.ExpiresAt) {
+		return nil, fmt.Errorf("%w: key %s expired", ErrInvalidAPIKey, id)
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := s.db.WithContext(ctx).Model(&key).Update("last_used_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to record API key use: %w", err)
+		}
+		key.LastUsedAt = &now
+	}
+	return &key, nil
+}
+
+// hashSecret hashes an API key secret. Secrets carry 256 random bits, so a fast hash
+// is as strong as a password hash here.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString encodes n random bytes
+func randomString(n int, encode func([]byte) string) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return encode(buf), nil
+}
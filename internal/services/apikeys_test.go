@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang-service/internal/database/dbtest"
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAPIKeyService(t *testing.T) *APIKeyService {
+	t.Helper()
+	return NewAPIKeyService(dbtest.Open(t, &models.APIKey{}), 30*24*time.Hour)
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	service := newAPIKeyService(t)
+	ctx := context.Background()
+
+	created, err := service.Create(models.CreateAPIKeyRequest{
+		Name:         "ci",
+		Roles:        []string{"Atlas.Reader"},
+		Environments: []string{"prod"},
+		ExpiresIn:    "24h",
+	}, "alice")
+	require.NoError(t, err)
+	assert.Regexp(t, `^ak_[0-9a-f]{12}\.[A-Za-z0-9_-]{43}$`, created.Key)
+	assert.NotContains(t, created.SecretHash, created.Key[len(created.ID)+1:])
+	assert.Equal(t, "alice", created.CreatedBy)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), created.ExpiresAt, time.Minute)
+
+	key, err := service.Authenticate(ctx, created.Key)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Atlas.Reader"}, key.Roles)
+	assert.Equal(t, []string{"prod"}, key.Environments)
+	require.NotNil(t, key.LastUsedAt)
+
+	stored, err := service.Get(created.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.LastUsedAt)
+
+	for _, presented := range []string{
+		created.ID + ".wrong-secret",
+		"ak_000000000000." + created.Key[len(created.ID)+1:],
+		"not-a-key",
+	} {
+		_, err := service.Authenticate(ctx, presented)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, presented)
+	}
+
+	revoked, err := service.Revoke(created.ID, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", revoked.RevokedBy)
+	_, err = service.Authenticate(ctx, created.Key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	keys, err := service.List(false)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	keys, err = service.List(true)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	_, err = service.Revoke("ak_missing", "bob")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+}
+
+func TestAPIKeyExpiry(t *testing.T) {
+	service := newAPIKeyService(t)
+
+	past := time.Now().Add(-time.Hour)
+	tooLate := time.Now().Add(365 * 24 * time.Hour)
+	for name, req := range map[string]models.CreateAPIKeyRequest{
+		"past expiry":      {Name: "k", Roles: []string{"r"}, ExpiresAt: &past},
+		"beyond max ttl":   {Name: "k", Roles: []string{"r"}, ExpiresAt: &tooLate},
+		"invalid duration": {Name: "k", Roles: []string{"r"}, ExpiresIn: "soon"},
+		"both expiries":    {Name: "k", Roles: []string{"r"}, ExpiresIn: "1h", ExpiresAt: &tooLate},
+		"blank name":       {Name: " ", Roles: []string{"r"}},
+	} {
+		_, err := service.Create(req, "alice")
+		assert.ErrorIs(t, err, ErrInvalidAPIKeyRequest, name)
+	}
+
+	// Keys default to the maximum lifetime and stop working once expired
+	created, err := service.Create(models.CreateAPIKeyRequest{Name: "k", Roles: []string{"r"}}, "alice")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), created.ExpiresAt, time.Minute)
+
+	require.NoError(t, service.db.Model(&models.APIKey{}).Where("id = ?", created.ID).Update("expires_at", past).Error)
+	_, err = service.Authenticate(context.Background(), created.Key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
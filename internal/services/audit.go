@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"golang-service/internal/models"
+)
+
+// auditExportBatchSize is how many audit events an export reads at a time
+const auditExportBatchSize = 500
+
+// AuditQuery selects audit events; zero fields match everything
+type AuditQuery struct {
+	UserID   string
+	ClientID string
+	Action   string
+	Method   string
+	Route    string
+	Status   int
+	From     *time.Time
+	To       *time.Time
+}
+
+// AuditService stores and queries the audit log
+type AuditService struct {
+	db *gorm.DB
+}
+
+// NewAuditService returns a service for the audit log in db
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// Record appends an event to the audit log
+func (s *AuditService) Record(ctx context.Context, event *models.AuditEvent) error {
+	if err := s.db.WithContext(ctx).Create(event).Error; err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+// List returns one page of matching events, newest first, and the number of matches
+func (s *AuditService) List(q AuditQuery, page, pageSize int) ([]models.AuditEvent, int64, error) {
+	var total int64
+	if err := s.filter(q).Model(&models.AuditEvent{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	events := []models.AuditEvent{}
+	err := s.filter(q).Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	return events, total, nil
+}
+
+// Export passes every matching event to fn, oldest first, reading them in batches
+func (s *AuditService) Export(ctx context.Context, q AuditQuery, fn func(models.AuditEvent) error) error {
+	var batch []models.AuditEvent
+	result := s.filter(q).WithContext(ctx).Order("id").FindInBatches(&batch, auditExportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, event := range batch {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to export audit events: %w", result.Error)
+	}
+	return nil
+}
+
+// filter applies a query's conditions
+func (s *AuditService) filter(q AuditQuery) *gorm.DB {
+	tx := s.db.Model(&models.AuditEvent{})
+	for column, value := range map[string]string{
+		"user_id":   q.UserID,
+		"client_id": q.ClientID,
+		"action":    q.Action,
+		"method":    q.Method,
+		"route":     q.Route,
+	} {
+		if value != "" {
+			tx = tx.Where(column+" = ?", value)
+		}
+	}
+	if q.Status != 0 {
+		tx = tx.Where("status = ?", q.Status)
+	}
+	if q.From != nil {
+		tx = tx.Where("created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		tx = tx.Where("created_at < ?", *q.To)
+	}
+	return tx
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang-service/internal/database/dbtest"
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuditService(t *testing.T) *AuditService {
+	t.Helper()
+	return NewAuditService(dbtest.Open(t, &models.AuditEvent{}))
+}
+
+func TestAuditService(t *testing.T) {
+	service := newAuditService(t)
+	ctx := context.Background()
+	count := 3
+
+	events := []models.AuditEvent{
+		{UserID: "alice", Method: "GET", Route: "/api/v1/vms", Status: 200, Filters: map[string]string{"cloud": "aws"}, ResultCount: &count},
+		{UserID: "bob", Method: "GET", Route: "/api/v1/vms", Status: 403},
+		{UserID: "alice", Method: "POST", Route: "/api/v1/environments/reload", Status: 200, Action: "environments.reload",
+			Before: map[string]interface{}{"configVersion": 1}, After: map[string]interface{}{"configVersion": 2}},
+	}
+	for i := range events {
+		require.NoError(t, service.Record(ctx, &events[i]))
+	}
+
+	// Newest first
+	listed, total, err := service.List(AuditQuery{UserID: "alice"}, 1, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	require.Len(t, listed, 2)
+	assert.Equal(t, "environments.reload", listed[0].Action)
+	assert.Equal(t, map[string]interface{}{"configVersion": float64(2)}, listed[0].After)
+	assert.Equal(t, map[string]string{"cloud": "aws"}, listed[1].Filters)
+	require.NotNil(t, listed[1].ResultCount)
+	assert.Equal(t, 3, *listed[1].ResultCount)
+
+	listed, total, err = service.List(AuditQuery{Status: 403}, 1, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.Equal(t, "bob", listed[0].UserID)
+
+	future := time.Now().Add(time.Hour)
+	_, total, err = service.List(AuditQuery{From: &future}, 1, 10)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+
+	// Export walks every match, oldest first
+	var exported []string
+	require.NoError(t, service.Export(ctx, AuditQuery{Route: "/api/v1/vms"}, func(event models.AuditEvent) error {
+		exported = append(exported, event.UserID)
+		return nil
+	}))
+	assert.Equal(t, []string{"alice", "bob"}, exported)
+}
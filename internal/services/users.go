@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"golang-service/internal/models"
+)
+
+// User errors
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserConflict       = errors.New("user already exists")
+	ErrInvalidUserRequest = errors.New("invalid user request")
+	ErrUserDeleted        = errors.New("user has been deleted")
+)
+
+// UserService manages the users stored in the database. Deleted users are kept (soft
+// deleted) and can be restored; their email and identity stay reserved meanwhile.
+type UserService struct {
+	db *gorm.DB
+}
+
+// NewUserService returns a service for the users in db
+func NewUserService(db *gorm.DB) *UserService {
+	return &UserService{db: db}
+}
+
+// List returns one page of users and the number of matches. query narrows and orders the
+// users; deleted lists deleted users instead of current ones.
+func (s *UserService) List(query func(*gorm.DB) *gorm.DB, deleted bool, page, pageSize int) ([]models.User, int64, error) {
+	base := func() *gorm.DB {
+		tx := s.db.Model(&models.User{})
+		if deleted {
+			tx = tx.Unscoped().Where("deleted_at IS NOT NULL")
+		}
+		return tx.Scopes(query)
+	}
+
+	var total int64
+	if err := base().Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	users := []models.User{}
+	if err := base().Offset((page - 1) * pageSize).Limit(pageSize).Find(&users).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, total, nil
+}
+
+// Get returns a user that is not deleted
+func (s *UserService) Get(id uint) (*models.User, error) {
+	var user models.User
+	err := s.db.First(&user, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %d", ErrUserNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	return &user, nil
+}
+
+// Create stores a new, active user
+func (s *UserService) Create(req models.CreateUserRequest) (*models.User, error) {
+	user := models.User{
+		Email:    normalizeEmail(req.Email),
+		Name:     strings.TrimSpace(req.Name),
+		Issuer:   strings.TrimSpace(req.Issuer),
+		AzureID:  strings.TrimSpace(req.AzureID),
+		IsActive: true,
+	}
+	if user.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidUserRequest)
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkUserUnique(tx, user); err != nil {
+			return err
+		}
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		return nil, userWriteError(err, "create")
+	}
+	return &user, nil
+}
+
+// Update changes the fields set in req
+func (s *UserService) Update(id uint, req models.UpdateUserRequest) (*models.User, error) {
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, id).Error; err != nil {
+			return err
+		}
+
+		changes := map[string]interface{}{}
+		if req.Email != nil {
+			user.Email = normalizeEmail(*req.Email)
+			changes["email"] = user.Email
+		}
+		if req.Name != nil {
+			if user.Name = strings.TrimSpace(*req.Name); user.Name == "" {
+				return fmt.Errorf("%w: name cannot be empty", ErrInvalidUserRequest)
+			}
+			changes["name"] = user.Name
+		}
+		if req.AzureID != nil {
+			user.AzureID = strings.TrimSpace(*req.AzureID)
+			changes["azure_id"] = user.AzureID
+		}
+		if req.Issuer != nil {
+			user.Issuer = strings.TrimSpace(*req.Issuer)
+			changes["issuer"] = user.Issuer
+		}
+		if req.IsActive != nil {
+			user.IsActive = *req.IsActive
+			changes["is_active"] = user.IsActive
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+
+		if err := checkUserUnique(tx, user); err != nil {
+			return err
+		}
+		return tx.Model(&user).Updates(changes).Error
+	})
+	if err != nil {
+		return nil, userWriteError(err, "update")
+	}
+	return &user, nil
+}
+
+// Delete soft deletes a user and returns it as it was
+func (s *UserService) Delete(id uint) (*models.User, error) {
+	user, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Delete(user).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+	return user, nil
+}
+
+// Restore undoes the deletion of a user
+func (s *UserService) Restore(id uint) (*models.User, error) {
+	var user models.User
+	err := s.db.Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: no deleted user %d", ErrUserNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	if err := s.db.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+		return nil, fmt.Errorf("failed to restore user: %w", err)
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	return &user, nil
+}
+
+// Provision returns the user a token was issued to, creating it on the user's first
+// request. Users are found by their issuer and object ID only: email comes from claims
+// users can change, so a user an admin created is linked by giving it the object ID
+// beforehand. Their name, email and tenant follow the token.
+// Callers without a user who cannot be created, such as applications without an email,
+// fail with ErrUserNotFound, deleted users with ErrUserDeleted, and callers whose email
+// another user has with ErrUserConflict.
+func (s *UserService) Provision(ctx context.Context, claims models.UserClaims) (*models.User, error) {
+	if claims.ObjectID == "" || claims.Issuer == "" {
+		return nil, fmt.Errorf("%w: the token names no object ID", ErrUserNotFound)
+	}
+	email := normalizeEmail(claims.Email)
+	name := strings.TrimSpace(claims.Name)
+	db := s.db.WithContext(ctx)
+
+	var user models.User
+	err := db.Unscoped().Where("issuer = ? AND azure_id = ?", claims.Issuer, claims.ObjectID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.createProvisioned(db, claims, name, email)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	if user.DeletedAt.Valid {
+		return nil, fmt.Errorf("%w: %d", ErrUserDeleted, user.ID)
+	}
+
+	// Only write when the token says something new, so most requests only read
+	changes := map[string]interface{}{}
+	if claims.TenantID != "" && user.TenantID != claims.TenantID {
+		user.TenantID = claims.TenantID
+		changes["tenant_id"] = user.TenantID
+	}
+	if name != "" && user.Name != name {
+		user.Name = name
+		changes["name"] = user.Name
+	}
+	// An email another user already has is left alone
+	if email != "" && user.Email != email && checkUserUnique(db, models.User{ID: user.ID, Email: email}) == nil {
+		user.Email = email
+		changes["email"] = user.Email
+	}
+	if len(changes) > 0 {
+		if err := db.Model(&user).Updates(changes).Error; err != nil {
+			return nil, fmt.Errorf("failed to update user: %w", err)
+		}
+	}
+	return &user, nil
+}
+
+// createProvisioned creates the user for a token seen for the first time
+func (s *UserService) createProvisioned(db *gorm.DB, claims models.UserClaims, name, email string) (*models.User, error) {
+	if email == "" {
+		return nil, fmt.Errorf("%w: no email to create user %s with", ErrUserNotFound, claims.ObjectID)
+	}
+	if name == "" {
+		name = email
+	}
+
+	user := models.User{Email: email, Name: name, Issuer: claims.Issuer, AzureID: claims.ObjectID, TenantID: claims.TenantID, IsActive: true}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := checkUserUnique(tx, user); err != nil {
+			return err
+		}
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		// Concurrent first requests race to create the user; the loser uses the winner's
+		var existing models.User
+		if db.Where("issuer = ? AND azure_id = ?", claims.Issuer, claims.ObjectID).First(&existing).Error == nil {
+			return &existing, nil
+		}
+		return nil, userWriteError(err, "provision")
+	}
+	return &user, nil
+}
+
+// UpdatePreferences merges preferences into a user's; keys set to null are removed
+func (s *UserService) UpdatePreferences(id uint, preferences map[string]interface{}) (*models.User, error) {
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, id).Error; err != nil {
+			return err
+		}
+		if user.Preferences == nil {
+			user.Preferences = map[string]interface{}{}
+		}
+		for key, value := range preferences {
+			if value == nil {
+				delete(user.Preferences, key)
+			} else {
+				user.Preferences[key] = value
+			}
+		}
+		return tx.Model(&user).Select("preferences").Updates(&user).Error
+	})
+	if err != nil {
+		return nil, userWriteError(err, "update")
+	}
+	return &user, nil
+}
+
+// AdoptLegacyIdentities assigns an issuer to the users linked to an object ID before
+// identities were qualified by issuer, so that they keep signing in as themselves. It
+// returns the number of users adopted.
+func (s *UserService) AdoptLegacyIdentities(issuer string) (int64, error) {
+	result := s.db.Unscoped().Model(&models.User{}).
+		Where("issuer = '' AND azure_id <> ''").
+		Update("issuer", issuer)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to adopt user identities: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// UnlinkedUsers returns the users not linked to an object ID, such as users admins created
+// without one. Tokens never match them, so their owners are refused on sign-in (their
+// email is taken) until an admin sets their azure_id and issuer.
+func (s *UserService) UnlinkedUsers() ([]models.User, error) {
+	users := []models.User{}
+	if err := s.db.Where("azure_id = ''").Order("id").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to list unlinked users: %w", err)
+	}
+	return users, nil
+}
+
+// checkUserUnique fails with ErrUserConflict when another user, deleted or not, has the
+// user's email or identity
+func checkUserUnique(tx *gorm.DB, user models.User) error {
+	var existing models.User
+	err := tx.Unscoped().
+		Where("id <> ? AND (email = ? OR (issuer = ? AND azure_id = ? AND azure_id <> ''))", user.ID, user.Email, user.Issuer, user.AzureID).
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	field := "email " + user.Email
+	if existing.Email != user.Email {
+		field = "Azure ID " + user.AzureID
+	}
+	if existing.DeletedAt.Valid {
+		return fmt.Errorf("%w: %s belongs to deleted user %d, which can be restored", ErrUserConflict, field, existing.ID)
+	}
+	return fmt.Errorf("%w: %s belongs to user %d", ErrUserConflict, field, existing.ID)
+}
+
+// userWriteError passes on the service's own errors and wraps the others
+func userWriteError(err error, action string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrUserNotFound
+	case errors.Is(err, ErrUserConflict), errors.Is(err, ErrInvalidUserRequest):
+		return err
+	}
+	return fmt.Errorf("failed to %s user: %w", action, err)
+}
+
+// normalizeEmail lower-cases an email address so lookups and uniqueness ignore case
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
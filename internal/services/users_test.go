@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"golang-service/internal/database/dbtest"
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newUserService(t *testing.T) (*UserService, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t, &models.User{})
+	return NewUserService(db), db
+}
+
+func TestProvisionUser(t *testing.T) {
+	service, db := newUserService(t)
+	ctx := context.Background()
+	claims := models.UserClaims{Issuer: "entra:tenant", ObjectID: "oid-1", TenantID: "tenant", Name: "Alice", Email: "Alice@Example.com"}
+
+	// The first request creates the user, later ones find it
+	created, err := service.Provision(ctx, claims)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", created.Email)
+	assert.Equal(t, "oid-1", created.AzureID)
+	assert.Equal(t, "entra:tenant", created.Issuer)
+	assert.Equal(t, "tenant", created.TenantID)
+	assert.True(t, created.IsActive)
+
+	claims.Name = "Alice Smith"
+	found, err := service.Provision(ctx, claims)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, found.ID)
+	assert.Equal(t, "Alice Smith", found.Name)
+
+	// The same object ID from another issuer is someone else, and Alice's email is taken
+	_, err = service.Provision(ctx, models.UserClaims{Issuer: "https://idp.example.com", ObjectID: "oid-1", Email: "mallory@example.com"})
+	require.NoError(t, err)
+	other, err := service.Provision(ctx, models.UserClaims{Issuer: "https://idp.example.com", ObjectID: "oid-1"})
+	require.NoError(t, err)
+	assert.NotEqual(t, created.ID, other.ID)
+
+	// Users created by an admin are only linked by their identity, never by email
+	admin, err := service.Create(models.CreateUserRequest{Email: "bob@example.com", Name: "Bob"})
+	require.NoError(t, err)
+	_, err = service.Provision(ctx, models.UserClaims{Issuer: "entra:tenant", ObjectID: "oid-2", Email: "bob@example.com"})
+	assert.ErrorIs(t, err, ErrUserConflict)
+	azureID, issuer := "oid-2", "entra:tenant"
+	_, err = service.Update(admin.ID, models.UpdateUserRequest{AzureID: &azureID, Issuer: &issuer})
+	require.NoError(t, err)
+	linked, err := service.Provision(ctx, models.UserClaims{Issuer: "entra:tenant", ObjectID: "oid-2", Email: "bob@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, admin.ID, linked.ID)
+	assert.Equal(t, "Bob", linked.Name)
+
+	// Applications without an email are not users
+	_, err = service.Provision(ctx, models.UserClaims{Issuer: "entra:tenant", ObjectID: "oid-app"})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	require.NoError(t, db.Delete(&models.User{}, linked.ID).Error)
+	_, err = service.Provision(ctx, models.UserClaims{Issuer: "entra:tenant", ObjectID: "oid-2", Email: "bob@example.com"})
+	assert.ErrorIs(t, err, ErrUserDeleted)
+
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&models.User{}).Count(&count).Error)
+	assert.EqualValues(t, 3, count)
+}
+
+func TestUserIdentities(t *testing.T) {
+	service, db := newUserService(t)
+
+	_, err := service.Create(models.CreateUserRequest{Email: "alice@example.com", Name: "Alice", AzureID: "oid-1", Issuer: "entra:tenant"})
+	require.NoError(t, err)
+	_, err = service.Create(models.CreateUserRequest{Email: "bob@example.com", Name: "Bob", AzureID: "oid-1", Issuer: "entra:tenant"})
+	assert.ErrorIs(t, err, ErrUserConflict)
+	_, err = service.Create(models.CreateUserRequest{Email: "bob@example.com", Name: "Bob", AzureID: "oid-1", Issuer: "https://idp.example.com"})
+	require.NoError(t, err)
+
+	// Users linked without an issuer, such as before identities carried one, are adopted by one
+	require.NoError(t, db.Create(&models.User{Email: "carol@example.com", Name: "Carol", AzureID: "oid-3", IsActive: true}).Error)
+	_, err = service.Create(models.CreateUserRequest{Email: "dave@example.com", Name: "Dave", AzureID: "oid-4"})
+	require.NoError(t, err)
+	adopted, err := service.AdoptLegacyIdentities("entra:tenant")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, adopted)
+
+	carol, err := service.Provision(context.Background(), models.UserClaims{Issuer: "entra:tenant", ObjectID: "oid-3"})
+	require.NoError(t, err)
+	assert.Equal(t, "carol@example.com", carol.Email)
+
+	// Users without an object ID are never matched by a token
+	_, err = service.Create(models.CreateUserRequest{Email: "erin@example.com", Name: "Erin"})
+	require.NoError(t, err)
+	unlinked, err := service.UnlinkedUsers()
+	require.NoError(t, err)
+	require.Len(t, unlinked, 1)
+	assert.Equal(t, "erin@example.com", unlinked[0].Email)
+}
+
+func TestUpdatePreferences(t *testing.T) {
+	service, _ := newUserService(t)
+	user, err := service.Create(models.CreateUserRequest{Email: "alice@example.com", Name: "Alice"})
+	require.NoError(t, err)
+
+	_, err = service.UpdatePreferences(user.ID, map[string]interface{}{"theme": "dark", "pageSize": float64(50)})
+	require.NoError(t, err)
+	updated, err := service.UpdatePreferences(user.ID, map[string]interface{}{"theme": nil})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"pageSize": float64(50)}, updated.Preferences)
+
+	stored, err := service.Get(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"pageSize": float64(50)}, stored.Preferences)
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"golang-service/internal/config"
+	"golang-service/internal/models"
+)
+
+// Saved view errors
+var (
+	ErrViewNotFound = errors.New("view not found")
+	ErrViewConflict = errors.New("view already exists")
+	ErrInvalidView  = errors.New("invalid view")
+	ErrViewNotOwner = errors.New("only the owner may change a view")
+	ErrBrokenView   = errors.New("view is broken")
+)
+
+// viewResources lists the resources views can be saved for, with the filter
+// configuration their filters must satisfy
+var viewResources = map[string]func() config.FilterConfig{
+	"vms": config.VMsFilterConfig,
+}
+
+// ViewService manages saved views. Views are checked against the current filter
+// configuration whenever they are loaded, so views broken by a configuration change are
+// reported instead of silently applying fewer filters.
+type ViewService struct {
+	db *gorm.DB
+}
+
+// NewViewService returns a service for the saved views in db
+func NewViewService(db *gorm.DB) *ViewService {
+	return &ViewService{db: db}
+}
+
+// List returns the views visible to owner, their own and shared ones, by name.
+// resourceType, when set, only lists views of that resource.
+func (s *ViewService) List(owner, resourceType string) ([]models.SavedView, error) {
+	tx := s.db.Where("owner = ? OR shared = ?", owner, true)
+	if resourceType != "" {
+		tx = tx.Where("resource_type = ?", resourceType)
+	}
+
+	views := []models.SavedView{}
+	if err := tx.Order("name").Order("id").Find(&views).Error; err != nil {
+		return nil, fmt.Errorf("failed to list views: %w", err)
+	}
+	for i := range views {
+		CheckView(&views[i])
+	}
+	return views, nil
+}
+
+// Get returns a view visible to owner
+func (s *ViewService) Get(id uint, owner string) (*models.SavedView, error) {
+	var view models.SavedView
+	err := s.db.Where("owner = ? OR shared = ?", owner, true).First(&view, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %d", ErrViewNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load view: %w", err)
+	}
+	CheckView(&view)
+	return &view, nil
+}
+
+// Create stores a new view for owner
+func (s *ViewService) Create(req models.SavedViewRequest, owner string) (*models.SavedView, error) {
+	if owner == "" {
+		return nil, fmt.Errorf("%w: an owner is required", ErrInvalidView)
+	}
+	view := models.SavedView{Owner: owner}
+	if err := applyViewRequest(&view, req); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkViewName(tx, view); err != nil {
+			return err
+		}
+		return tx.Create(&view).Error
+	})
+	if err != nil {
+		return nil, viewWriteError(err, "create")
+	}
+	return &view, nil
+}
+
+// Update replaces a view owned by owner
+func (s *ViewService) Update(id uint, req models.SavedViewRequest, owner string) (*models.SavedView, error) {
+	view, err := s.owned(id, owner)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyViewRequest(view, req); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkViewName(tx, *view); err != nil {
+			return err
+		}
+		return tx.Save(view).Error
+	})
+	if err != nil {
+		return nil, viewWriteError(err, "update")
+	}
+	return view, nil
+}
+
+// Delete removes a view owned by owner
+func (s *ViewService) Delete(id uint, owner string) error {
+	view, err := s.owned(id, owner)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(view).Error; err != nil {
+		return fmt.Errorf("failed to delete view: %w", err)
+	}
+	return nil
+}
+
+// AdoptLegacyOwners qualifies the owners of views saved before owners carried their
+// issuer with namespace, returning how many views it changed. API key owners are left alone.
+func (s *ViewService) AdoptLegacyOwners(namespace string) (int64, error) {
+	result := s.db.Model(&models.SavedView{}).
+		Where("owner NOT LIKE ? AND owner NOT LIKE ?", "%#%", "apikey:%").
+		Update("owner", gorm.Expr("? || '#' || owner", namespace))
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to adopt view owners: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// Filters returns a view's filters for its resource, or ErrBrokenView when they no
+// longer satisfy the resource's filter configuration
+func (s *ViewService) Filters(view *models.SavedView) ([]config.FilterParam, error) {
+	CheckView(view)
+	if view.Broken {
+		return nil, fmt.Errorf("%w: %s", ErrBrokenView, strings.Join(view.Problems, "; "))
+	}
+
+	filters := make([]config.FilterParam, 0, len(view.Filters))
+	for _, filter := range view.Filters {
+		filters = append(filters, config.FilterParam{Field: filter.Field, Operator: config.FilterOperator(filter.Operator), Value: filter.Value})
+	}
+	return filters, nil
+}
+
+// CheckView records on a view what, if anything, keeps it from being applied
+func CheckView(view *models.SavedView) {
+	view.Problems = viewProblems(view.ResourceType, view.Filters)
+	view.Broken = len(view.Problems) > 0
+}
+
+// owned returns a view that owner may change
+func (s *ViewService) owned(id uint, owner string) (*models.SavedView, error) {
+	view, err := s.Get(id, owner)
+	if err != nil {
+		return nil, err
+	}
+	if view.Owner != owner {
+		return nil, fmt.Errorf("%w: view %d belongs to %s", ErrViewNotOwner, id, view.Owner)
+	}
+	return view, nil
+}
+
+// viewProblems checks filters against the current filter configuration of a resource
+func viewProblems(resourceType string, filters []models.ViewFilter) []string {
+	filterConfig, ok := viewResources[resourceType]
+	if !ok {
+		return []string{fmt.Sprintf("unknown resource type '%s'", resourceType)}
+	}
+
+	rules := filterConfig()
+	var problems []string
+	for _, filter := range filters {
+		if err := rules.ValidateFilter(filter.Field, filter.Operator, filter.Value); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// applyViewRequest validates a request and copies it onto a view
+func applyViewRequest(view *models.SavedView, req models.SavedViewRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidView)
+	}
+	if req.SortOrder != "" && req.SortOrder != "asc" && req.SortOrder != "desc" {
+		return fmt.Errorf("%w: sortOrder must be 'asc' or 'desc'", ErrInvalidView)
+	}
+	if problems := viewProblems(req.ResourceType, req.Filters); len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidView, strings.Join(problems, "; "))
+	}
+
+	view.Name = name
+	view.ResourceType = req.ResourceType
+	view.Filters = req.Filters
+	if view.Filters == nil {
+		view.Filters = []models.ViewFilter{}
+	}
+	view.SortBy = req.SortBy
+	view.SortOrder = req.SortOrder
+	view.Columns = req.Columns
+	view.Shared = req.Shared
+	view.Problems, view.Broken = nil, false
+	return nil
+}
+
+// checkViewName fails with ErrViewConflict when the owner has another view by that name
+func checkViewName(tx *gorm.DB, view models.SavedView) error {
+	var count int64
+	err := tx.Model(&models.SavedView{}).
+		Where("owner = ? AND name = ? AND id <> ?", view.Owner, view.Name, view.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %s already has a view named '%s'", ErrViewConflict, view.Owner, view.Name)
+	}
+	return nil
+}
+
+// viewWriteError passes on the service's own errors and wraps the others
+func viewWriteError(err error, action string) error {
+	if errors.Is(err, ErrViewConflict) {
+		return err
+	}
+	return fmt.Errorf("failed to %s view: %w", action, err)
+}
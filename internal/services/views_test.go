@@ -0,0 +1,123 @@
+package services
+
+import (
+	"testing"
+
+	"golang-service/internal/config"
+	"golang-service/internal/database/dbtest"
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newViewService(t *testing.T) (*ViewService, *gorm.DB) {
+	t.Helper()
+	db := dbtest.Open(t, &models.SavedView{})
+	return NewViewService(db), db
+}
+
+func TestViewService(t *testing.T) {
+	service, _ := newViewService(t)
+
+	req := models.SavedViewRequest{
+		Name:         "Running in prod",
+		ResourceType: "vms",
+		Filters: []models.ViewFilter{
+			{Field: "status", Operator: "eq", Value: "running"},
+			{Field: "env", Operator: "under", Value: "prod"},
+		},
+		SortBy:  "name",
+		Columns: []string{"name", "status"},
+	}
+	private, err := service.Create(req, "alice")
+	require.NoError(t, err)
+	assert.False(t, private.Broken)
+
+	req.Name = "Everything"
+	req.Filters = nil
+	req.Shared = true
+	shared, err := service.Create(req, "alice")
+	require.NoError(t, err)
+
+	_, err = service.Create(req, "alice")
+	assert.ErrorIs(t, err, ErrViewConflict)
+	_, err = service.Create(req, "")
+	assert.ErrorIs(t, err, ErrInvalidView)
+	req.Filters = []models.ViewFilter{{Field: "colour", Operator: "eq", Value: "red"}}
+	_, err = service.Create(req, "bob")
+	assert.ErrorIs(t, err, ErrInvalidView)
+
+	// Others see shared views but cannot change them
+	views, err := service.List("bob", "vms")
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	assert.Equal(t, shared.ID, views[0].ID)
+	_, err = service.Get(private.ID, "bob")
+	assert.ErrorIs(t, err, ErrViewNotFound)
+	assert.ErrorIs(t, service.Delete(shared.ID, "bob"), ErrViewNotOwner)
+
+	views, err = service.List("alice", "")
+	require.NoError(t, err)
+	assert.Len(t, views, 2)
+
+	filters, err := service.Filters(private)
+	require.NoError(t, err)
+	assert.Equal(t, []config.FilterParam{
+		{Field: "status", Operator: config.OperatorEquals, Value: "running"},
+		{Field: "env", Operator: config.OperatorUnder, Value: "prod"},
+	}, filters)
+
+	require.NoError(t, service.Delete(shared.ID, "alice"))
+	_, err = service.Get(shared.ID, "alice")
+	assert.ErrorIs(t, err, ErrViewNotFound)
+}
+
+func TestBrokenView(t *testing.T) {
+	service, db := newViewService(t)
+
+	// A view saved before its filter stopped being supported
+	view := models.SavedView{
+		Owner:        "alice",
+		Name:         "Old",
+		ResourceType: "vms",
+		Filters: []models.ViewFilter{
+			{Field: "status", Operator: "eq", Value: "running"},
+			{Field: "region", Operator: "eq", Value: "eu-west-1"},
+			{Field: "status", Operator: "gt", Value: "a"},
+		},
+	}
+	require.NoError(t, db.Create(&view).Error)
+
+	loaded, err := service.Get(view.ID, "alice")
+	require.NoError(t, err)
+	assert.True(t, loaded.Broken)
+	assert.Equal(t, []string{
+		"field 'region' is not allowed for filtering",
+		"operator 'gt' is not allowed for field 'status' of type 'string'",
+	}, loaded.Problems)
+
+	_, err = service.Filters(loaded)
+	assert.ErrorIs(t, err, ErrBrokenView)
+
+	views, err := service.List("alice", "vms")
+	require.NoError(t, err)
+	require.Len(t, views, 1)
+	assert.True(t, views[0].Broken)
+}
+
+func TestAdoptLegacyOwners(t *testing.T) {
+	service, db := newViewService(t)
+	for _, owner := range []string{"alice", "apikey:key-1", "entra:tenant#bob"} {
+		require.NoError(t, db.Create(&models.SavedView{Owner: owner, Name: "View", ResourceType: "vms"}).Error)
+	}
+
+	adopted, err := service.AdoptLegacyOwners("entra:tenant")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), adopted)
+
+	var owners []string
+	require.NoError(t, db.Model(&models.SavedView{}).Order("id").Pluck("owner", &owners).Error)
+	assert.Equal(t, []string{"entra:tenant#alice", "apikey:key-1", "entra:tenant#bob"}, owners)
+}
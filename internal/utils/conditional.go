@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheControlRevalidate lets clients keep responses but requires them to
+// revalidate with the ETag before every reuse. Responses are per-user, so
+// shared caches must not store them.
+const CacheControlRevalidate = "private, no-cache"
+
+// ETag builds a strong entity tag from the values identifying a representation's version
+func ETag(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// CheckNotModified sets the ETag, Last-Modified and Cache-Control headers and
+// answers 304 Not Modified when the request's validators still match.
+// It returns true when the response has been sent and the handler should stop.
+// A zero lastModified omits the Last-Modified header.
+func CheckNotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	header := c.Writer.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", CacheControlRevalidate)
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if !requestMatches(c.Request, etag, lastModified) {
+		return false
+	}
+
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+	return true
+}
+
+// requestMatches evaluates If-None-Match, falling back to If-Modified-Since when no entity tags were sent
+func requestMatches(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// If-None-Match uses weak comparison, so W/ prefixes are ignored
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckNotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	etag := ETag("vms:query:abc", "1700000000")
+	lastModified := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+
+	serve := func(headers map[string]string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/", func(c *gin.Context) {
+			if CheckNotModified(c, etag, lastModified) {
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"data": "fresh"})
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Validators are set on full responses", func(t *testing.T) {
+		w := serve(nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+		assert.Equal(t, CacheControlRevalidate, w.Header().Get("Cache-Control"))
+		assert.Equal(t, "Tue, 02 Jan 2024 03:04:05 GMT", w.Header().Get("Last-Modified"))
+	})
+
+	t.Run("Matching If-None-Match returns 304", func(t *testing.T) {
+		for _, inm := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+			w := serve(map[string]string{"If-None-Match": inm})
+			assert.Equal(t, http.StatusNotModified, w.Code, inm)
+			assert.Empty(t, w.Body.String())
+			assert.Equal(t, etag, w.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("Stale If-None-Match returns the full response", func(t *testing.T) {
+		w := serve(map[string]string{"If-None-Match": `"stale"`})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("If-None-Match takes precedence over If-Modified-Since", func(t *testing.T) {
+		w := serve(map[string]string{
+			"If-None-Match":     `"stale"`,
+			"If-Modified-Since": lastModified.Add(time.Hour).Format(http.TimeFormat),
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("If-Modified-Since", func(t *testing.T) {
+		w := serve(map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)})
+		assert.Equal(t, http.StatusNotModified, w.Code)
+
+		w = serve(map[string]string{"If-Modified-Since": lastModified.Add(-time.Second).Format(http.TimeFormat)})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestETag(t *testing.T) {
+	assert.Equal(t, ETag("a", "b"), ETag("a", "b"))
+	assert.NotEqual(t, ETag("a", "b"), ETag("ab"))
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, ETag("a"))
+}
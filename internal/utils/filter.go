@@ -37,6 +37,11 @@ func ApplyFilters(vms []models.VM, filters []config.FilterParam) []models.VM {
 
 // applyFilter applies a single filter to a VM
 func applyFilter(vm models.VM, filter config.FilterParam) bool {
+	// Hierarchy filters look at the environment's ancestors as well as the environment itself
+	if filter.Operator == config.OperatorUnder {
+		return underEnvironment(vm, filter.Value)
+	}
+
 	// Get the field value using reflection
 	fieldValue := GetFieldValue(vm, filter.Field)
 	if fieldValue == nil {
@@ -93,6 +98,22 @@ func applyFilter(vm models.VM, filter config.FilterParam) bool {
 	}
 }
 
+// underEnvironment reports whether a VM's environment is envID or one of its descendants
+func underEnvironment(vm models.VM, envID string) bool {
+	if vm.Environment == nil {
+		return false
+	}
+	if vm.Environment.ID == envID {
+		return true
+	}
+	for _, ancestor := range vm.Environment.Ancestors {
+		if ancestor == envID {
+			return true
+		}
+	}
+	return false
+}
+
 // GetFieldValue gets the value of a field from a VM using reflection
 func GetFieldValue(vm models.VM, fieldName string) interface{} {
 	// Handle nested fields (e.g., "environment.id")
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"testing"
+
+	"golang-service/internal/config"
+	"golang-service/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyFiltersUnderEnvironment(t *testing.T) {
+	vms := []models.VM{
+		{ID: "root", Env: "prod", Environment: &models.EnvironmentInfo{ID: "prod"}},
+		{ID: "leaf", Env: "prod-us-east1", Environment: &models.EnvironmentInfo{ID: "prod-us-east1", Ancestors: []string{"prod", "prod-us"}}},
+		{ID: "other", Env: "staging", Environment: &models.EnvironmentInfo{ID: "staging"}},
+		{ID: "unassigned"},
+	}
+
+	filterConfig := config.VMsFilterConfig()
+	filters, err := filterConfig.ParseQueryParams(map[string][]string{"env_under": {"prod"}})
+	assert.NoError(t, err)
+
+	var ids []string
+	for _, vm := range ApplyFilters(vms, filters) {
+		ids = append(ids, vm.ID)
+	}
+	assert.Equal(t, []string{"root", "leaf"}, ids)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // QueryParams represents common query parameters for any list endpoint
@@ -72,13 +73,26 @@ func ParseQueryParams(c *gin.Context) (QueryParams, error) {
 	}
 
 	// Parse filters using standard format: field=value, field_op=value
-	params.Filters = ParseStandardFilters(c)
+	filters, err := ParseStandardFilters(c)
+	if err != nil {
+		return params, err
+	}
+	params.Filters = filters
 
 	return params, nil
 }
 
-// ParseStandardFilters parses filters in the format field=value (eq), field_op=value (for other ops)
-func ParseStandardFilters(c *gin.Context) []QueryFilter {
+// filterOperators are the operators a filter parameter can end with, longest first so
+// that field_is_not_null is not read as field_is_not with operator null
+var filterOperators = []string{
+	"is_not_null", "starts_with", "ends_with", "is_null", "not_in", "between",
+	"contains", "ilike", "like", "gte", "lte", "eq", "ne", "gt", "lt", "in",
+}
+
+// ParseStandardFilters parses filters in the format field=value (eq), field_op=value (for other ops).
+// A key only carries an operator when it ends with a known one, so fields such as azure_id
+// or created_at filter for equality.
+func ParseStandardFilters(c *gin.Context) ([]QueryFilter, error) {
 	var filters []QueryFilter
 	queryParams := c.Request.URL.Query()
 	for key, values := range queryParams {
@@ -91,16 +105,16 @@ func ParseStandardFilters(c *gin.Context) []QueryFilter {
 		value := values[0]
 		field := key
 		operator := "eq"
-		if idx := strings.LastIndex(key, "_"); idx != -1 {
-			field = key[:idx]
-			operator = key[idx+1:]
-		}
-		if !IsValidOperator(operator) {
-			continue
+		for _, op := range filterOperators {
+			if strings.HasSuffix(key, "_"+op) && len(key) > len(op)+1 {
+				field = strings.TrimSuffix(key, "_"+op)
+				operator = op
+				break
+			}
 		}
 		convertedValue, err := ConvertFilterValue(value, operator)
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("invalid filter %s: %w", key, err)
 		}
 		filter := QueryFilter{
 			Field:    field,
@@ -109,7 +123,7 @@ func ParseStandardFilters(c *gin.Context) []QueryFilter {
 		}
 		filters = append(filters, filter)
 	}
-	return filters
+	return filters, nil
 }
 
 // IsValidOperator checks if the operator is valid
@@ -383,4 +397,97 @@ func CalculatePagination(totalItems, page, pageSize int) (int, int) {
 		totalPages++
 	}
 	return totalItems, totalPages
-} 
\ No newline at end of file
+}
+
+// SQLFilters returns a query scope adding filters as WHERE conditions. columns maps the
+// field names callers may filter on to their columns; a filter on any other field is an error.
+func SQLFilters(filters []QueryFilter, columns map[string]string) (func(*gorm.DB) *gorm.DB, error) {
+	type condition struct {
+		query string
+		args  []interface{}
+	}
+	conditions := make([]condition, 0, len(filters))
+	add := func(query string, args ...interface{}) {
+		conditions = append(conditions, condition{query: query, args: args})
+	}
+
+	for _, filter := range filters {
+		column, ok := columns[filter.Field]
+		if !ok {
+			return nil, fmt.Errorf("cannot filter on field: %s", filter.Field)
+		}
+
+		switch filter.Operator {
+		case "eq":
+			add(column+" = ?", filter.Value)
+		case "ne":
+			add(column+" <> ?", filter.Value)
+		case "gt":
+			add(column+" > ?", filter.Value)
+		case "gte":
+			add(column+" >= ?", filter.Value)
+		case "lt":
+			add(column+" < ?", filter.Value)
+		case "lte":
+			add(column+" <= ?", filter.Value)
+		case "in":
+			add(column+" IN ?", filter.Value)
+		case "not_in":
+			add(column+" NOT IN ?", filter.Value)
+		case "contains":
+			add(column+` LIKE ? ESCAPE '\'`, "%"+escapeLike(fmt.Sprint(filter.Value))+"%")
+		case "starts_with":
+			add(column+` LIKE ? ESCAPE '\'`, escapeLike(fmt.Sprint(filter.Value))+"%")
+		case "ends_with":
+			add(column+` LIKE ? ESCAPE '\'`, "%"+escapeLike(fmt.Sprint(filter.Value)))
+		case "like":
+			add(column+" LIKE ?", filter.Value)
+		case "ilike":
+			add("LOWER("+column+") LIKE LOWER(?)", filter.Value)
+		case "between":
+			bounds, _ := filter.Value.([]string)
+			if len(bounds) != 2 {
+				return nil, fmt.Errorf("between operator requires exactly 2 values")
+			}
+			add(column+" BETWEEN ? AND ?", bounds[0], bounds[1])
+		case "is_null":
+			add(column + " IS NULL")
+		case "is_not_null":
+			add(column + " IS NOT NULL")
+		default:
+			return nil, fmt.Errorf("unsupported operator: %s", filter.Operator)
+		}
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		for _, c := range conditions {
+			db = db.Where(c.query, c.args...)
+		}
+		return db
+	}, nil
+}
+
+// SQLSort returns a query scope ordering by a sortable field, with the primary key
+// breaking ties so pages are stable. columns maps the field names callers may sort on to
+// their columns.
+func SQLSort(sortBy, sortOrder string, columns map[string]string) (func(*gorm.DB) *gorm.DB, error) {
+	column := "id"
+	if sortBy != "" {
+		var ok bool
+		if column, ok = columns[sortBy]; !ok {
+			return nil, fmt.Errorf("cannot sort by field: %s", sortBy)
+		}
+	}
+	direction := "ASC"
+	if sortOrder == "desc" {
+		direction = "DESC"
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(column + " " + direction).Order("id " + direction)
+	}, nil
+}
+
+// escapeLike escapes the LIKE wildcards in a value that is matched literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
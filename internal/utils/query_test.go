@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStandardFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	parse := func(query string) ([]QueryFilter, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/users?"+query, nil)
+		return ParseStandardFilters(c)
+	}
+
+	cases := []struct {
+		query    string
+		field    string
+		operator string
+		value    interface{}
+	}{
+		{"azure_id=oid-1", "azure_id", "eq", "oid-1"},
+		{"created_at_gte=2024-01-01", "created_at", "gte", "2024-01-01"},
+		{"updated_at_is_not_null=", "updated_at", "is_not_null", nil},
+		{"name_starts_with=Al", "name", "starts_with", "Al"},
+		{"email_not_in=a@example.com,b@example.com", "email", "not_in", []string{"a@example.com", "b@example.com"}},
+		{"per_page=2", "per_page", "eq", "2"},
+	}
+	for _, tc := range cases {
+		filters, err := parse(tc.query)
+		require.NoError(t, err, tc.query)
+		require.Len(t, filters, 1, tc.query)
+		assert.Equal(t, QueryFilter{Field: tc.field, Operator: tc.operator, Value: tc.value}, filters[0], tc.query)
+	}
+
+	_, err := parse("created_at_between=2024-01-01")
+	assert.Error(t, err)
+}
+
+func TestSQLFiltersRejectsUnknownFields(t *testing.T) {
+	columns := map[string]string{"azure_id": "azure_id"}
+
+	_, err := SQLFilters([]QueryFilter{{Field: "azure_id", Operator: "eq", Value: "oid-1"}}, columns)
+	assert.NoError(t, err)
+	_, err = SQLFilters([]QueryFilter{{Field: "azure", Operator: "eq", Value: "oid-1"}}, columns)
+	assert.Error(t, err)
+}
@@ -6,6 +6,10 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// ResultCountKey is the context key list responses store their number of results under,
+// for the audit log
+const ResultCountKey = "result_count"
+
 // PaginatedResponse represents a generic paginated response
 type PaginatedResponse[T any] struct {
 	Data       []T `json:"data"`
@@ -38,6 +42,7 @@ func NewPaginatedResponse[T any](data []T, page, pageSize, totalItems int) Pagin
 // SendPaginatedResponse sends a paginated response with proper HTTP status
 func SendPaginatedResponse[T any](c *gin.Context, data []T, page, pageSize, totalItems int) {
 	response := NewPaginatedResponse(data, page, pageSize, totalItems)
+	c.Set(ResultCountKey, totalItems)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -57,6 +62,7 @@ func SendSuccessResponse[T any](c *gin.Context, data T) {
 
 // SendListResponse sends a simple list response without pagination
 func SendListResponse[T any](c *gin.Context, data []T) {
+	c.Set(ResultCountKey, len(data))
 	c.JSON(http.StatusOK, gin.H{
 		"data": data,
 	})
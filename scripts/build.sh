@@ -6,7 +6,7 @@ echo "Building Golang Service..."
 
 # Build the application
 echo "Building Go application..."
-CGO_ENABLED=0 GOOS=linux go build -a -installsuffix cgo -o bin/server cmd/server/main.go
+CGO_ENABLED=0 GOOS=linux go build -a -installsuffix cgo -o bin/server ./cmd/server
 
 echo "Build completed successfully!"
 